@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"gorm.io/gorm"
+
+	"github.com/nilabhsubramaniam/kapas/internal/config"
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+)
+
+// Backfills Product.RegionID from the deprecated StateOrigin using the
+// seeded regions, and fills StateOrigin from the region for products that
+// only have a region. Run after the location seed; safe to run again.
+func main() {
+	dryRun := flag.Bool("dry-run", false, "Report the changes without saving them")
+	flag.Parse()
+
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
+
+	// Initialize database
+	config.InitDatabase()
+
+	log.Println("🗺️  Backfilling product regions...")
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := backfillRegions(tx); err != nil {
+			return err
+		}
+		if err := backfillStateOrigins(tx); err != nil {
+			return err
+		}
+		if *dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errDryRun):
+		log.Println("Dry run: no changes saved")
+	case err != nil:
+		log.Fatalf("❌ Backfill failed: %v", err)
+	default:
+		log.Println("✅ Product region backfill completed!")
+	}
+}
+
+// errDryRun rolls back the transaction in dry-run mode
+var errDryRun = errors.New("dry run")
+
+// backfillRegions sets RegionID on products that only have a StateOrigin
+func backfillRegions(tx *gorm.DB) error {
+	var products []models.Product
+	if err := tx.Unscoped().
+		Select("id, name, state_origin, saree_type").
+		Where("region_id IS NULL AND state_origin IS NOT NULL AND state_origin <> ''").
+		Order("id ASC").
+		Find(&products).Error; err != nil {
+		return err
+	}
+
+	updated, unmatched := 0, 0
+	for _, product := range products {
+		region, err := models.RegionForState(tx, product.StateOrigin, product.SareeType)
+		if err != nil {
+			return err
+		}
+		if region == nil {
+			log.Printf("⚠️  No region for product %d (%s) with state_origin %q", product.ID, product.Name, product.StateOrigin)
+			unmatched++
+			continue
+		}
+
+		if err := tx.Unscoped().Model(&product).UpdateColumns(map[string]interface{}{
+			"region_id":    region.ID,
+			"state_origin": strings.ToUpper(product.StateOrigin),
+		}).Error; err != nil {
+			return err
+		}
+		log.Printf("Product %d (%s): %s → %s", product.ID, product.Name, product.StateOrigin, region.Slug)
+		updated++
+	}
+
+	log.Printf("Regions set on %d of %d products; %d without a matching region", updated, len(products), unmatched)
+	return nil
+}
+
+// backfillStateOrigins keeps the compatibility column filled for products
+// that were created with a region only
+func backfillStateOrigins(tx *gorm.DB) error {
+	result := tx.Exec(`
+		UPDATE products SET state_origin = states.code
+		FROM regions JOIN states ON states.id = regions.state_id
+		WHERE regions.id = products.region_id
+			AND (products.state_origin IS NULL OR products.state_origin = '')`)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	log.Printf("state_origin filled from the region on %d products", result.RowsAffected)
+	return nil
+}
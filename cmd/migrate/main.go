@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/joho/godotenv"
+
+	"github.com/nilabhsubramaniam/kapas/internal/config"
+	"github.com/nilabhsubramaniam/kapas/internal/migrations"
+)
+
+const usage = `Usage: go run ./cmd/migrate [-dir DIR] <command>
+
+Commands:
+  up [N]        Apply pending migrations (at most N)
+  down [N]      Revert the last N applied migrations (default 1)
+  status        List migrations and when they were applied
+  create NAME   Add empty up/down files for a new migration to DIR
+`
+
+// Manages the database schema with the versioned SQL migrations in
+// internal/migrations/sql. The files are built into the binary, so new
+// ones need a rebuild (go run does this).
+func main() {
+	dir := flag.String("dir", migrations.Dir, "Migration directory used by create")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if args[0] == "create" {
+		if len(args) < 2 {
+			log.Fatal("❌ create needs a migration name, e.g. create add_product_sku")
+		}
+		up, down, err := migrations.Create(*dir, strings.Join(args[1:], "_"))
+		if err != nil {
+			log.Fatalf("❌ Failed to create migration: %v", err)
+		}
+		log.Printf("✅ Created %s and %s", up, down)
+		return
+	}
+
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
+
+	// Initialize database
+	config.InitDatabase()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrations.Up(config.DB, count(args, 0))
+		for _, m := range applied {
+			log.Printf("Applied %s", m)
+		}
+		if err != nil {
+			log.Fatalf("❌ Migration failed: %v", err)
+		}
+		log.Printf("✅ %d migrations applied", len(applied))
+	case "down":
+		reverted, err := migrations.Down(config.DB, count(args, 1))
+		for _, m := range reverted {
+			log.Printf("Reverted %s", m)
+		}
+		if err != nil {
+			log.Fatalf("❌ Rollback failed: %v", err)
+		}
+		log.Printf("✅ %d migrations reverted", len(reverted))
+	case "status":
+		printStatus()
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// count parses the optional N argument of up and down
+func count(args []string, fallback int) int {
+	if len(args) < 2 {
+		return fallback
+	}
+	n, err := strconv.Atoi(args[1])
+	if err != nil || n < 1 {
+		log.Fatalf("❌ %s: N must be a positive number, got %q", args[0], args[1])
+	}
+	return n
+}
+
+// printStatus lists every migration with its applied time or "pending"
+func printStatus() {
+	statuses, err := migrations.List(config.DB)
+	if err != nil {
+		log.Fatalf("❌ Failed to read migrations: %v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	pending := 0
+	for _, s := range statuses {
+		appliedAt := "pending"
+		switch {
+		case s.AppliedAt == nil:
+			pending++
+		case s.Missing:
+			appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05") + " (no file in this build)"
+		default:
+			appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+	}
+	w.Flush()
+	fmt.Printf("\n%d pending\n", pending)
+}
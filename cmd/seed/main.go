@@ -22,6 +22,12 @@ func main() {
 	// Seed products
 	seedProducts()
 
+	// Seed warehouse stock
+	seedWarehouseStock()
+
+	// Seed couriers
+	seedLogisticsProviders()
+
 	log.Println("✅ Database seeding completed!")
 }
 
@@ -346,3 +352,73 @@ func seedProducts() {
 
 	log.Printf("✅ Seeded %d products", len(products))
 }
+
+// seedWarehouseStock creates the main warehouse and moves product stock that
+// has no inventory rows yet into it
+func seedWarehouseStock() {
+	log.Println("🏬 Seeding warehouse stock...")
+
+	warehouse := models.Warehouse{
+		Name:     "Tantuka Main Warehouse",
+		Code:     "MAIN",
+		Address:  "Plot 12, Transport Nagar",
+		City:     "Lucknow",
+		State:    "Uttar Pradesh",
+		PinCode:  "226012",
+		IsActive: true,
+	}
+	if err := config.DB.Where("code = ?", warehouse.Code).FirstOrCreate(&warehouse).Error; err != nil {
+		log.Printf("Failed to create warehouse '%s': %v", warehouse.Code, err)
+		return
+	}
+
+	var products []models.Product
+	config.DB.Where("stock_quantity > 0").
+		Where("NOT EXISTS (SELECT 1 FROM inventory WHERE inventory.product_id = products.id)").
+		Find(&products)
+
+	for _, product := range products {
+		inventory := models.Inventory{
+			ProductID:   product.ID,
+			WarehouseID: warehouse.ID,
+			Quantity:    product.StockQuantity,
+		}
+		if err := config.DB.Create(&inventory).Error; err != nil {
+			log.Printf("Failed to stock product '%s': %v", product.Name, err)
+			continue
+		}
+
+		movement := models.StockMovement{
+			ProductID:   product.ID,
+			WarehouseID: warehouse.ID,
+			Type:        models.StockMovementReceipt,
+			Quantity:    product.StockQuantity,
+			Reason:      "Opening stock",
+		}
+		if err := config.DB.Create(&movement).Error; err != nil {
+			log.Printf("Failed to record opening stock for '%s': %v", product.Name, err)
+		}
+	}
+
+	log.Printf("✅ Stocked %d products in warehouse %s", len(products), warehouse.Code)
+}
+
+// seedLogisticsProviders creates the couriers the shipping package integrates with
+func seedLogisticsProviders() {
+	log.Println("🚚 Seeding logistics providers...")
+
+	providers := []models.LogisticsProvider{
+		{Name: "Delhivery", Code: "delhivery", APIEndpoint: "https://track.delhivery.com", IsActive: true},
+		{Name: "Blue Dart", Code: "bluedart", APIEndpoint: "https://apigateway.bluedart.com/in/transportation", IsActive: true},
+		{Name: "Shiprocket", Code: "shiprocket", APIEndpoint: "https://apiv2.shiprocket.in/v1/external", IsActive: true},
+		{Name: "Fake Courier", Code: "fake", IsActive: true},
+	}
+
+	for _, provider := range providers {
+		if err := config.DB.Where("code = ?", provider.Code).FirstOrCreate(&provider).Error; err != nil {
+			log.Printf("Failed to create logistics provider '%s': %v", provider.Code, err)
+		}
+	}
+
+	log.Printf("✅ Seeded %d logistics providers", len(providers))
+}
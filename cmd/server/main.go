@@ -1,8 +1,10 @@
 package main
 
 import (
+	"flag"
 	"log"
 	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -13,6 +15,7 @@ import (
 	"github.com/nilabhsubramaniam/kapas/internal/config"
 	"github.com/nilabhsubramaniam/kapas/internal/handlers"
 	"github.com/nilabhsubramaniam/kapas/internal/middleware"
+	"github.com/nilabhsubramaniam/kapas/internal/migrations"
 )
 
 // @title Tantuka E-Commerce API
@@ -40,8 +43,19 @@ func main() {
 		log.Println("Warning: No .env file found, using system environment variables")
 	}
 
+	migrate := flag.Bool("migrate", config.GetEnvBool("DB_MIGRATE_ON_START", false), "Apply pending database migrations before starting")
+	requireMigrations := flag.Bool("require-migrations", config.GetEnvBool("DB_REQUIRE_MIGRATIONS", false), "Refuse to start while database migrations are pending")
+	flag.Parse()
+
 	// Initialize database connection
 	config.InitDatabase()
+	checkMigrations(*migrate, *requireMigrations)
+
+	// Release stock held by unpaid orders
+	go handlers.RunReservationExpiry(time.Minute)
+	go handlers.RunTrackingPoller(config.GetEnvDuration("COURIER_POLL_INTERVAL", 30*time.Minute))
+	go handlers.RunNotificationDispatcher(config.GetEnvDuration("NOTIFY_DISPATCH_INTERVAL", 15*time.Second))
+	go handlers.RunTokenCleanup(time.Hour)
 
 	// Get environment
 	appEnv := os.Getenv("APP_ENV")
@@ -96,8 +110,15 @@ func main() {
 		{
 			auth.POST("/register", handlers.Register)
 			auth.POST("/login", handlers.Login)
+			auth.POST("/refresh", handlers.RefreshToken)
 			auth.GET("/me", middleware.AuthMiddleware(), handlers.GetCurrentUser)
 			auth.POST("/logout", middleware.AuthMiddleware(), handlers.Logout)
+			auth.POST("/verify-email", handlers.VerifyEmail)
+			auth.POST("/resend-verification", middleware.AuthMiddleware(), handlers.ResendVerificationEmail)
+			auth.POST("/forgot-password", handlers.ForgotPassword)
+			auth.POST("/reset-password", handlers.ResetPassword)
+			auth.POST("/otp/send", handlers.SendOTP)
+			auth.POST("/otp/verify", handlers.VerifyOTP)
 		}
 
 		// Product routes
@@ -109,7 +130,7 @@ func main() {
 
 			// Protected routes (admin only)
 			protected := products.Group("")
-			protected.Use(middleware.AuthMiddleware(), middleware.AdminOnly())
+			protected.Use(middleware.AuthMiddleware(), middleware.AdminOnly(), middleware.AuditWrites())
 			{
 				protected.POST("", handlers.CreateProduct)
 				protected.PUT("/:id", handlers.UpdateProduct)
@@ -117,6 +138,18 @@ func main() {
 			}
 		}
 
+		// Location lookups (public, cacheable)
+		locations := api.Group("/locations")
+		{
+			locations.GET("/countries", handlers.ListCountries)
+			locations.GET("/countries/:country/states", handlers.ListStates)
+			locations.GET("/states/:id/districts", handlers.ListDistricts)
+			locations.GET("/regions", handlers.ListRegions)
+		}
+
+		// Delivery serviceability (public)
+		api.GET("/delivery/estimate", handlers.GetDeliveryEstimate)
+
 		// Cart routes (protected)
 		cart := api.Group("/cart")
 		cart.Use(middleware.AuthMiddleware())
@@ -126,6 +159,7 @@ func main() {
 			cart.PUT("/items/:id", handlers.UpdateCartItem)
 			cart.DELETE("/items/:id", handlers.RemoveFromCart)
 			cart.DELETE("", handlers.ClearCart)
+			cart.POST("/coupon", handlers.ApplyCoupon)
 		}
 
 		// Order routes (protected)
@@ -137,11 +171,57 @@ func main() {
 			orders.GET("/:id", handlers.GetOrder)
 			orders.PUT("/:id/cancel", handlers.CancelOrder)
 			orders.GET("/:id/track", handlers.TrackOrder)
+			orders.POST("/:id/payment", handlers.CreatePayment)
+			orders.POST("/:id/payment/verify", handlers.VerifyPayment)
+		}
+
+		// Address book routes (protected)
+		addresses := api.Group("/me/addresses")
+		addresses.Use(middleware.AuthMiddleware())
+		{
+			addresses.GET("", handlers.ListAddresses)
+			addresses.POST("", handlers.CreateAddress)
+			addresses.GET("/:id", handlers.GetAddress)
+			addresses.PUT("/:id", handlers.UpdateAddress)
+			addresses.DELETE("/:id", handlers.DeleteAddress)
+			addresses.PUT("/:id/default", handlers.SetDefaultAddress)
+		}
+
+		// Return routes (protected)
+		returns := api.Group("/returns")
+		returns.Use(middleware.AuthMiddleware())
+		{
+			returns.POST("", handlers.CreateReturn)
+			returns.GET("", handlers.ListUserReturns)
+			returns.GET("/:id", handlers.GetReturn)
+		}
+
+		// Notification inbox routes (protected)
+		notifications := api.Group("/notifications")
+		notifications.Use(middleware.TokenFromQuery(), middleware.AuthMiddleware())
+		{
+			notifications.GET("", handlers.ListNotifications)
+			notifications.GET("/unread-count", handlers.GetUnreadNotificationCount)
+			notifications.GET("/stream", handlers.StreamNotifications)
+			notifications.PUT("/read-all", handlers.MarkAllNotificationsRead)
+			notifications.PUT("/:id/read", handlers.MarkNotificationRead)
+		}
+
+		// Payment gateway callbacks (signature-verified, no JWT)
+		payments := api.Group("/payments")
+		{
+			payments.POST("/webhook/:provider", handlers.PaymentWebhook)
+		}
+
+		// Courier tracking webhooks (public, authenticated per courier)
+		shipping := api.Group("/shipping")
+		{
+			shipping.POST("/webhook/:code", handlers.CourierWebhook)
 		}
 
 		// Admin routes
 		admin := api.Group("/admin")
-		admin.Use(middleware.AuthMiddleware(), middleware.AdminOnly())
+		admin.Use(middleware.AuthMiddleware(), middleware.AdminOnly(), middleware.AuditWrites())
 		{
 			// Dashboard & Analytics
 			admin.GET("/dashboard", handlers.GetDashboard)
@@ -157,10 +237,56 @@ func main() {
 			// Order Management
 			admin.GET("/orders", handlers.ListAllOrders)
 			admin.PUT("/orders/:id/status", handlers.UpdateOrderStatus)
+			admin.POST("/orders/:id/shipment", handlers.CreateShipment)
+			admin.DELETE("/orders/:id/shipment", handlers.CancelShipment)
+			admin.POST("/orders/:id/refunds", handlers.CreateOrderRefund)
+
+			// Returns
+			admin.GET("/returns", handlers.ListReturns)
+			admin.GET("/returns/:id", handlers.GetReturnDetails)
+			admin.PUT("/returns/:id/status", handlers.UpdateReturnStatus)
+			admin.POST("/returns/:id/refund", handlers.RefundReturn)
+
+			// Refunds
+			admin.GET("/refunds", handlers.ListRefunds)
+			admin.PUT("/refunds/:id/complete", handlers.CompleteRefund)
+
+			// Payments
+			admin.GET("/payments/webhook-events", handlers.ListPaymentWebhookEvents)
+
+			// Coupons
+			admin.GET("/coupons", handlers.ListCoupons)
+			admin.GET("/coupons/:id", handlers.GetCoupon)
+			admin.POST("/coupons", handlers.CreateCoupon)
+			admin.PUT("/coupons/:id", handlers.UpdateCoupon)
+			admin.DELETE("/coupons/:id", handlers.DeleteCoupon)
 
 			// Inventory Management
 			admin.GET("/inventory", handlers.GetInventory)
 			admin.PUT("/inventory/:id", handlers.UpdateInventory)
+
+			// Warehouses
+			admin.GET("/warehouses", handlers.ListWarehouses)
+			admin.GET("/warehouses/:id", handlers.GetWarehouse)
+			admin.GET("/warehouses/:id/stock", handlers.GetWarehouseStock)
+			admin.POST("/warehouses", handlers.CreateWarehouse)
+			admin.PUT("/warehouses/:id", handlers.UpdateWarehouse)
+			admin.DELETE("/warehouses/:id", handlers.DeleteWarehouse)
+			admin.GET("/stock-transfers", handlers.ListStockTransfers)
+			admin.POST("/stock-transfers", handlers.TransferStock)
+
+			// Stock ledger
+			admin.GET("/stock-movements", handlers.ListStockMovements)
+			admin.GET("/stock-movements/audit", handlers.AuditStock)
+			admin.POST("/stock-movements/reconcile", handlers.ReconcileStock)
+
+			// Delivery serviceability
+			admin.POST("/serviceability/pin-codes", handlers.ImportPinCodes)
+			admin.POST("/serviceability/coverage", handlers.ImportPinCodeCoverage)
+
+			// Audit log
+			admin.GET("/audit-logs", handlers.ListAuditLogs)
+			admin.GET("/audit-logs/:id", handlers.GetAuditLog)
 		}
 	}
 
@@ -178,3 +304,27 @@ func main() {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+// checkMigrations applies pending migrations when migrate is set, then
+// warns about any still pending, or exits when they are required
+func checkMigrations(migrate, required bool) {
+	if migrate {
+		applied, err := migrations.Up(config.DB, 0)
+		if err != nil {
+			log.Fatalf("❌ Failed to run migrations: %v", err)
+		}
+		log.Printf("✅ Database migrations completed (%d applied)", len(applied))
+	}
+
+	pending, err := migrations.Pending(config.DB)
+	if err != nil {
+		log.Fatalf("❌ Failed to check migrations: %v", err)
+	}
+	if len(pending) == 0 {
+		return
+	}
+	if required {
+		log.Fatalf("❌ %d database migrations pending, starting with %s; run: go run ./cmd/migrate up", len(pending), pending[0])
+	}
+	log.Printf("⚠️  %d database migrations pending, starting with %s; run: go run ./cmd/migrate up", len(pending), pending[0])
+}
@@ -10,13 +10,12 @@ import (
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
-
-	"github.com/nilabhsubramaniam/kapas/internal/models"
 )
 
 var DB *gorm.DB
 
-// InitDatabase initializes the database connection and runs migrations
+// InitDatabase initializes the database connection. The schema is managed
+// by the migrations package (see cmd/migrate).
 func InitDatabase() {
 	var err error
 
@@ -72,68 +71,6 @@ func InitDatabase() {
 	}
 
 	log.Println("✅ Database connected successfully")
-
-	// Run auto-migration
-	if err := runMigrations(); err != nil {
-		log.Fatalf("❌ Failed to run migrations: %v", err)
-	}
-
-	log.Println("✅ Database migrations completed")
-}
-
-// runMigrations runs GORM auto-migrations for all models
-func runMigrations() error {
-	return DB.AutoMigrate(
-		// Location Hierarchy (NEW - must be first for foreign keys)
-		&models.Country{},
-		&models.State{},
-		&models.District{},
-		&models.Region{},
-
-		// User & Auth
-		&models.User{},
-		&models.Address{},
-
-		// Vendors (NEW)
-		&models.Vendor{},
-
-		// Products & Catalog
-		&models.Product{},
-		&models.ProductImage{},
-		&models.Category{},
-		&models.Review{},
-
-		// Cart & Wishlist
-		&models.CartItem{},
-		&models.WishlistItem{},
-
-		// Orders
-		&models.Order{},
-		&models.OrderItem{},
-		&models.OrderStatusHistory{},
-
-		// Payments
-		&models.Payment{},
-		&models.Coupon{},
-		&models.CouponUsage{},
-
-		// Inventory & Warehouses
-		&models.Warehouse{},
-		&models.Inventory{},
-
-		// Shipping & Logistics
-		&models.Shipment{},
-		&models.TrackingEvent{},
-		&models.LogisticsProvider{},
-
-		// Returns
-		&models.Return{},
-		&models.ReturnItem{},
-
-		// Admin & System
-		&models.Notification{},
-		&models.ActivityLog{},
-	)
 }
 
 // CheckDatabaseHealth checks if database is healthy
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"strconv"
+	"time"
+)
+
+// GetEnv gets environment variable with fallback
+func GetEnv(key, fallback string) string {
+	return getEnv(key, fallback)
+}
+
+// GetEnvInt gets an integer environment variable with fallback
+func GetEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(getEnv(key, ""))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// GetEnvFloat gets a float environment variable with fallback
+func GetEnvFloat(key string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(getEnv(key, ""), 64)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// GetEnvBool gets a boolean environment variable with fallback
+func GetEnvBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(getEnv(key, ""))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// GetEnvDuration gets a duration environment variable (e.g. "15m") with fallback
+func GetEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(getEnv(key, ""))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/nilabhsubramaniam/kapas/internal/config"
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+	"github.com/nilabhsubramaniam/kapas/internal/notify"
+	"github.com/nilabhsubramaniam/kapas/internal/utils"
+)
+
+// accountEmailCooldown is the least time between two emails of one kind to a user
+const accountEmailCooldown = time.Minute
+
+// VerifyEmailRequest represents an email verification request
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+}
+
+// ForgotPasswordRequest represents a password reset link request
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email" example:"user@example.com"`
+}
+
+// ResetPasswordRequest represents a password reset
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	Password string `json:"password" binding:"required,min=6" example:"newpassword123"`
+}
+
+// VerifyEmail godoc
+// @Summary Verify email address
+// @Description Confirm the email address with the token from the verification link
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body VerifyEmailRequest true "Verification token"
+// @Success 200 {object} MessageResponse "Email verified"
+// @Failure 400 {object} ErrorResponse "Invalid, expired or used link"
+// @Router /auth/verify-email [post]
+func VerifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		token, err := consumeAccountToken(tx, req.Token, models.UserTokenEmailVerification)
+		if err != nil {
+			return err
+		}
+
+		var user models.User
+		if err := tx.First(&user, token.UserID).Error; err != nil {
+			return newHTTPError(http.StatusBadRequest, "Invalid or expired link")
+		}
+		if !strings.EqualFold(user.Email, token.Email) {
+			return newHTTPError(http.StatusBadRequest, "This link was sent to a different email address")
+		}
+
+		return tx.Model(&user).Update("email_verified", true).Error
+	})
+	if err != nil {
+		respondError(c, err, "Failed to verify email")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+// ResendVerificationEmail godoc
+// @Summary Resend verification email
+// @Description Send a new email verification link to the current user; earlier links stop working
+// @Tags Authentication
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} MessageResponse "Verification email sent"
+// @Failure 409 {object} ErrorResponse "Email already verified"
+// @Failure 429 {object} ErrorResponse "Requested too recently"
+// @Router /auth/resend-verification [post]
+func ResendVerificationEmail(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.EmailVerified {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already verified"})
+		return
+	}
+	if accountEmailSentRecently(user.ID, models.UserTokenEmailVerification) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Please wait a minute before requesting another email"})
+		return
+	}
+
+	if err := sendAccountToken(c.Request.Context(), &user, models.UserTokenEmailVerification); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
+// ForgotPassword godoc
+// @Summary Request password reset
+// @Description Email a password reset link. The response is the same whether or not the address has an account.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body ForgotPasswordRequest true "Account email"
+// @Success 200 {object} MessageResponse "Reset link sent if the account exists"
+// @Router /auth/forgot-password [post]
+func ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	err := config.DB.Where("email = ?", req.Email).First(&user).Error
+	if err == nil && user.IsActive && !accountEmailSentRecently(user.ID, models.UserTokenPasswordReset) {
+		// Send in the background so response times don't reveal which addresses exist
+		go func() {
+			if err := sendAccountToken(context.Background(), &user, models.UserTokenPasswordReset); err != nil {
+				log.Printf("Failed to send password reset email to user %d: %v", user.ID, err)
+			}
+		}()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "If an account exists for this email, a password reset link has been sent",
+	})
+}
+
+// ResetPassword godoc
+// @Summary Reset password
+// @Description Set a new password with the token from the reset link. All existing sessions are logged out.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} MessageResponse "Password reset"
+// @Failure 400 {object} ErrorResponse "Invalid, expired or used link"
+// @Router /auth/reset-password [post]
+func ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process password"})
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		token, err := consumeAccountToken(tx, req.Token, models.UserTokenPasswordReset)
+		if err != nil {
+			return err
+		}
+
+		var user models.User
+		if err := tx.First(&user, token.UserID).Error; err != nil || !user.IsActive {
+			return newHTTPError(http.StatusBadRequest, "Invalid or expired link")
+		}
+
+		updates := map[string]interface{}{"password_hash": string(hashedPassword)}
+		// Opening the link proves the user owns the address
+		if strings.EqualFold(user.Email, token.Email) {
+			updates["email_verified"] = true
+		}
+		if err := tx.Model(&user).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		return revokeSessions(tx, "user_id = ?", user.ID)
+	})
+	if err != nil {
+		respondError(c, err, "Failed to reset password")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully. Please log in again."})
+}
+
+// sendAccountToken emails the user a link with a new single-use token for
+// purpose; earlier unused tokens for the same purpose stop working
+func sendAccountToken(ctx context.Context, user *models.User, purpose models.UserTokenPurpose) error {
+	if user.ContactEmail() == "" {
+		return notify.ErrNoAddress
+	}
+
+	ttl := accountTokenTTL(purpose)
+	token, err := utils.GenerateActionToken(user.ID, string(purpose), ttl)
+	if err != nil {
+		return err
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND purpose = ? AND used_at IS NULL", user.ID, purpose).
+			Delete(&models.UserToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.UserToken{
+			UserID:    user.ID,
+			Purpose:   purpose,
+			TokenHash: utils.HashToken(token),
+			Email:     user.Email,
+			ExpiresAt: time.Now().Add(ttl),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	event, path := notify.EventVerifyEmail, "/verify-email"
+	if purpose == models.UserTokenPasswordReset {
+		event, path = notify.EventPasswordReset, "/reset-password"
+	}
+	link := strings.TrimRight(config.GetEnv("FRONTEND_URL", "http://localhost:3000"), "/") +
+		path + "?token=" + url.QueryEscape(token)
+
+	msg, err := notify.Render(event, models.NotificationChannelEmail, map[string]interface{}{
+		"Name":      user.Name,
+		"Link":      link,
+		"ExpiresIn": humanizeDuration(ttl),
+	})
+	if err != nil {
+		return err
+	}
+
+	sender, err := notify.Get(models.NotificationChannelEmail)
+	if err != nil {
+		return err
+	}
+	return sender.Send(ctx, notify.Recipient{Name: user.Name, Email: user.Email}, msg)
+}
+
+// consumeAccountToken checks a token from an emailed link and marks it used within tx
+func consumeAccountToken(tx *gorm.DB, token string, purpose models.UserTokenPurpose) (*models.UserToken, error) {
+	claims, err := utils.ValidateActionToken(token, string(purpose))
+	if err != nil {
+		return nil, newHTTPError(http.StatusBadRequest, "Invalid or expired link")
+	}
+
+	var row models.UserToken
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ? AND purpose = ?", utils.HashToken(token), purpose).
+		First(&row).Error; err != nil || row.UserID != claims.UserID {
+		// Signed but unknown: replaced by a newer link
+		return nil, newHTTPError(http.StatusBadRequest, "This link is no longer valid; request a new one")
+	}
+	if row.UsedAt != nil {
+		return nil, newHTTPError(http.StatusBadRequest, "This link has already been used")
+	}
+	if time.Now().After(row.ExpiresAt) {
+		return nil, newHTTPError(http.StatusBadRequest, "Invalid or expired link")
+	}
+
+	now := time.Now()
+	if err := tx.Model(&row).Update("used_at", now).Error; err != nil {
+		return nil, err
+	}
+	row.UsedAt = &now
+	return &row, nil
+}
+
+// accountEmailSentRecently reports whether the user was sent a link for
+// purpose within accountEmailCooldown
+func accountEmailSentRecently(userID uint, purpose models.UserTokenPurpose) bool {
+	var recent int64
+	config.DB.Model(&models.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND created_at > ?", userID, purpose, time.Now().Add(-accountEmailCooldown)).
+		Count(&recent)
+	return recent > 0
+}
+
+// accountTokenTTL is how long emailed links for purpose stay valid
+func accountTokenTTL(purpose models.UserTokenPurpose) time.Duration {
+	if purpose == models.UserTokenPasswordReset {
+		return config.GetEnvDuration("PASSWORD_RESET_TTL", time.Hour)
+	}
+	return config.GetEnvDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour)
+}
+
+// humanizeDuration formats d for emails, e.g. "48 hours" or "30 minutes"
+func humanizeDuration(d time.Duration) string {
+	unit, n := "minute", int(d.Minutes())
+	if d >= time.Hour && d%time.Hour == 0 {
+		unit, n = "hour", int(d.Hours())
+	}
+	if n == 1 {
+		return fmt.Sprintf("1 %s", unit)
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
@@ -0,0 +1,411 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/nilabhsubramaniam/kapas/internal/config"
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+	"github.com/nilabhsubramaniam/kapas/internal/utils"
+)
+
+// indianPinCode matches a 6-digit Indian postal code; they never start with 0
+var indianPinCode = regexp.MustCompile(`^[1-9][0-9]{5}$`)
+
+// AddressRequest represents an address create or update request
+type AddressRequest struct {
+	FullName     string `json:"full_name" binding:"required,max=100" example:"Priya Sharma"`
+	Phone        string `json:"phone" binding:"required" example:"9876543210"`
+	AddressLine1 string `json:"address_line1" binding:"required,max=255" example:"12, MG Road"`
+	AddressLine2 string `json:"address_line2" binding:"max=255" example:"Near City Mall"`
+	Landmark     string `json:"landmark" binding:"max=255" example:"Opposite Metro Station"`
+	CountryID    uint   `json:"country_id" binding:"required" example:"1"`
+	StateID      uint   `json:"state_id" binding:"required" example:"5"`
+	DistrictID   uint   `json:"district_id" binding:"required" example:"42"`
+	PinCode      string `json:"pin_code" binding:"required" example:"226001"`
+	AddressType  string `json:"address_type" binding:"omitempty,oneof=shipping billing home work" example:"shipping"`
+	IsDefault    bool   `json:"is_default" example:"true"`
+}
+
+// ListAddresses godoc
+// @Summary List addresses
+// @Description Get the current user's saved addresses, defaults first
+// @Tags Addresses
+// @Produce json
+// @Security BearerAuth
+// @Param type query string false "Filter by address type (shipping, billing, home, work)"
+// @Success 200 {object} map[string]interface{} "Addresses"
+// @Router /me/addresses [get]
+func ListAddresses(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	query := config.DB.Where("user_id = ?", userID)
+	if addressType := c.Query("type"); addressType != "" {
+		query = query.Where("address_type = ?", addressType)
+	}
+
+	var addresses []models.Address
+	if err := query.Preload("Country").
+		Preload("State").
+		Preload("District").
+		Order("is_default DESC, updated_at DESC").
+		Find(&addresses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch addresses"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"addresses": addresses})
+}
+
+// GetAddress godoc
+// @Summary Get address
+// @Description Get one of the current user's saved addresses
+// @Tags Addresses
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Address ID"
+// @Success 200 {object} models.Address "Address"
+// @Failure 404 {object} ErrorResponse "Address not found"
+// @Router /me/addresses/{id} [get]
+func GetAddress(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	address, err := loadAddress(config.DB, userID, c.Param("id"))
+	if err != nil {
+		respondError(c, err, "Failed to fetch address")
+		return
+	}
+
+	c.JSON(http.StatusOK, address)
+}
+
+// CreateAddress godoc
+// @Summary Add address
+// @Description Save a new address for the current user. The first address of a type becomes its default.
+// @Tags Addresses
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body AddressRequest true "Address details"
+// @Success 201 {object} map[string]interface{} "Address created"
+// @Failure 400 {object} ErrorResponse "Invalid address"
+// @Router /me/addresses [post]
+func CreateAddress(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req AddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	address := models.Address{UserID: userID}
+	if err := applyAddressRequest(config.DB, &address, &req); err != nil {
+		respondError(c, err, "Invalid address")
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockAddressBook(tx, userID); err != nil {
+			return err
+		}
+		if !address.IsDefault {
+			var others int64
+			if err := tx.Model(&models.Address{}).
+				Where("user_id = ? AND address_type = ?", userID, address.AddressType).
+				Count(&others).Error; err != nil {
+				return err
+			}
+			address.IsDefault = others == 0
+		}
+		if address.IsDefault {
+			if err := clearDefaultAddress(tx, userID, address.AddressType); err != nil {
+				return err
+			}
+		}
+		return tx.Create(&address).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create address"})
+		return
+	}
+
+	created, err := loadAddress(config.DB, userID, address.ID)
+	if err != nil {
+		respondError(c, err, "Failed to fetch address")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Address added successfully",
+		"address": created,
+	})
+}
+
+// UpdateAddress godoc
+// @Summary Update address
+// @Description Replace one of the current user's saved addresses. Orders already placed keep the address they were placed with.
+// @Tags Addresses
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Address ID"
+// @Param request body AddressRequest true "Address details"
+// @Success 200 {object} map[string]interface{} "Address updated"
+// @Failure 400 {object} ErrorResponse "Invalid address"
+// @Failure 404 {object} ErrorResponse "Address not found"
+// @Router /me/addresses/{id} [put]
+func UpdateAddress(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req AddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockAddressBook(tx, userID); err != nil {
+			return err
+		}
+		address, err := loadAddress(tx, userID, c.Param("id"))
+		if err != nil {
+			return err
+		}
+		wasDefault, oldType := address.IsDefault, address.AddressType
+
+		if err := applyAddressRequest(tx, address, &req); err != nil {
+			return err
+		}
+		// Unsetting the flag on the default would leave its type without one
+		if wasDefault && oldType == address.AddressType {
+			address.IsDefault = true
+		}
+		if address.IsDefault {
+			if err := clearDefaultAddress(tx, userID, address.AddressType); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Omit(clause.Associations).Save(address).Error; err != nil {
+			return err
+		}
+		if wasDefault && oldType != address.AddressType {
+			return promoteDefaultAddress(tx, userID, oldType)
+		}
+		return nil
+	})
+	if err != nil {
+		respondError(c, err, "Failed to update address")
+		return
+	}
+
+	updated, err := loadAddress(config.DB, userID, c.Param("id"))
+	if err != nil {
+		respondError(c, err, "Failed to fetch address")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Address updated successfully",
+		"address": updated,
+	})
+}
+
+// SetDefaultAddress godoc
+// @Summary Set default address
+// @Description Make an address the default for its type
+// @Tags Addresses
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Address ID"
+// @Success 200 {object} MessageResponse "Default address set"
+// @Failure 404 {object} ErrorResponse "Address not found"
+// @Router /me/addresses/{id}/default [put]
+func SetDefaultAddress(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockAddressBook(tx, userID); err != nil {
+			return err
+		}
+		address, err := loadAddress(tx, userID, c.Param("id"))
+		if err != nil {
+			return err
+		}
+		if err := clearDefaultAddress(tx, userID, address.AddressType); err != nil {
+			return err
+		}
+		return tx.Model(address).Update("is_default", true).Error
+	})
+	if err != nil {
+		respondError(c, err, "Failed to set default address")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Default address set successfully"})
+}
+
+// DeleteAddress godoc
+// @Summary Delete address
+// @Description Delete one of the current user's saved addresses. If it was the default, the most recently updated address of the same type takes its place.
+// @Tags Addresses
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Address ID"
+// @Success 200 {object} MessageResponse "Address deleted"
+// @Failure 404 {object} ErrorResponse "Address not found"
+// @Router /me/addresses/{id} [delete]
+func DeleteAddress(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockAddressBook(tx, userID); err != nil {
+			return err
+		}
+		address, err := loadAddress(tx, userID, c.Param("id"))
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(address).Error; err != nil {
+			return err
+		}
+		if address.IsDefault {
+			return promoteDefaultAddress(tx, userID, address.AddressType)
+		}
+		return nil
+	})
+	if err != nil {
+		respondError(c, err, "Failed to delete address")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Address deleted successfully"})
+}
+
+// loadAddress fetches one of a user's addresses with its location names
+func loadAddress(db *gorm.DB, userID uint, id interface{}) (*models.Address, error) {
+	var address models.Address
+	err := db.Where("id = ? AND user_id = ?", id, userID).
+		Preload("Country").
+		Preload("State").
+		Preload("District").
+		First(&address).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, newHTTPError(http.StatusNotFound, "Address not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
+
+// applyAddressRequest validates req and copies it onto address. The district
+// must be in the state and the state in the country; the phone and pin code
+// are checked against the country's formats.
+func applyAddressRequest(db *gorm.DB, address *models.Address, req *AddressRequest) error {
+	var country models.Country
+	if err := db.Where("id = ? AND is_active = ?", req.CountryID, true).First(&country).Error; err != nil {
+		return newHTTPError(http.StatusBadRequest, "Invalid country")
+	}
+	var state models.State
+	if err := db.Where("id = ? AND country_id = ? AND is_active = ?", req.StateID, country.ID, true).
+		First(&state).Error; err != nil {
+		return newHTTPError(http.StatusBadRequest, "State does not belong to the selected country")
+	}
+	var district models.District
+	if err := db.Where("id = ? AND state_id = ? AND is_active = ?", req.DistrictID, state.ID, true).
+		First(&district).Error; err != nil {
+		return newHTTPError(http.StatusBadRequest, "District does not belong to the selected state")
+	}
+
+	pinCode := strings.ReplaceAll(strings.TrimSpace(req.PinCode), " ", "")
+	if country.Code == "IN" && !indianPinCode.MatchString(pinCode) {
+		return newHTTPError(http.StatusBadRequest, "Pin code must be 6 digits")
+	}
+	if pinCode == "" || len(pinCode) > 10 {
+		return newHTTPError(http.StatusBadRequest, "Invalid pin code")
+	}
+
+	phone, err := utils.NormalizePhone(req.Phone, country.PhoneCode)
+	if err != nil {
+		return newHTTPError(http.StatusBadRequest, "Invalid phone number")
+	}
+
+	addressType := req.AddressType
+	if addressType == "" {
+		addressType = "shipping"
+	}
+
+	address.FullName = strings.TrimSpace(req.FullName)
+	address.Phone = phone
+	address.AddressLine1 = strings.TrimSpace(req.AddressLine1)
+	address.AddressLine2 = strings.TrimSpace(req.AddressLine2)
+	address.Landmark = strings.TrimSpace(req.Landmark)
+	address.CountryID = country.ID
+	address.StateID = state.ID
+	address.DistrictID = district.ID
+	address.PinCode = pinCode
+	address.AddressType = addressType
+	address.IsDefault = req.IsDefault
+	return nil
+}
+
+// lockAddressBook serialises default changes to a user's addresses within tx
+func lockAddressBook(tx *gorm.DB, userID uint) error {
+	var user models.User
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, userID).Error
+}
+
+// clearDefaultAddress unsets the user's default address of addressType
+func clearDefaultAddress(tx *gorm.DB, userID uint, addressType string) error {
+	return tx.Model(&models.Address{}).
+		Where("user_id = ? AND address_type = ? AND is_default = ?", userID, addressType, true).
+		Update("is_default", false).Error
+}
+
+// promoteDefaultAddress makes the user's most recently updated address of
+// addressType the default, if there is one
+func promoteDefaultAddress(tx *gorm.DB, userID uint, addressType string) error {
+	var next models.Address
+	err := tx.Where("user_id = ? AND address_type = ?", userID, addressType).
+		Order("updated_at DESC").
+		First(&next).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Model(&next).Update("is_default", true).Error
+}
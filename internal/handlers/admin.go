@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
 	"github.com/nilabhsubramaniam/kapas/internal/config"
 	"github.com/nilabhsubramaniam/kapas/internal/models"
 	"github.com/nilabhsubramaniam/kapas/internal/utils"
@@ -38,7 +40,7 @@ func GetDashboard(c *gin.Context) {
 	config.DB.Model(&models.User{}).Count(&stats.TotalUsers)
 	config.DB.Model(&models.Order{}).Count(&stats.TotalOrders)
 	config.DB.Model(&models.Product{}).Count(&stats.TotalProducts)
-	config.DB.Model(&models.Order{}).Where("status = ?", "PENDING").Count(&stats.PendingOrders)
+	config.DB.Model(&models.Order{}).Where("status = ?", models.OrderStatusPending).Count(&stats.PendingOrders)
 	config.DB.Model(&models.Product{}).Where("stock_quantity < ?", 10).Count(&stats.LowStockProducts)
 
 	// Calculate total revenue
@@ -46,7 +48,7 @@ func GetDashboard(c *gin.Context) {
 		Total float64
 	}
 	config.DB.Model(&models.Order{}).
-		Where("status IN ?", []string{"COMPLETED", "DELIVERED"}).
+		Where("status = ?", models.OrderStatusDelivered).
 		Select("COALESCE(SUM(total_amount), 0) as total").
 		Scan(&revenue)
 	stats.TotalRevenue = revenue.Total
@@ -105,11 +107,12 @@ func GetSalesAnalytics(c *gin.Context) {
 
 // GetRevenueAnalytics godoc
 // @Summary Get revenue analytics
-// @Description Get revenue breakdown by product type, state, etc.
+// @Description Get revenue breakdown by product type, state and craft region. Cancelled, returned and refunded orders are left out.
 // @Tags Admin
 // @Produce json
 // @Security BearerAuth
 // @Success 200 {object} map[string]interface{} "Revenue analytics"
+// @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /admin/analytics/revenue [get]
 func GetRevenueAnalytics(c *gin.Context) {
 	// Revenue by product type
@@ -119,11 +122,14 @@ func GetRevenueAnalytics(c *gin.Context) {
 		Orders      int64   `json:"orders"`
 	}
 
-	config.DB.Table("order_items").
-		Select("products.product_type, COUNT(DISTINCT order_items.order_id) as orders, COALESCE(SUM(order_items.price * order_items.quantity), 0) as revenue").
+	if err := revenueOrderItems().
+		Select("products.product_type, COUNT(DISTINCT order_items.order_id) as orders, COALESCE(SUM(order_items.total_price), 0) as revenue").
 		Joins("JOIN products ON products.id = order_items.product_id").
 		Group("products.product_type").
-		Scan(&byProductType)
+		Scan(&byProductType).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revenue analytics"})
+		return
+	}
 
 	// Revenue by state origin
 	var byState []struct {
@@ -132,19 +138,54 @@ func GetRevenueAnalytics(c *gin.Context) {
 		Orders  int64   `json:"orders"`
 	}
 
-	config.DB.Table("order_items").
-		Select("products.state_origin as state, COUNT(DISTINCT order_items.order_id) as orders, COALESCE(SUM(order_items.price * order_items.quantity), 0) as revenue").
+	// A product's state comes from its region; state_origin covers products without one
+	stateExpr := "COALESCE(states.code, NULLIF(products.state_origin, ''))"
+	if err := revenueOrderItems().
+		Select(stateExpr+" as state, COUNT(DISTINCT order_items.order_id) as orders, COALESCE(SUM(order_items.total_price), 0) as revenue").
 		Joins("JOIN products ON products.id = order_items.product_id").
-		Where("products.state_origin IS NOT NULL AND products.state_origin != ''").
-		Group("products.state_origin").
-		Scan(&byState)
+		Joins("LEFT JOIN regions ON regions.id = products.region_id").
+		Joins("LEFT JOIN states ON states.id = regions.state_id").
+		Where(stateExpr + " IS NOT NULL").
+		Group(stateExpr).
+		Scan(&byState).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revenue analytics"})
+		return
+	}
+
+	// Revenue by craft region
+	var byRegion []struct {
+		Region  string  `json:"region"`
+		Slug    string  `json:"slug"`
+		Revenue float64 `json:"revenue"`
+		Orders  int64   `json:"orders"`
+	}
+
+	if err := revenueOrderItems().
+		Select("regions.name as region, regions.slug, COUNT(DISTINCT order_items.order_id) as orders, COALESCE(SUM(order_items.total_price), 0) as revenue").
+		Joins("JOIN products ON products.id = order_items.product_id").
+		Joins("JOIN regions ON regions.id = products.region_id").
+		Group("regions.name, regions.slug").
+		Scan(&byRegion).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revenue analytics"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"by_product_type": byProductType,
 		"by_state":        byState,
+		"by_region":       byRegion,
 	})
 }
 
+// revenueOrderItems selects the items of orders that count as revenue,
+// leaving out cancelled, returned and refunded ones
+func revenueOrderItems() *gorm.DB {
+	return config.DB.Table("order_items").
+		Joins("JOIN orders ON orders.id = order_items.order_id AND orders.deleted_at IS NULL").
+		Where("orders.status NOT IN ?", []models.OrderStatus{models.OrderStatusCancelled, models.OrderStatusReturned}).
+		Where("orders.payment_status <> ?", models.PaymentStatusRefunded)
+}
+
 // ============================================
 // USER MANAGEMENT
 // ============================================
@@ -232,7 +273,7 @@ func GetUserOrders(c *gin.Context) {
 	config.DB.Model(&models.Order{}).Where("user_id = ?", userID).Count(&total)
 
 	config.DB.Where("user_id = ?", userID).
-		Preload("OrderItems").
+		Preload("Items").
 		Preload("Payment").
 		Order("created_at DESC").
 		Limit(pagination.PerPage).
@@ -278,7 +319,36 @@ func UpdateUserStatus(c *gin.Context) {
 		updates["role"] = req.Role
 	}
 
-	if err := config.DB.Model(&models.User{}).Where("id = ?", userID).Updates(updates).Error; err != nil {
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	before := auditSnapshot(user)
+	oldRole := user.Role
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		// Deactivated or re-roled users must log in again
+		if (req.IsActive != nil && !*req.IsActive) || (req.Role != "" && models.UserRole(req.Role) != oldRole) {
+			if err := revokeSessions(tx, "user_id = ?", user.ID); err != nil {
+				return err
+			}
+		}
+
+		return recordAudit(tx, c, auditEntry{
+			Action:      "USER_UPDATED",
+			EntityType:  "user",
+			EntityID:    user.ID,
+			Description: "Updated user " + user.Email,
+			Before:      before,
+			After:       user,
+		})
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
 		return
 	}
@@ -298,7 +368,7 @@ func UpdateUserStatus(c *gin.Context) {
 // @Security BearerAuth
 // @Param page query int false "Page number" default(1)
 // @Param per_page query int false "Items per page" default(20)
-// @Param status query string false "Filter by status (PENDING, CONFIRMED, SHIPPED, etc.)"
+// @Param status query string false "Filter by status (pending, confirmed, shipped, etc.)"
 // @Param user_id query int false "Filter by user ID"
 // @Success 200 {object} map[string]interface{} "Paginated orders list"
 // @Router /admin/orders [get]
@@ -323,8 +393,8 @@ func ListAllOrders(c *gin.Context) {
 	query.Count(&total)
 
 	query.Preload("User").
-		Preload("OrderItems").
-		Preload("OrderItems.Product").
+		Preload("Items").
+		Preload("Items.Product").
 		Preload("Payment").
 		Order("created_at DESC").
 		Limit(pagination.PerPage).
@@ -334,134 +404,271 @@ func ListAllOrders(c *gin.Context) {
 	c.JSON(http.StatusOK, utils.PaginatedResponse(orders, total, pagination.Page, pagination.PerPage))
 }
 
+// UpdateOrderStatusRequest represents admin order status update request
+type UpdateOrderStatusRequest struct {
+	Status         string `json:"status" binding:"required" example:"shipped"`
+	TrackingNumber string `json:"tracking_number" example:"AWB123456789"`
+	ProviderID     uint   `json:"provider_id" example:"1"`
+	Notes          string `json:"notes" example:"Packed and handed to courier"`
+}
+
 // UpdateOrderStatus godoc
 // @Summary Update order status
-// @Description Update order status and tracking (Admin only)
+// @Description Move an order through its lifecycle; illegal transitions are rejected (Admin only)
 // @Tags Admin
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "Order ID"
-// @Param request body map[string]interface{} true "Status update"
-// @Success 200 {object} MessageResponse "Order updated successfully"
+// @Param request body UpdateOrderStatusRequest true "Status update"
+// @Success 200 {object} map[string]interface{} "Order updated successfully"
+// @Failure 400 {object} ErrorResponse "Invalid status"
+// @Failure 404 {object} ErrorResponse "Order not found"
+// @Failure 409 {object} ErrorResponse "Illegal status transition"
 // @Router /admin/orders/{id}/status [put]
 func UpdateOrderStatus(c *gin.Context) {
-	orderID := c.Param("id")
-	
-	var req struct {
-		Status         string `json:"status"`
-		TrackingNumber string `json:"tracking_number"`
-		Notes          string `json:"notes"`
-	}
+	adminID, _ := currentUserID(c)
 
+	var req UpdateOrderStatusRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	updates := make(map[string]interface{})
-	if req.Status != "" {
-		updates["status"] = req.Status
-	}
-	if req.TrackingNumber != "" {
-		updates["tracking_number"] = req.TrackingNumber
-	}
+	var order models.Order
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockOrder(tx, &order, "id = ?", c.Param("id")); err != nil {
+			return newHTTPError(http.StatusNotFound, "Order not found")
+		}
 
-	if err := config.DB.Model(&models.Order{}).Where("id = ?", orderID).Updates(updates).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order"})
-		return
-	}
+		before := auditSnapshot(order)
+		if err := transitionOrder(tx, &order, orderTransition{
+			To:         models.OrderStatus(req.Status),
+			ActorID:    adminID,
+			Comment:    req.Notes,
+			ProviderID: req.ProviderID,
+			AWBNumber:  req.TrackingNumber,
+		}); err != nil {
+			return err
+		}
 
-	// Create activity log
-	if req.Notes != "" {
-		orderIDInt, _ := strconv.ParseUint(orderID, 10, 32)
-		log := models.ActivityLog{
-			UserID:      0, // System action
+		description := fmt.Sprintf("Order %s moved to %s", order.OrderNumber, order.Status)
+		if req.Notes != "" {
+			description += ": " + req.Notes
+		}
+		return recordAudit(tx, c, auditEntry{
 			Action:      "ORDER_STATUS_UPDATED",
-			Description: req.Notes,
 			EntityType:  "order",
-			EntityID:    uint(orderIDInt),
+			EntityID:    order.ID,
+			Description: description,
+			Before:      before,
+			After:       order,
+		})
+	})
+	if err != nil {
+		respondError(c, err, "Failed to update order")
+		return
+	}
+
+	response := gin.H{
+		"message": "Order updated successfully",
+		"order":   order,
+	}
+	if order.Status == models.OrderStatusCancelled {
+		if refund := refundCancelledOrder(c.Request.Context(), &order, adminID); refund != nil {
+			response["refund"] = refund
 		}
-		config.DB.Create(&log)
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Order updated successfully"})
+	c.JSON(http.StatusOK, response)
 }
 
 // ============================================
 // INVENTORY MANAGEMENT
 // ============================================
 
+// UpdateInventoryRequest represents admin stock update request
+type UpdateInventoryRequest struct {
+	WarehouseID       uint  `json:"warehouse_id" example:"1"` // Defaults to the default warehouse
+	StockQuantity     *int  `json:"stock_quantity" binding:"required,min=0" example:"50"`
+	LowStockThreshold *int  `json:"low_stock_threshold" binding:"omitempty,min=0" example:"10"`
+	IsActive          *bool `json:"is_active" example:"true"`
+
+	// Recorded on the stock movement ledger
+	MovementType string `json:"movement_type" binding:"omitempty,oneof=receipt adjustment" example:"adjustment"` // Defaults to adjustment
+	Reason       string `json:"reason" example:"Quarterly stock count"`
+}
+
 // GetInventory godoc
 // @Summary Get inventory list
-// @Description Get all products with stock information (Admin only)
+// @Description Get per-warehouse stock levels with reservations (Admin only)
 // @Tags Admin
 // @Produce json
 // @Security BearerAuth
 // @Param page query int false "Page number" default(1)
 // @Param per_page query int false "Items per page" default(50)
-// @Param low_stock query bool false "Show only low stock items (< 10)"
+// @Param warehouse_id query int false "Filter by warehouse"
+// @Param product_id query int false "Filter by product"
+// @Param low_stock query bool false "Show only rows at or below their low stock threshold"
+// @Param region query string false "Filter by product region slug"
+// @Param vendor_id query int false "Filter by product vendor"
 // @Success 200 {object} map[string]interface{} "Inventory list"
 // @Router /admin/inventory [get]
 func GetInventory(c *gin.Context) {
 	pagination := utils.GetPaginationParams(c)
-	
-	var products []models.Product
+
+	var rows []models.Inventory
 	var total int64
 
-	query := config.DB.Model(&models.Product{})
+	query := config.DB.Model(&models.Inventory{})
 
-	// Filter low stock
+	if warehouseID := c.Query("warehouse_id"); warehouseID != "" {
+		query = query.Where("warehouse_id = ?", warehouseID)
+	}
+	if productID := c.Query("product_id"); productID != "" {
+		query = query.Where("product_id = ?", productID)
+	}
 	if c.Query("low_stock") == "true" {
-		query = query.Where("stock_quantity < ?", 10)
+		query = query.Where("quantity - reserved_quantity <= low_stock_threshold")
+	}
+	if region, vendorID := c.Query("region"), c.Query("vendor_id"); region != "" || vendorID != "" {
+		query = query.Where("product_id IN (?)", config.DB.Model(&models.Product{}).
+			Select("id").
+			Scopes(productOriginFilters(region, vendorID, "")))
 	}
 
 	query.Count(&total)
 
-	query.Select("id, name, slug, product_type, state_origin, stock_quantity, base_price, final_price, is_active").
-		Order("stock_quantity ASC").
+	query.Preload("Product", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, name, slug, product_type, region_id, vendor_id, state_origin, stock_quantity, base_price, final_price, is_active")
+	}).
+		Preload("Product.Region").
+		Preload("Warehouse").
+		Order("(quantity - reserved_quantity) ASC").
 		Limit(pagination.PerPage).
-		Offset((pagination.Page - 1) * pagination.PerPage).
-		Find(&products)
+		Offset(pagination.Offset).
+		Find(&rows)
 
-	c.JSON(http.StatusOK, utils.PaginatedResponse(products, total, pagination.Page, pagination.PerPage))
+	c.JSON(http.StatusOK, utils.PaginatedResponse(rows, total, pagination.Page, pagination.PerPage))
 }
 
 // UpdateInventory godoc
 // @Summary Update product inventory
-// @Description Update stock quantity for a product (Admin only)
+// @Description Set on-hand stock for a product in a warehouse; the change is recorded on the stock ledger and product availability is recomputed (Admin only)
 // @Tags Admin
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "Product ID"
-// @Param request body map[string]interface{} true "Inventory update"
-// @Success 200 {object} MessageResponse "Inventory updated successfully"
+// @Param request body UpdateInventoryRequest true "Inventory update"
+// @Success 200 {object} map[string]interface{} "Inventory updated successfully"
+// @Failure 400 {object} ErrorResponse "Quantity below reserved stock"
+// @Failure 404 {object} ErrorResponse "Product or warehouse not found"
 // @Router /admin/inventory/{id} [put]
 func UpdateInventory(c *gin.Context) {
-	productID := c.Param("id")
-	
-	var req struct {
-		StockQuantity int  `json:"stock_quantity"`
-		IsActive      *bool `json:"is_active"`
-	}
+	adminID, _ := currentUserID(c)
 
+	var req UpdateInventoryRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	updates := map[string]interface{}{
-		"stock_quantity": req.StockQuantity,
-	}
-	if req.IsActive != nil {
-		updates["is_active"] = *req.IsActive
+	var product models.Product
+	if err := config.DB.First(&product, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
 	}
 
-	if err := config.DB.Model(&models.Product{}).Where("id = ?", productID).Updates(updates).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update inventory"})
+	var row models.Inventory
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		warehouseID := req.WarehouseID
+		if warehouseID == 0 {
+			warehouse, err := defaultWarehouse(tx)
+			if err != nil {
+				return err
+			}
+			warehouseID = warehouse.ID
+		} else if err := tx.First(&models.Warehouse{}, warehouseID).Error; err != nil {
+			return newHTTPError(http.StatusNotFound, "Warehouse not found")
+		}
+
+		if err := lockInventoryRow(tx, &row, product.ID, warehouseID); err != nil {
+			return err
+		}
+
+		before := auditSnapshot(row)
+		before["is_active"] = product.IsActive
+
+		if *req.StockQuantity < row.ReservedQuantity {
+			return newHTTPError(http.StatusBadRequest,
+				fmt.Sprintf("Stock cannot be set below the %d units reserved for open orders", row.ReservedQuantity))
+		}
+
+		movement := models.StockMovement{
+			ProductID:   product.ID,
+			WarehouseID: warehouseID,
+			Type:        models.StockMovementAdjustment,
+			Quantity:    *req.StockQuantity - row.Quantity,
+			Reason:      req.Reason,
+			ActorID:     adminID,
+		}
+		if req.MovementType == string(models.StockMovementReceipt) {
+			if movement.Quantity < 0 {
+				return newHTTPError(http.StatusBadRequest, "A receipt cannot reduce stock")
+			}
+			movement.Type = models.StockMovementReceipt
+		}
+
+		updates := map[string]interface{}{"quantity": *req.StockQuantity}
+		if req.LowStockThreshold != nil {
+			updates["low_stock_threshold"] = *req.LowStockThreshold
+		}
+		if err := tx.Model(&row).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := recordStockMovement(tx, movement); err != nil {
+			return err
+		}
+
+		if req.IsActive != nil {
+			if err := tx.Model(&product).Update("is_active", *req.IsActive).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := syncProductStock(tx, product.ID); err != nil {
+			return err
+		}
+
+		if err := tx.First(&row, row.ID).Error; err != nil {
+			return err
+		}
+		after := auditSnapshot(row)
+		after["is_active"] = product.IsActive
+		return recordAudit(tx, c, auditEntry{
+			Action:      "INVENTORY_UPDATED",
+			EntityType:  "inventory",
+			EntityID:    row.ID,
+			Description: fmt.Sprintf("Set stock of %s in warehouse %d to %d", product.Name, row.WarehouseID, row.Quantity),
+			Before:      before,
+			After:       after,
+			Extra: map[string]interface{}{
+				"product_id":    product.ID,
+				"movement_type": movement.Type,
+				"reason":        req.Reason,
+			},
+		})
+	})
+	if err != nil {
+		respondError(c, err, "Failed to update inventory")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Inventory updated successfully"})
+	config.DB.Preload("Warehouse").First(&row, row.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Inventory updated successfully",
+		"inventory": row,
+	})
 }
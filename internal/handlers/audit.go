@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/nilabhsubramaniam/kapas/internal/config"
+	"github.com/nilabhsubramaniam/kapas/internal/middleware"
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+	"github.com/nilabhsubramaniam/kapas/internal/utils"
+)
+
+// auditEntry describes one admin change for the activity log
+type auditEntry struct {
+	Action      string // e.g. PRODUCT_UPDATED
+	EntityType  string
+	EntityID    uint
+	Description string
+	Before      interface{}            // Entity before the change; nil for creates
+	After       interface{}            // Entity after the change; nil for deletes
+	Extra       map[string]interface{} // Extra metadata stored as is
+}
+
+// auditIgnoredFields are left out of before/after diffs
+var auditIgnoredFields = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+}
+
+// recordAudit writes an activity log entry for the acting admin within db,
+// with the request's IP address and user agent and a field-level diff of
+// Before and After in Metadata["changes"]
+func recordAudit(db *gorm.DB, c *gin.Context, e auditEntry) error {
+	actorID, _ := currentUserID(c)
+
+	metadata := models.JSONB{
+		"method": c.Request.Method,
+		"path":   c.Request.URL.Path,
+	}
+	for key, value := range e.Extra {
+		metadata[key] = value
+	}
+	if changes := auditDiff(auditSnapshot(e.Before), auditSnapshot(e.After)); len(changes) > 0 {
+		metadata["changes"] = changes
+	}
+
+	entry := models.ActivityLog{
+		UserID:      actorID,
+		Action:      e.Action,
+		EntityType:  e.EntityType,
+		EntityID:    e.EntityID,
+		Description: e.Description,
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.Request.UserAgent(),
+		Metadata:    metadata,
+	}
+	if err := db.Create(&entry).Error; err != nil {
+		return err
+	}
+
+	c.Set(middleware.AuditRecordedKey, true)
+	return nil
+}
+
+// auditSnapshot captures an entity's JSON fields; take it before changing
+// the entity so later writes don't alter the "before" side of the diff
+func auditSnapshot(v interface{}) map[string]interface{} {
+	if v == nil {
+		return nil
+	}
+	if snapshot, ok := v.(map[string]interface{}); ok {
+		return snapshot
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var snapshot map[string]interface{}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil
+	}
+	return snapshot
+}
+
+// auditDiff lists the fields that differ as {"field": {"from": x, "to": y}}
+func auditDiff(before, after map[string]interface{}) map[string]interface{} {
+	changes := map[string]interface{}{}
+	for field, from := range before {
+		if auditIgnoredFields[field] {
+			continue
+		}
+		if to, ok := after[field]; !ok || !reflect.DeepEqual(from, to) {
+			changes[field] = map[string]interface{}{"from": from, "to": after[field]}
+		}
+	}
+	for field, to := range after {
+		if _, seen := before[field]; seen || auditIgnoredFields[field] {
+			continue
+		}
+		changes[field] = map[string]interface{}{"from": nil, "to": to}
+	}
+	return changes
+}
+
+// ListAuditLogs godoc
+// @Summary Search the audit log
+// @Description Search admin activity with its before/after changes (Admin only)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(20)
+// @Param user_id query int false "Filter by acting user"
+// @Param action query string false "Filter by action (e.g. PRODUCT_UPDATED)"
+// @Param entity_type query string false "Filter by entity type (product, user, order, inventory, coupon, ...)"
+// @Param entity_id query int false "Filter by entity ID"
+// @Param ip_address query string false "Filter by IP address"
+// @Param from query string false "Created on or after (YYYY-MM-DD)"
+// @Param to query string false "Created before the end of (YYYY-MM-DD)"
+// @Param q query string false "Search in the description"
+// @Success 200 {object} map[string]interface{} "Audit log entries"
+// @Router /admin/audit-logs [get]
+func ListAuditLogs(c *gin.Context) {
+	pagination := utils.GetPaginationParams(c)
+
+	var logs []models.ActivityLog
+	var total int64
+
+	query := config.DB.Model(&models.ActivityLog{})
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if entityType := c.Query("entity_type"); entityType != "" {
+		query = query.Where("entity_type = ?", entityType)
+	}
+	if entityID := c.Query("entity_id"); entityID != "" {
+		query = query.Where("entity_id = ?", entityID)
+	}
+	if ip := c.Query("ip_address"); ip != "" {
+		query = query.Where("ip_address = ?", ip)
+	}
+	if from := c.Query("from"); from != "" {
+		query = query.Where("created_at >= ?", from)
+	}
+	if to := c.Query("to"); to != "" {
+		query = query.Where("created_at < ?::date + INTERVAL '1 day'", to)
+	}
+	if search := c.Query("q"); search != "" {
+		query = query.Where("description ILIKE ?", "%"+search+"%")
+	}
+
+	query.Count(&total)
+
+	query.Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, name, email, role")
+	}).
+		Order("created_at DESC, id DESC").
+		Limit(pagination.PerPage).
+		Offset(pagination.Offset).
+		Find(&logs)
+
+	c.JSON(http.StatusOK, utils.PaginatedResponse(logs, total, pagination.Page, pagination.PerPage))
+}
+
+// GetAuditLog godoc
+// @Summary Get audit log entry
+// @Description Get one admin activity entry (Admin only)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Activity log ID"
+// @Success 200 {object} models.ActivityLog
+// @Failure 404 {object} ErrorResponse "Entry not found"
+// @Router /admin/audit-logs/{id} [get]
+func GetAuditLog(c *gin.Context) {
+	var entry models.ActivityLog
+	if err := config.DB.Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, name, email, role")
+	}).First(&entry, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Entry not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
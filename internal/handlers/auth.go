@@ -1,15 +1,17 @@
 package handlers
 
 import (
+	"context"
+	"log"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 
 	"github.com/nilabhsubramaniam/kapas/internal/config"
 	"github.com/nilabhsubramaniam/kapas/internal/models"
-	"github.com/nilabhsubramaniam/kapas/internal/utils"
 )
 
 type RegisterRequest struct {
@@ -26,7 +28,7 @@ type LoginRequest struct {
 
 // Register godoc
 // @Summary Register a new user
-// @Description Create a new user account with email and password
+// @Description Create a new user account with email and password; a verification link is emailed to the user
 // @Tags Authentication
 // @Accept json
 // @Produce json
@@ -63,7 +65,7 @@ func Register(c *gin.Context) {
 		Email:        req.Email,
 		PasswordHash: string(hashedPassword),
 		Name:         req.Name,
-		Phone:        req.Phone,
+		Phone:        req.Phone, // Unverified, so OTP login never matches it
 		Role:         models.RoleCustomer,
 		IsActive:     true,
 	}
@@ -73,13 +75,20 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateToken(user.ID, user.Email, string(user.Role))
+	// Start a session with an access and refresh token
+	tokens, err := startSession(config.DB, c, &user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
+	// Send the verification link without holding up the response
+	go func(user models.User) {
+		if err := sendAccountToken(context.Background(), &user, models.UserTokenEmailVerification); err != nil {
+			log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+		}
+	}(user)
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "User registered successfully",
 		"user": gin.H{
@@ -88,13 +97,15 @@ func Register(c *gin.Context) {
 			"name":  user.Name,
 			"role":  user.Role,
 		},
-		"token": token,
+		"token":         tokens.Token,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
 	})
 }
 
 // Login godoc
 // @Summary Login user
-// @Description Authenticate user with email and password, returns JWT token
+// @Description Authenticate user with email and password, returns a short-lived access token and a refresh token
 // @Tags Authentication
 // @Accept json
 // @Produce json
@@ -136,8 +147,8 @@ func Login(c *gin.Context) {
 	user.LastLogin = &now
 	config.DB.Save(&user)
 
-	// Generate JWT token
-	token, err := utils.GenerateToken(user.ID, user.Email, string(user.Role))
+	// Start a session with an access and refresh token
+	tokens, err := startSession(config.DB, c, &user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
@@ -151,7 +162,9 @@ func Login(c *gin.Context) {
 			"name":  user.Name,
 			"role":  user.Role,
 		},
-		"token": token,
+		"token":         tokens.Token,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
 	})
 }
 
@@ -191,13 +204,27 @@ func GetCurrentUser(c *gin.Context) {
 
 // Logout godoc
 // @Summary Logout user
-// @Description Invalidate current session (client should discard token)
+// @Description End the current session: its refresh tokens are revoked and its access tokens stop working
 // @Tags Authentication
 // @Produce json
 // @Security BearerAuth
 // @Success 200 {object} MessageResponse "Logged out successfully"
 // @Router /auth/logout [post]
 func Logout(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return revokeSessions(tx, "user_id = ? AND family_id = ?", userID, c.GetString("session_id"))
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Logged out successfully",
 	})
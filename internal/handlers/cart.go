@@ -2,41 +2,301 @@ package handlers
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nilabhsubramaniam/kapas/internal/config"
+	"github.com/nilabhsubramaniam/kapas/internal/models"
 )
 
-// GetCart returns user's cart
+// AddToCartRequest represents add-to-cart request
+type AddToCartRequest struct {
+	ProductID uint `json:"product_id" binding:"required" example:"1"`
+	Quantity  int  `json:"quantity" binding:"required,min=1" example:"1"`
+}
+
+// UpdateCartItemRequest represents cart quantity update request
+type UpdateCartItemRequest struct {
+	Quantity int `json:"quantity" binding:"required,min=1" example:"2"`
+}
+
+// CartLine represents a single cart item with computed prices
+type CartLine struct {
+	ID        uint           `json:"id" example:"1"`
+	ProductID uint           `json:"product_id" example:"1"`
+	Quantity  int            `json:"quantity" example:"2"`
+	UnitPrice float64        `json:"unit_price" example:"3999.00"`
+	BasePrice float64        `json:"base_price" example:"4999.00"`
+	LineTotal float64        `json:"line_total" example:"7998.00"`
+	InStock   bool           `json:"in_stock" example:"true"`
+	Product   models.Product `json:"product"`
+	AddedAt   time.Time      `json:"added_at"`
+}
+
+// CartSummary represents cart totals
+type CartSummary struct {
+	Subtotal  float64 `json:"subtotal" example:"9998.00"` // Sum of base prices
+	Discount  float64 `json:"discount" example:"2000.00"` // Product discounts (base - final)
+	Total     float64 `json:"total" example:"7998.00"`    // Sum of line totals
+	ItemCount int     `json:"item_count" example:"2"`     // Sum of quantities
+	LineCount int     `json:"line_count" example:"1"`     // Distinct products
+}
+
+// CartResponse represents the full cart
+type CartResponse struct {
+	Items   []CartLine  `json:"items"`
+	Summary CartSummary `json:"summary"`
+}
+
+// GetCart godoc
+// @Summary Get cart
+// @Description Get the authenticated user's cart with line totals and summary
+// @Tags Cart
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} CartResponse "Cart contents"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Router /cart [get]
 func GetCart(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Get cart endpoint - To be implemented",
-	})
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	items, err := loadCartItems(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load cart"})
+		return
+	}
+
+	c.JSON(http.StatusOK, buildCart(items))
 }
 
-// AddToCart adds an item to cart
+// AddToCart godoc
+// @Summary Add item to cart
+// @Description Add a product to the cart, merging with an existing line for the same product
+// @Tags Cart
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body AddToCartRequest true "Product and quantity"
+// @Success 200 {object} CartResponse "Updated cart"
+// @Failure 400 {object} ErrorResponse "Invalid request or insufficient stock"
+// @Failure 404 {object} ErrorResponse "Product not found"
+// @Router /cart/items [post]
 func AddToCart(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Add to cart endpoint - To be implemented",
-	})
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req AddToCartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var product models.Product
+	if err := config.DB.Where("id = ? AND is_active = ?", req.ProductID, true).First(&product).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	// Merge with existing line for the same product
+	var item models.CartItem
+	err := config.DB.Where("user_id = ? AND product_id = ?", userID, req.ProductID).First(&item).Error
+	quantity := req.Quantity
+	if err == nil {
+		quantity += item.Quantity
+	}
+
+	if quantity > product.StockQuantity {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     "Insufficient stock",
+			"available": product.StockQuantity,
+		})
+		return
+	}
+
+	if err == nil {
+		item.Quantity = quantity
+		err = config.DB.Save(&item).Error
+	} else {
+		item = models.CartItem{
+			UserID:    userID,
+			ProductID: req.ProductID,
+			Quantity:  quantity,
+			AddedAt:   time.Now(),
+		}
+		err = config.DB.Create(&item).Error
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update cart"})
+		return
+	}
+
+	respondWithCart(c, userID)
 }
 
-// UpdateCartItem updates cart item quantity
+// UpdateCartItem godoc
+// @Summary Update cart item quantity
+// @Description Set the quantity of a cart line
+// @Tags Cart
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Cart item ID"
+// @Param request body UpdateCartItemRequest true "New quantity"
+// @Success 200 {object} CartResponse "Updated cart"
+// @Failure 400 {object} ErrorResponse "Invalid request or insufficient stock"
+// @Failure 404 {object} ErrorResponse "Cart item not found"
+// @Router /cart/items/{id} [put]
 func UpdateCartItem(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Update cart item endpoint - To be implemented",
-	})
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req UpdateCartItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var item models.CartItem
+	if err := config.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).
+		Preload("Product").
+		First(&item).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cart item not found"})
+		return
+	}
+
+	if !item.Product.IsActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Product is no longer available"})
+		return
+	}
+
+	if req.Quantity > item.Product.StockQuantity {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     "Insufficient stock",
+			"available": item.Product.StockQuantity,
+		})
+		return
+	}
+
+	if err := config.DB.Model(&item).Update("quantity", req.Quantity).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update cart"})
+		return
+	}
+
+	respondWithCart(c, userID)
 }
 
-// RemoveFromCart removes an item from cart
+// RemoveFromCart godoc
+// @Summary Remove item from cart
+// @Description Remove a single line from the cart
+// @Tags Cart
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Cart item ID"
+// @Success 200 {object} CartResponse "Updated cart"
+// @Failure 404 {object} ErrorResponse "Cart item not found"
+// @Router /cart/items/{id} [delete]
 func RemoveFromCart(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Remove from cart endpoint - To be implemented",
-	})
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	result := config.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).Delete(&models.CartItem{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove item"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cart item not found"})
+		return
+	}
+
+	respondWithCart(c, userID)
 }
 
-// ClearCart clears all cart items
+// ClearCart godoc
+// @Summary Clear cart
+// @Description Remove all items from the cart
+// @Tags Cart
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} MessageResponse "Cart cleared"
+// @Router /cart [delete]
 func ClearCart(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Clear cart endpoint - To be implemented",
-	})
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := config.DB.Where("user_id = ?", userID).Delete(&models.CartItem{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear cart"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Cart cleared"})
+}
+
+// loadCartItems fetches a user's cart items with products, oldest first
+func loadCartItems(userID uint) ([]models.CartItem, error) {
+	var items []models.CartItem
+	err := config.DB.Where("user_id = ?", userID).
+		Preload("Product").
+		Preload("Product.Images").
+		Order("added_at ASC").
+		Find(&items).Error
+	return items, err
+}
+
+// buildCart computes line totals and the cart summary from FinalPrice
+func buildCart(items []models.CartItem) CartResponse {
+	cart := CartResponse{Items: make([]CartLine, 0, len(items))}
+
+	for _, item := range items {
+		line := CartLine{
+			ID:        item.ID,
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			UnitPrice: item.Product.FinalPrice,
+			BasePrice: item.Product.BasePrice,
+			LineTotal: roundAmount(item.Product.FinalPrice * float64(item.Quantity)),
+			InStock:   item.Product.IsActive && item.Quantity <= item.Product.StockQuantity,
+			Product:   item.Product,
+			AddedAt:   item.AddedAt,
+		}
+		cart.Items = append(cart.Items, line)
+
+		cart.Summary.Subtotal += item.Product.BasePrice * float64(item.Quantity)
+		cart.Summary.Total += line.LineTotal
+		cart.Summary.ItemCount += item.Quantity
+	}
+
+	cart.Summary.Subtotal = roundAmount(cart.Summary.Subtotal)
+	cart.Summary.Total = roundAmount(cart.Summary.Total)
+	cart.Summary.Discount = roundAmount(cart.Summary.Subtotal - cart.Summary.Total)
+	cart.Summary.LineCount = len(cart.Items)
+
+	return cart
+}
+
+// respondWithCart writes the user's current cart as the response
+func respondWithCart(c *gin.Context, userID uint) {
+	items, err := loadCartItems(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load cart"})
+		return
+	}
+
+	c.JSON(http.StatusOK, buildCart(items))
 }
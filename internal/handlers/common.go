@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"errors"
+	"math"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -16,51 +18,42 @@ func HealthCheck(c *gin.Context) {
 	})
 }
 
-// GetDashboard returns admin dashboard data (placeholder)
-func GetDashboard(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Dashboard endpoint - To be implemented",
-	})
-}
-
-// GetSalesAnalytics returns sales analytics (placeholder)
-func GetSalesAnalytics(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Sales analytics endpoint - To be implemented",
-	})
+// currentUserID returns the authenticated user's ID set by AuthMiddleware
+func currentUserID(c *gin.Context) (uint, bool) {
+	value, exists := c.Get("user_id")
+	if !exists {
+		return 0, false
+	}
+	userID, ok := value.(uint)
+	return userID, ok && userID != 0
 }
 
-// GetRevenueAnalytics returns revenue analytics (placeholder)
-func GetRevenueAnalytics(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Revenue analytics endpoint - To be implemented",
-	})
+// roundAmount rounds a rupee amount to 2 decimal places
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
 }
 
-// GetInventory returns inventory data (placeholder)
-func GetInventory(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Inventory endpoint - To be implemented",
-	})
+// httpError is a business-rule failure that maps to an HTTP status
+type httpError struct {
+	Status  int
+	Message string
 }
 
-// UpdateInventory updates inventory (placeholder)
-func UpdateInventory(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Update inventory endpoint - To be implemented",
-	})
+func (e *httpError) Error() string {
+	return e.Message
 }
 
-// ListAllOrders returns all orders for admin (placeholder)
-func ListAllOrders(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "List all orders endpoint - To be implemented",
-	})
+// newHTTPError creates an httpError with the given status and message
+func newHTTPError(status int, message string) error {
+	return &httpError{Status: status, Message: message}
 }
 
-// UpdateOrderStatus updates order status (placeholder)
-func UpdateOrderStatus(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Update order status endpoint - To be implemented",
-	})
+// respondError writes err as JSON, using fallback for unexpected errors
+func respondError(c *gin.Context, err error, fallback string) {
+	var he *httpError
+	if errors.As(err, &he) {
+		c.JSON(he.Status, gin.H{"error": he.Message})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
 }
@@ -0,0 +1,458 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/nilabhsubramaniam/kapas/internal/config"
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+	"github.com/nilabhsubramaniam/kapas/internal/utils"
+)
+
+// ApplyCouponRequest represents coupon validation request
+type ApplyCouponRequest struct {
+	Code string `json:"code" binding:"required" example:"FESTIVE10"`
+}
+
+// CouponRequest represents admin coupon create/update request
+type CouponRequest struct {
+	Code           string    `json:"code" binding:"required" example:"FESTIVE10"`
+	Description    string    `json:"description" example:"10% off festive collection"`
+	DiscountType   string    `json:"discount_type" binding:"required,oneof=percentage fixed" example:"percentage"`
+	DiscountValue  float64   `json:"discount_value" binding:"required,gt=0" example:"10"`
+	MinOrderAmount float64   `json:"min_order_amount" binding:"gte=0" example:"1999"`
+	MaxDiscount    float64   `json:"max_discount" binding:"gte=0" example:"500"`
+	UsageLimit     int       `json:"usage_limit" binding:"gte=0" example:"100"`
+	ValidFrom      time.Time `json:"valid_from" binding:"required" example:"2024-10-01T00:00:00Z"`
+	ValidUntil     time.Time `json:"valid_until" binding:"required" example:"2024-11-15T23:59:59Z"`
+	IsActive       *bool     `json:"is_active" example:"true"`
+}
+
+// ApplyCoupon godoc
+// @Summary Apply coupon to cart
+// @Description Validate a coupon against the authenticated user's cart and preview the discount
+// @Tags Cart
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ApplyCouponRequest true "Coupon code"
+// @Success 200 {object} map[string]interface{} "Coupon discount preview"
+// @Failure 400 {object} ErrorResponse "Coupon not applicable"
+// @Failure 404 {object} ErrorResponse "Coupon not found"
+// @Router /cart/coupon [post]
+func ApplyCoupon(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req ApplyCouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	items, err := loadCartItems(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load cart"})
+		return
+	}
+	cart := buildCart(items)
+	if len(cart.Items) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cart is empty"})
+		return
+	}
+
+	var coupon models.Coupon
+	if err := config.DB.Where("code = ?", normalizeCouponCode(req.Code)).First(&coupon).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coupon not found"})
+		return
+	}
+
+	discount, err := checkCoupon(config.DB, &coupon, userID, cart.Summary.Total)
+	if err != nil {
+		respondError(c, err, "Failed to validate coupon")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"coupon":          coupon,
+		"coupon_discount": discount,
+		"cart_total":      cart.Summary.Total,
+		"total":           roundAmount(cart.Summary.Total - discount),
+	})
+}
+
+// lockCoupon locks and validates a coupon for checkout and returns the discount
+func lockCoupon(tx *gorm.DB, code string, userID uint, amount float64) (*models.Coupon, float64, error) {
+	var coupon models.Coupon
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("code = ?", normalizeCouponCode(code)).
+		First(&coupon).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, 0, newHTTPError(http.StatusNotFound, "Coupon not found")
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	discount, err := checkCoupon(tx, &coupon, userID, amount)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return &coupon, discount, nil
+}
+
+// recordCouponUsage records a coupon redemption for an order and bumps its usage count
+func recordCouponUsage(tx *gorm.DB, coupon *models.Coupon, userID, orderID uint) error {
+	usage := models.CouponUsage{
+		CouponID: coupon.ID,
+		UserID:   userID,
+		OrderID:  orderID,
+		UsedAt:   time.Now(),
+	}
+	if err := tx.Create(&usage).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(coupon).Update("used_count", gorm.Expr("used_count + 1")).Error
+}
+
+// releaseCoupon frees the coupon used on an order so it can be used again
+func releaseCoupon(tx *gorm.DB, orderID uint) error {
+	var usage models.CouponUsage
+	err := tx.Where("order_id = ?", orderID).First(&usage).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Delete(&usage).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.Coupon{}).
+		Where("id = ? AND used_count > 0", usage.CouponID).
+		Update("used_count", gorm.Expr("used_count - 1")).Error
+}
+
+// checkCoupon validates a coupon for a user and order amount and returns the discount
+func checkCoupon(db *gorm.DB, coupon *models.Coupon, userID uint, amount float64) (float64, error) {
+	now := time.Now()
+
+	if !coupon.IsActive {
+		return 0, newHTTPError(http.StatusBadRequest, "Coupon is not active")
+	}
+	if now.Before(coupon.ValidFrom) {
+		return 0, newHTTPError(http.StatusBadRequest, "Coupon is not valid yet")
+	}
+	if !coupon.ValidUntil.IsZero() && now.After(coupon.ValidUntil) {
+		return 0, newHTTPError(http.StatusBadRequest, "Coupon has expired")
+	}
+	if amount < coupon.MinOrderAmount {
+		return 0, newHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("Minimum order amount for this coupon is ₹%.2f", coupon.MinOrderAmount))
+	}
+	if coupon.UsageLimit > 0 && coupon.UsedCount >= coupon.UsageLimit {
+		return 0, newHTTPError(http.StatusBadRequest, "Coupon usage limit reached")
+	}
+
+	var used int64
+	if err := db.Model(&models.CouponUsage{}).
+		Where("coupon_id = ? AND user_id = ?", coupon.ID, userID).
+		Count(&used).Error; err != nil {
+		return 0, err
+	}
+	if used > 0 {
+		return 0, newHTTPError(http.StatusBadRequest, "Coupon already used")
+	}
+
+	return couponDiscount(coupon, amount), nil
+}
+
+// couponDiscount computes the discount a coupon gives on an amount
+func couponDiscount(coupon *models.Coupon, amount float64) float64 {
+	var discount float64
+
+	switch coupon.DiscountType {
+	case models.CouponTypePercentage:
+		discount = amount * coupon.DiscountValue / 100
+		if coupon.MaxDiscount > 0 {
+			discount = math.Min(discount, coupon.MaxDiscount)
+		}
+	case models.CouponTypeFixed:
+		discount = coupon.DiscountValue
+	}
+
+	return roundAmount(math.Min(discount, amount))
+}
+
+// normalizeCouponCode makes coupon codes case-insensitive
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// ============================================
+// ADMIN COUPON MANAGEMENT
+// ============================================
+
+// ListCoupons godoc
+// @Summary List coupons
+// @Description Get paginated list of coupons (Admin only)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(20)
+// @Param active query bool false "Filter by active flag"
+// @Param search query string false "Search by code"
+// @Success 200 {object} map[string]interface{} "Paginated coupons list"
+// @Router /admin/coupons [get]
+func ListCoupons(c *gin.Context) {
+	pagination := utils.GetPaginationParams(c)
+
+	var coupons []models.Coupon
+	var total int64
+
+	query := config.DB.Model(&models.Coupon{})
+
+	if active := c.Query("active"); active != "" {
+		query = query.Where("is_active = ?", active == "true")
+	}
+	if search := c.Query("search"); search != "" {
+		query = query.Where("code ILIKE ?", "%"+search+"%")
+	}
+
+	query.Count(&total)
+
+	query.Order("created_at DESC").
+		Limit(pagination.PerPage).
+		Offset(pagination.Offset).
+		Find(&coupons)
+
+	c.JSON(http.StatusOK, utils.PaginatedResponse(coupons, total, pagination.Page, pagination.PerPage))
+}
+
+// GetCoupon godoc
+// @Summary Get coupon
+// @Description Get a coupon with its usages (Admin only)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Coupon ID"
+// @Success 200 {object} models.Coupon "Coupon details"
+// @Failure 404 {object} ErrorResponse "Coupon not found"
+// @Router /admin/coupons/{id} [get]
+func GetCoupon(c *gin.Context) {
+	var coupon models.Coupon
+	if err := config.DB.Preload("Usages").First(&coupon, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coupon not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, coupon)
+}
+
+// CreateCoupon godoc
+// @Summary Create coupon
+// @Description Create a new coupon (Admin only)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CouponRequest true "Coupon details"
+// @Success 201 {object} map[string]interface{} "Coupon created"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 409 {object} ErrorResponse "Coupon code already exists"
+// @Router /admin/coupons [post]
+func CreateCoupon(c *gin.Context) {
+	var req CouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateCouponRequest(&req); err != nil {
+		respondError(c, err, "Invalid coupon")
+		return
+	}
+
+	var existing models.Coupon
+	if err := config.DB.Unscoped().Where("code = ?", normalizeCouponCode(req.Code)).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Coupon code already exists"})
+		return
+	}
+
+	coupon := models.Coupon{IsActive: true}
+	applyCouponRequest(&coupon, &req)
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&coupon).Error; err != nil {
+			return err
+		}
+
+		// GORM skips false for columns with default:true on insert
+		if !coupon.IsActive {
+			if err := tx.Model(&coupon).Update("is_active", false).Error; err != nil {
+				return err
+			}
+		}
+
+		return recordAudit(tx, c, auditEntry{
+			Action:      "COUPON_CREATED",
+			EntityType:  "coupon",
+			EntityID:    coupon.ID,
+			Description: "Created coupon " + coupon.Code,
+			After:       coupon,
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create coupon"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Coupon created successfully",
+		"coupon":  coupon,
+	})
+}
+
+// UpdateCoupon godoc
+// @Summary Update coupon
+// @Description Update an existing coupon (Admin only)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Coupon ID"
+// @Param request body CouponRequest true "Coupon details"
+// @Success 200 {object} map[string]interface{} "Coupon updated"
+// @Failure 404 {object} ErrorResponse "Coupon not found"
+// @Failure 409 {object} ErrorResponse "Coupon code already exists"
+// @Router /admin/coupons/{id} [put]
+func UpdateCoupon(c *gin.Context) {
+	var req CouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateCouponRequest(&req); err != nil {
+		respondError(c, err, "Invalid coupon")
+		return
+	}
+
+	var coupon models.Coupon
+	if err := config.DB.First(&coupon, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coupon not found"})
+		return
+	}
+
+	var existing models.Coupon
+	if err := config.DB.Unscoped().
+		Where("code = ? AND id <> ?", normalizeCouponCode(req.Code), coupon.ID).
+		First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Coupon code already exists"})
+		return
+	}
+
+	before := auditSnapshot(coupon)
+	applyCouponRequest(&coupon, &req)
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&coupon).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, auditEntry{
+			Action:      "COUPON_UPDATED",
+			EntityType:  "coupon",
+			EntityID:    coupon.ID,
+			Description: "Updated coupon " + coupon.Code,
+			Before:      before,
+			After:       coupon,
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update coupon"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Coupon updated successfully",
+		"coupon":  coupon,
+	})
+}
+
+// DeleteCoupon godoc
+// @Summary Delete coupon
+// @Description Soft delete a coupon (Admin only)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Coupon ID"
+// @Success 200 {object} MessageResponse "Coupon deleted"
+// @Failure 404 {object} ErrorResponse "Coupon not found"
+// @Router /admin/coupons/{id} [delete]
+func DeleteCoupon(c *gin.Context) {
+	var coupon models.Coupon
+	if err := config.DB.First(&coupon, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coupon not found"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&coupon).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, auditEntry{
+			Action:      "COUPON_DELETED",
+			EntityType:  "coupon",
+			EntityID:    coupon.ID,
+			Description: "Deleted coupon " + coupon.Code,
+			Before:      coupon,
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete coupon"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Coupon deleted successfully"})
+}
+
+// validateCouponRequest checks rules the binding tags can't express
+func validateCouponRequest(req *CouponRequest) error {
+	if req.DiscountType == models.CouponTypePercentage && req.DiscountValue > 100 {
+		return newHTTPError(http.StatusBadRequest, "Percentage discount cannot exceed 100")
+	}
+	if !req.ValidUntil.After(req.ValidFrom) {
+		return newHTTPError(http.StatusBadRequest, "valid_until must be after valid_from")
+	}
+	return nil
+}
+
+// applyCouponRequest copies request fields onto a coupon
+func applyCouponRequest(coupon *models.Coupon, req *CouponRequest) {
+	coupon.Code = normalizeCouponCode(req.Code)
+	coupon.Description = req.Description
+	coupon.DiscountType = req.DiscountType
+	coupon.DiscountValue = req.DiscountValue
+	coupon.MinOrderAmount = req.MinOrderAmount
+	coupon.MaxDiscount = req.MaxDiscount
+	coupon.UsageLimit = req.UsageLimit
+	coupon.ValidFrom = req.ValidFrom
+	coupon.ValidUntil = req.ValidUntil
+	if req.IsActive != nil {
+		coupon.IsActive = *req.IsActive
+	}
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nilabhsubramaniam/kapas/internal/config"
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+	"github.com/nilabhsubramaniam/kapas/internal/serviceability"
+	"github.com/nilabhsubramaniam/kapas/internal/shipping"
+)
+
+// GetDeliveryEstimate godoc
+// @Summary Check delivery to a pin code
+// @Description Whether we deliver to a pin code, whether cash on delivery is available there, and, for a product, the estimated delivery time from the nearest warehouse holding stock and the shipping charge
+// @Tags Delivery
+// @Produce json
+// @Param pin query string true "6-digit pin code"
+// @Param product_id query int false "Product ID"
+// @Success 200 {object} map[string]interface{} "Serviceability and estimate"
+// @Failure 400 {object} ErrorResponse "Invalid pin code"
+// @Failure 404 {object} ErrorResponse "Product not found"
+// @Router /delivery/estimate [get]
+func GetDeliveryEstimate(c *gin.Context) {
+	pin, err := serviceability.NormalizePinCode(c.Query("pin"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Please enter a valid 6-digit pin code"})
+		return
+	}
+
+	var product *models.Product
+	if value := c.Query("product_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 64)
+		var found models.Product
+		if err != nil || config.DB.Where("id = ? AND is_active = ?", id, true).First(&found).Error != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		}
+		product = &found
+	}
+
+	dest, err := serviceability.Lookup(config.DB, pin)
+	if err != nil {
+		log.Printf("Failed to look up pin code %s: %v", pin, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check delivery"})
+		return
+	}
+
+	response := gin.H{
+		"pin_code":      pin,
+		"serviceable":   dest.Serviceable(),
+		"cod_available": dest.CODAvailable(),
+	}
+	if dest.PinCode != nil {
+		response["district"] = dest.DistrictName()
+		response["state"] = dest.StateName()
+	}
+	if !dest.Serviceable() {
+		response["message"] = "Sorry, we don't deliver to this pin code yet"
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	var productID uint
+	itemsTotal := 0.0
+	if product != nil {
+		productID, itemsTotal = product.ID, product.FinalPrice
+		response["product_id"] = product.ID
+	}
+	response["shipping_charge"] = shippingCharge(itemsTotal)
+	response["free_shipping_threshold"] = config.GetEnvFloat("FREE_SHIPPING_THRESHOLD", 999)
+
+	origin, err := serviceability.NearestWarehouse(config.DB, dest, productID, 1)
+	if err != nil {
+		log.Printf("Failed to find a warehouse for pin code %s: %v", pin, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check delivery"})
+		return
+	}
+	if product != nil {
+		response["in_stock"] = origin != nil
+	}
+	if origin == nil {
+		if product != nil {
+			response["message"] = "This product is currently out of stock"
+		}
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	days := serviceability.EstimatedDays(origin, dest)
+	response["estimated_days"] = days
+	response["estimated_delivery"] = shipping.EstimateDelivery(time.Now(), days).Format("2006-01-02")
+	response["ships_from"] = origin.Warehouse.City + ", " + origin.Warehouse.State
+	response["zone"] = origin.Zone
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ImportPinCodes godoc
+// @Summary Import pin codes
+// @Description Load pin code to district mappings from a CSV file, e.g. the India Post directory. Required columns: pincode, district, statename; optional: officename, latitude, longitude. Missing districts are created. (Admin only)
+// @Tags Admin
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "CSV file"
+// @Success 200 {object} map[string]interface{} "Import summary"
+// @Failure 400 {object} ErrorResponse "Missing or invalid file"
+// @Router /admin/serviceability/pin-codes [post]
+func ImportPinCodes(c *gin.Context) {
+	file, name, ok := openUploadedCSV(c)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	result, err := serviceability.ImportPinCodes(config.DB, file)
+	respondImport(c, result, err, auditEntry{
+		Action:     "PIN_CODES_IMPORTED",
+		EntityType: "pin_code",
+	}, name)
+}
+
+// ImportPinCodeCoverage godoc
+// @Summary Import courier coverage
+// @Description Load which couriers deliver to which pin codes from a CSV file. Required columns: pincode, courier (provider code); optional: prepaid, cod (Y/N) and extra_days. With replace=true, couriers in the file lose pin codes the file doesn't list. (Admin only)
+// @Tags Admin
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "CSV file"
+// @Param replace query bool false "Replace the listed couriers' coverage"
+// @Success 200 {object} map[string]interface{} "Import summary"
+// @Failure 400 {object} ErrorResponse "Missing or invalid file"
+// @Router /admin/serviceability/coverage [post]
+func ImportPinCodeCoverage(c *gin.Context) {
+	file, name, ok := openUploadedCSV(c)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	replace := c.Query("replace") == "true"
+	result, err := serviceability.ImportCoverage(config.DB, file, replace)
+	respondImport(c, result, err, auditEntry{
+		Action:     "PIN_CODE_COVERAGE_IMPORTED",
+		EntityType: "pin_code_coverage",
+		Extra:      map[string]interface{}{"replace": replace},
+	}, name)
+}
+
+// openUploadedCSV opens the CSV file uploaded as "file"; on failure it
+// responds and returns ok false
+func openUploadedCSV(c *gin.Context) (multipart.File, string, bool) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload a CSV file as 'file'"})
+		return nil, "", false
+	}
+	if !strings.HasSuffix(strings.ToLower(header.Filename), ".csv") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File must be a .csv"})
+		return nil, "", false
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return nil, "", false
+	}
+	return file, header.Filename, true
+}
+
+// respondImport audits a finished CSV import and writes its summary
+func respondImport(c *gin.Context, result *serviceability.ImportResult, err error, entry auditEntry, fileName string) {
+	if errors.Is(err, serviceability.ErrInvalidCSV) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to import %s: %v", fileName, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import file"})
+		return
+	}
+
+	if entry.Extra == nil {
+		entry.Extra = map[string]interface{}{}
+	}
+	entry.Extra["file"] = fileName
+	entry.Extra["result"] = result
+	entry.Description = fmt.Sprintf("Imported %d rows from %s", result.Imported, fileName)
+	if err := recordAudit(config.DB, c, entry); err != nil {
+		log.Printf("Failed to record audit log for %s import: %v", fileName, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Import completed",
+		"result":  result,
+	})
+}
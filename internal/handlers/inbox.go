@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/nilabhsubramaniam/kapas/internal/config"
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+	"github.com/nilabhsubramaniam/kapas/internal/utils"
+)
+
+// inboxQuery scopes notifications to a user's in-app inbox
+func inboxQuery(userID uint) *gorm.DB {
+	return config.DB.Model(&models.Notification{}).
+		Where("user_id = ? AND channel = ?", userID, models.NotificationChannelInApp)
+}
+
+// ListNotifications godoc
+// @Summary List notifications
+// @Description Get the current user's in-app notifications, newest first
+// @Tags Notifications
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(20)
+// @Param unread query bool false "Only unread notifications"
+// @Param type query string false "Filter by type (order, payment, shipping, promotion, system)"
+// @Success 200 {object} map[string]interface{} "Notifications list with unread count"
+// @Router /notifications [get]
+func ListNotifications(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	pagination := utils.GetPaginationParams(c)
+
+	var notifications []models.Notification
+	var total int64
+
+	query := inboxQuery(userID)
+	if c.Query("unread") == "true" {
+		query = query.Where("is_read = ?", false)
+	}
+	if notificationType := c.Query("type"); notificationType != "" {
+		query = query.Where("type = ?", notificationType)
+	}
+
+	query.Count(&total)
+
+	query.Order("created_at DESC, id DESC").
+		Limit(pagination.PerPage).
+		Offset(pagination.Offset).
+		Find(&notifications)
+
+	var unread int64
+	inboxQuery(userID).Where("is_read = ?", false).Count(&unread)
+
+	response := utils.PaginatedResponse(notifications, total, pagination.Page, pagination.PerPage)
+	response["unread_count"] = unread
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetUnreadNotificationCount godoc
+// @Summary Unread notification count
+// @Description Get how many in-app notifications the current user hasn't read
+// @Tags Notifications
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Unread count"
+// @Router /notifications/unread-count [get]
+func GetUnreadNotificationCount(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var unread int64
+	if err := inboxQuery(userID).Where("is_read = ?", false).Count(&unread).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"unread_count": unread})
+}
+
+// MarkNotificationRead godoc
+// @Summary Mark notification read
+// @Description Mark one of the current user's notifications as read
+// @Tags Notifications
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Notification ID"
+// @Success 200 {object} models.Notification
+// @Failure 404 {object} ErrorResponse "Notification not found"
+// @Router /notifications/{id}/read [put]
+func MarkNotificationRead(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var notification models.Notification
+	if err := inboxQuery(userID).Where("id = ?", c.Param("id")).First(&notification).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+
+	if !notification.IsRead {
+		now := time.Now()
+		if err := config.DB.Model(&notification).Updates(map[string]interface{}{
+			"is_read": true,
+			"read_at": now,
+		}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
+			return
+		}
+		notification.IsRead = true
+		notification.ReadAt = &now
+	}
+
+	c.JSON(http.StatusOK, notification)
+}
+
+// MarkAllNotificationsRead godoc
+// @Summary Mark all notifications read
+// @Description Mark every unread in-app notification of the current user as read
+// @Tags Notifications
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Number of notifications updated"
+// @Router /notifications/read-all [put]
+func MarkAllNotificationsRead(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	result := inboxQuery(userID).
+		Where("is_read = ?", false).
+		Updates(map[string]interface{}{
+			"is_read": true,
+			"read_at": time.Now(),
+		})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Notifications marked as read",
+		"updated": result.RowsAffected,
+	})
+}
+
+// StreamNotifications godoc
+// @Summary Notification stream
+// @Description Server-Sent Events stream of the current user's new in-app notifications. Sends "notification" events with the notification as data and "unread_count" events whenever the count changes. Browsers that can't set headers may pass the JWT as the access_token query parameter; reconnecting clients resume from Last-Event-ID. The stream ends with a "session_ended" event when the access token expires or is revoked; reconnect with a fresh token.
+// @Tags Notifications
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Param access_token query string false "JWT, for EventSource clients"
+// @Success 200 {string} string "Event stream"
+// @Router /notifications/stream [get]
+func StreamNotifications(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Streaming not supported"})
+		return
+	}
+
+	// Resume after the last event the client saw, else start from now
+	var lastID uint
+	if id, err := strconv.ParseUint(c.GetHeader("Last-Event-ID"), 10, 64); err == nil {
+		lastID = uint(id)
+	} else {
+		inboxQuery(userID).Select("COALESCE(MAX(id), 0)").Scan(&lastID)
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Disable nginx response buffering
+	c.Status(http.StatusOK)
+
+	// The token was only checked when the stream opened
+	tokenID := c.GetString("token_id")
+	expiry := time.NewTimer(time.Until(c.GetTime("token_expires_at")))
+	defer expiry.Stop()
+
+	unread := int64(-1)
+	poll := time.NewTicker(config.GetEnvDuration("NOTIFY_STREAM_POLL_INTERVAL", 3*time.Second))
+	defer poll.Stop()
+	heartbeat := time.NewTicker(25 * time.Second)
+	defer heartbeat.Stop()
+
+	// send writes any new notifications and the unread count if it changed,
+	// unless the token has been revoked since the stream opened
+	send := func() error {
+		var revoked int64
+		if err := config.DB.Model(&models.RevokedToken{}).Where("jti = ?", tokenID).Count(&revoked).Error; err != nil {
+			return err
+		}
+		if revoked > 0 {
+			return endStream(c.Writer, flusher, "Token has been revoked")
+		}
+
+		var fresh []models.Notification
+		if err := inboxQuery(userID).Where("id > ?", lastID).Order("id ASC").Limit(100).Find(&fresh).Error; err != nil {
+			return err
+		}
+		for _, n := range fresh {
+			if err := writeSSE(c.Writer, fmt.Sprint(n.ID), "notification", n); err != nil {
+				return err
+			}
+			lastID = n.ID
+		}
+
+		var count int64
+		if err := inboxQuery(userID).Where("is_read = ?", false).Count(&count).Error; err != nil {
+			return err
+		}
+		if count != unread {
+			if err := writeSSE(c.Writer, "", "unread_count", gin.H{"unread_count": count}); err != nil {
+				return err
+			}
+			unread = count
+		}
+
+		flusher.Flush()
+		return nil
+	}
+
+	if err := send(); err != nil {
+		return
+	}
+
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-expiry.C:
+			endStream(c.Writer, flusher, "Token has expired")
+			return
+		case <-poll.C:
+			if err := send(); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// errStreamEnded stops a notification stream whose token is no longer valid
+var errStreamEnded = errors.New("stream ended")
+
+// endStream tells the client why its stream is closing and returns errStreamEnded
+func endStream(w http.ResponseWriter, flusher http.Flusher, reason string) error {
+	if err := writeSSE(w, "", "session_ended", gin.H{"error": reason}); err != nil {
+		return err
+	}
+	flusher.Flush()
+	return errStreamEnded
+}
+
+// writeSSE writes one Server-Sent Event with a JSON payload
+func writeSSE(w http.ResponseWriter, id, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
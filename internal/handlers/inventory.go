@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/nilabhsubramaniam/kapas/internal/config"
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+)
+
+// errInsufficientStock is returned by reserveStock; callers add product context
+var errInsufficientStock = newHTTPError(http.StatusConflict, "Insufficient stock")
+
+// insufficientStockError names the product that could not be reserved
+func insufficientStockError(productName string) error {
+	return newHTTPError(http.StatusConflict, fmt.Sprintf("Insufficient stock for %q", productName))
+}
+
+// reservationTTL is how long unpaid online orders hold stock
+func reservationTTL() time.Duration {
+	return config.GetEnvDuration("STOCK_RESERVATION_TTL", 30*time.Minute)
+}
+
+// lockProductInventory locks a product's inventory rows in active warehouses,
+// most available stock first
+func lockProductInventory(tx *gorm.DB, productID uint) ([]models.Inventory, error) {
+	var rows []models.Inventory
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "inventory"}}).
+		Joins("JOIN warehouses ON warehouses.id = inventory.warehouse_id AND warehouses.deleted_at IS NULL").
+		Where("inventory.product_id = ? AND warehouses.is_active = ?", productID, true).
+		Order("(inventory.quantity - inventory.reserved_quantity) DESC, inventory.warehouse_id ASC").
+		Find(&rows).Error
+	return rows, err
+}
+
+// lockInventoryRow locks the inventory row for a product in a warehouse,
+// creating an empty one if it doesn't exist yet
+func lockInventoryRow(tx *gorm.DB, row *models.Inventory, productID, warehouseID uint) error {
+	seed := models.Inventory{ProductID: productID, WarehouseID: warehouseID}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&seed).Error; err != nil {
+		return err
+	}
+
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("product_id = ? AND warehouse_id = ?", productID, warehouseID).
+		First(row).Error
+}
+
+// reserveStock holds quantity of a product for an order, splitting across
+// warehouses when no single warehouse has enough
+func reserveStock(tx *gorm.DB, orderID, productID uint, quantity int, expiresAt *time.Time) error {
+	rows, err := lockProductInventory(tx, productID)
+	if err != nil {
+		return err
+	}
+
+	available := 0
+	for _, row := range rows {
+		if row.Available() > 0 {
+			available += row.Available()
+		}
+	}
+	if available < quantity {
+		return errInsufficientStock
+	}
+
+	remaining := quantity
+	for _, row := range rows {
+		if remaining == 0 {
+			break
+		}
+		take := row.Available()
+		if take <= 0 {
+			continue
+		}
+		if take > remaining {
+			take = remaining
+		}
+
+		if err := tx.Model(&models.Inventory{}).
+			Where("id = ?", row.ID).
+			Update("reserved_quantity", gorm.Expr("reserved_quantity + ?", take)).Error; err != nil {
+			return err
+		}
+
+		reservation := models.StockReservation{
+			OrderID:     orderID,
+			ProductID:   productID,
+			WarehouseID: row.WarehouseID,
+			Quantity:    take,
+			Status:      models.ReservationStatusActive,
+			ExpiresAt:   expiresAt,
+		}
+		if err := tx.Create(&reservation).Error; err != nil {
+			return err
+		}
+
+		remaining -= take
+	}
+
+	return syncProductStock(tx, productID)
+}
+
+// commitOrderStock deducts an order's active reservations from warehouse stock
+func commitOrderStock(tx *gorm.DB, orderID, actorID uint) error {
+	reservations, err := lockReservations(tx, orderID, models.ReservationStatusActive)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range reservations {
+		if err := tx.Model(&models.Inventory{}).
+			Where("product_id = ? AND warehouse_id = ?", r.ProductID, r.WarehouseID).
+			Updates(map[string]interface{}{
+				"quantity":          gorm.Expr("quantity - ?", r.Quantity),
+				"reserved_quantity": gorm.Expr("reserved_quantity - ?", r.Quantity),
+			}).Error; err != nil {
+			return err
+		}
+
+		if err := recordStockMovement(tx, models.StockMovement{
+			ProductID:     r.ProductID,
+			WarehouseID:   r.WarehouseID,
+			Type:          models.StockMovementSale,
+			Quantity:      -r.Quantity,
+			ReferenceType: models.StockReferenceOrder,
+			ReferenceID:   orderID,
+			ActorID:       actorID,
+		}); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&r).Updates(map[string]interface{}{
+			"status":     models.ReservationStatusCommitted,
+			"expires_at": nil,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// releaseOrderStock returns an order's reserved or committed stock to its warehouses
+func releaseOrderStock(tx *gorm.DB, orderID, actorID uint) error {
+	reservations, err := lockReservations(tx, orderID,
+		models.ReservationStatusActive, models.ReservationStatusCommitted)
+	if err != nil {
+		return err
+	}
+
+	productIDs := make([]uint, 0, len(reservations))
+	for _, r := range reservations {
+		updates := map[string]interface{}{
+			"reserved_quantity": gorm.Expr("reserved_quantity - ?", r.Quantity),
+		}
+		if r.Status == models.ReservationStatusCommitted {
+			updates = map[string]interface{}{
+				"quantity": gorm.Expr("quantity + ?", r.Quantity),
+			}
+		}
+
+		if err := tx.Model(&models.Inventory{}).
+			Where("product_id = ? AND warehouse_id = ?", r.ProductID, r.WarehouseID).
+			Updates(updates).Error; err != nil {
+			return err
+		}
+
+		if r.Status == models.ReservationStatusCommitted {
+			if err := recordStockMovement(tx, models.StockMovement{
+				ProductID:     r.ProductID,
+				WarehouseID:   r.WarehouseID,
+				Type:          models.StockMovementReturn,
+				Quantity:      r.Quantity,
+				ReferenceType: models.StockReferenceOrder,
+				ReferenceID:   orderID,
+				Reason:        "Order cancelled",
+				ActorID:       actorID,
+			}); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&r).Update("status", models.ReservationStatusReleased).Error; err != nil {
+			return err
+		}
+		productIDs = append(productIDs, r.ProductID)
+	}
+
+	return syncProductStock(tx, productIDs...)
+}
+
+// lockReservations loads an order's reservations in the given statuses for update
+func lockReservations(tx *gorm.DB, orderID uint, statuses ...models.ReservationStatus) ([]models.StockReservation, error) {
+	var reservations []models.StockReservation
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ? AND status IN ?", orderID, statuses).
+		Find(&reservations).Error
+	return reservations, err
+}
+
+// recordStockMovement appends a ledger entry for a change in on-hand stock
+func recordStockMovement(tx *gorm.DB, movement models.StockMovement) error {
+	if movement.Quantity == 0 {
+		return nil
+	}
+	return tx.Create(&movement).Error
+}
+
+// syncProductStock recomputes the cached Product.StockQuantity as available
+// stock across active warehouses
+func syncProductStock(tx *gorm.DB, productIDs ...uint) error {
+	if len(productIDs) == 0 {
+		return nil
+	}
+
+	return tx.Exec(`
+		UPDATE products SET stock_quantity = (
+			SELECT COALESCE(SUM(inventory.quantity - inventory.reserved_quantity), 0)
+			FROM inventory
+			JOIN warehouses ON warehouses.id = inventory.warehouse_id
+			WHERE inventory.product_id = products.id
+				AND inventory.deleted_at IS NULL
+				AND warehouses.deleted_at IS NULL
+				AND warehouses.is_active = TRUE
+		)
+		WHERE id IN ?`, productIDs).Error
+}
+
+// defaultWarehouse returns the warehouse that receives stock when none is given
+func defaultWarehouse(tx *gorm.DB) (*models.Warehouse, error) {
+	var warehouse models.Warehouse
+	query := tx.Where("is_active = ?", true)
+	if code := config.GetEnv("DEFAULT_WAREHOUSE_CODE", ""); code != "" {
+		query = query.Where("code = ?", code)
+	}
+	if err := query.Order("id ASC").First(&warehouse).Error; err != nil {
+		return nil, newHTTPError(http.StatusBadRequest, "No active warehouse configured")
+	}
+	return &warehouse, nil
+}
+
+// ExpireStockReservations cancels unpaid orders whose reservations have expired
+func ExpireStockReservations() {
+	var orderIDs []uint
+	if err := config.DB.Model(&models.StockReservation{}).
+		Where("status = ? AND expires_at < ?", models.ReservationStatusActive, time.Now()).
+		Distinct().
+		Pluck("order_id", &orderIDs).Error; err != nil {
+		log.Printf("Failed to find expired reservations: %v", err)
+		return
+	}
+
+	for _, orderID := range orderIDs {
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			var order models.Order
+			if err := lockOrder(tx, &order, "id = ?", orderID); err != nil {
+				return err
+			}
+
+			// Paid in the meantime; capture will confirm and commit
+			if order.Status != models.OrderStatusPending || order.PaymentStatus == models.PaymentStatusCompleted {
+				return nil
+			}
+
+			return transitionOrder(tx, &order, orderTransition{
+				To:      models.OrderStatusCancelled,
+				Comment: "Payment not received in time",
+			})
+		})
+		if err != nil {
+			log.Printf("Failed to expire reservations for order %d: %v", orderID, err)
+		}
+	}
+}
+
+// RunReservationExpiry runs ExpireStockReservations every interval; call in a goroutine
+func RunReservationExpiry(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ExpireStockReservations()
+	}
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nilabhsubramaniam/kapas/internal/config"
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+)
+
+// RegionSummary is a craft region with the number of active products from it
+type RegionSummary struct {
+	models.Region
+	ProductCount int64 `json:"product_count"`
+}
+
+// ListCountries godoc
+// @Summary List countries
+// @Description Get active countries with their dialling codes and currencies. Supports If-None-Match.
+// @Tags Locations
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Countries"
+// @Success 304 "Not modified"
+// @Router /locations/countries [get]
+func ListCountries(c *gin.Context) {
+	var countries []models.Country
+	if err := config.DB.Where("is_active = ?", true).Order("name ASC").Find(&countries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch countries"})
+		return
+	}
+
+	respondCached(c, gin.H{"countries": countries})
+}
+
+// ListStates godoc
+// @Summary List states of a country
+// @Description Get the active states of a country, by country ID or ISO code. Supports If-None-Match.
+// @Tags Locations
+// @Produce json
+// @Param country path string true "Country ID or ISO code (e.g. IN)"
+// @Success 200 {object} map[string]interface{} "States"
+// @Success 304 "Not modified"
+// @Failure 404 {object} ErrorResponse "Country not found"
+// @Router /locations/countries/{country}/states [get]
+func ListStates(c *gin.Context) {
+	query := config.DB.Where("is_active = ?", true)
+	if id, err := strconv.ParseUint(c.Param("country"), 10, 64); err == nil {
+		query = query.Where("id = ?", id)
+	} else {
+		query = query.Where("code = ?", strings.ToUpper(c.Param("country")))
+	}
+
+	var country models.Country
+	if err := query.First(&country).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Country not found"})
+		return
+	}
+
+	var states []models.State
+	if err := config.DB.Where("country_id = ? AND is_active = ?", country.ID, true).
+		Order("name ASC").
+		Find(&states).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch states"})
+		return
+	}
+
+	respondCached(c, gin.H{
+		"country": country,
+		"states":  states,
+	})
+}
+
+// ListDistricts godoc
+// @Summary List districts of a state
+// @Description Get the active districts of a state. Supports If-None-Match.
+// @Tags Locations
+// @Produce json
+// @Param id path int true "State ID"
+// @Success 200 {object} map[string]interface{} "Districts"
+// @Success 304 "Not modified"
+// @Failure 404 {object} ErrorResponse "State not found"
+// @Router /locations/states/{id}/districts [get]
+func ListDistricts(c *gin.Context) {
+	var state models.State
+	if err := config.DB.Where("id = ? AND is_active = ?", c.Param("id"), true).First(&state).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "State not found"})
+		return
+	}
+
+	var districts []models.District
+	if err := config.DB.Where("state_id = ? AND is_active = ?", state.ID, true).
+		Order("name ASC").
+		Find(&districts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch districts"})
+		return
+	}
+
+	respondCached(c, gin.H{
+		"state":     state,
+		"districts": districts,
+	})
+}
+
+// ListRegions godoc
+// @Summary List craft regions
+// @Description Get active craft regions in display order, with what they are famous for and how many active products come from each. Supports If-None-Match.
+// @Tags Locations
+// @Produce json
+// @Param state_id query int false "Only regions in this state"
+// @Success 200 {object} map[string]interface{} "Regions"
+// @Success 304 "Not modified"
+// @Router /locations/regions [get]
+func ListRegions(c *gin.Context) {
+	query := config.DB.Model(&models.Region{}).
+		Select("regions.*, COUNT(products.id) AS product_count").
+		Joins("LEFT JOIN products ON products.region_id = regions.id AND products.is_active = ? AND products.deleted_at IS NULL", true).
+		Where("regions.is_active = ?", true)
+	if stateID := c.Query("state_id"); stateID != "" {
+		query = query.Where("regions.state_id = ?", stateID)
+	}
+
+	var regions []RegionSummary
+	if err := query.Group("regions.id").
+		Order("regions.display_order ASC, regions.name ASC").
+		Find(&regions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch regions"})
+		return
+	}
+
+	respondCached(c, gin.H{"regions": regions})
+}
+
+// respondCached writes body as JSON with an ETag derived from it, or 304
+// when the client's If-None-Match already has that version. Clients may
+// reuse the response for LOCATION_CACHE_MAX_AGE before revalidating.
+func respondCached(c *gin.Context, body interface{}) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode response"})
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	maxAge := config.GetEnvDuration("LOCATION_CACHE_MAX_AGE", time.Hour)
+
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+}
+
+// etagMatches reports whether an If-None-Match header matches etag, using
+// the weak comparison RFC 9110 requires for If-None-Match
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
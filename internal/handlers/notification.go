@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/nilabhsubramaniam/kapas/internal/config"
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+	"github.com/nilabhsubramaniam/kapas/internal/notify"
+)
+
+const (
+	// notificationBatchSize is how many outbox rows one dispatch sends
+	notificationBatchSize = 50
+
+	// notificationLease keeps claimed rows from other dispatchers while sending
+	notificationLease = 5 * time.Minute
+)
+
+// enqueueNotification renders an event for each channel that can deliver it
+// and adds the messages to the outbox within tx. In-app notifications are
+// delivered by being stored.
+func enqueueNotification(tx *gorm.DB, userID uint, event notify.Event, vars map[string]interface{}, data models.JSONB) error {
+	for _, channel := range notify.Channels(event) {
+		if !notify.Enabled(channel) {
+			continue
+		}
+
+		msg, err := notify.Render(event, channel, vars)
+		if err != nil {
+			return err
+		}
+
+		notification := models.Notification{
+			UserID:  userID,
+			Type:    notify.TypeOf(event),
+			Channel: channel,
+			Event:   string(event),
+			Title:   msg.Subject,
+			Message: msg.Body,
+			Data:    data,
+			Status:  models.NotificationStatusPending,
+		}
+		if channel == models.NotificationChannelInApp {
+			now := time.Now()
+			notification.Status = models.NotificationStatusSent
+			notification.SentAt = &now
+		}
+		if err := tx.Create(&notification).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyOrder queues an order event for the order's customer; vars adds to
+// the template variables every order event gets
+func notifyOrder(tx *gorm.DB, order *models.Order, event notify.Event, vars map[string]interface{}) error {
+	var user models.User
+	if err := tx.Select("id, name").First(&user, order.UserID).Error; err != nil {
+		return err
+	}
+
+	var itemCount int64
+	if err := tx.Model(&models.OrderItem{}).
+		Where("order_id = ?", order.ID).
+		Select("COALESCE(SUM(quantity), 0)").
+		Scan(&itemCount).Error; err != nil {
+		return err
+	}
+
+	all := map[string]interface{}{
+		"Name":          user.Name,
+		"OrderNumber":   order.OrderNumber,
+		"Total":         order.TotalAmount,
+		"ItemCount":     itemCount,
+		"PaymentMethod": order.PaymentMethod,
+	}
+	for key, value := range vars {
+		all[key] = value
+	}
+
+	return enqueueNotification(tx, order.UserID, event, all, models.JSONB{
+		"order_id":     order.ID,
+		"order_number": order.OrderNumber,
+	})
+}
+
+// notifyOrderShipped queues the shipped notification with tracking details
+func notifyOrderShipped(tx *gorm.DB, order *models.Order) error {
+	vars := map[string]interface{}{"AWBNumber": "", "Courier": "", "TrackingURL": ""}
+
+	var shipment models.Shipment
+	if err := tx.Preload("Provider").Where("order_id = ?", order.ID).First(&shipment).Error; err == nil {
+		vars["AWBNumber"] = shipment.AWBNumber
+		vars["Courier"] = shipment.Provider.Name
+		vars["TrackingURL"] = shipment.TrackingURL
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	return notifyOrder(tx, order, notify.EventOrderShipped, vars)
+}
+
+// DispatchNotifications sends due outbox notifications; failures are retried
+// with exponential backoff until NOTIFY_MAX_ATTEMPTS
+func DispatchNotifications() {
+	now := time.Now()
+
+	var batch []models.Notification
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?)", models.NotificationStatusPending, now).
+			Order("id ASC").
+			Limit(notificationBatchSize).
+			Find(&batch).Error; err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, 0, len(batch))
+		for _, n := range batch {
+			ids = append(ids, n.ID)
+		}
+		return tx.Model(&models.Notification{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(notificationLease)).Error
+	})
+	if err != nil {
+		log.Printf("Failed to claim notifications: %v", err)
+		return
+	}
+	if len(batch) == 0 {
+		return
+	}
+
+	userIDs := make([]uint, 0, len(batch))
+	for _, n := range batch {
+		userIDs = append(userIDs, n.UserID)
+	}
+	var users []models.User
+	if err := config.DB.Select("id, name, email, phone").Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		log.Printf("Failed to load notification recipients: %v", err)
+		return
+	}
+	recipients := make(map[uint]notify.Recipient, len(users))
+	for _, u := range users {
+		recipients[u.ID] = notify.Recipient{Name: u.Name, Email: u.ContactEmail(), Phone: u.Phone}
+	}
+
+	for i := range batch {
+		sendNotification(&batch[i], recipients[batch[i].UserID])
+	}
+}
+
+// sendNotification delivers one outbox row and records the outcome
+func sendNotification(n *models.Notification, to notify.Recipient) {
+	err := func() error {
+		sender, err := notify.Get(n.Channel)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		return sender.Send(ctx, to, notify.Message{Subject: n.Title, Body: n.Message})
+	}()
+
+	attempts := n.Attempts + 1
+	updates := map[string]interface{}{"attempts": attempts}
+	if err == nil {
+		now := time.Now()
+		updates["status"] = models.NotificationStatusSent
+		updates["sent_at"] = now
+		updates["next_attempt_at"] = nil
+		updates["last_error"] = ""
+	} else {
+		updates["last_error"] = err.Error()
+		if attempts >= config.GetEnvInt("NOTIFY_MAX_ATTEMPTS", 8) || errors.Is(err, notify.ErrNoAddress) {
+			updates["status"] = models.NotificationStatusFailed
+			updates["next_attempt_at"] = nil
+		} else {
+			updates["next_attempt_at"] = time.Now().Add(notificationBackoff(attempts))
+		}
+		log.Printf("Failed to send %s notification %d (attempt %d): %v", n.Channel, n.ID, attempts, err)
+	}
+
+	if err := config.DB.Model(n).Updates(updates).Error; err != nil {
+		log.Printf("Failed to record notification %d: %v", n.ID, err)
+	}
+}
+
+// notificationBackoff doubles the retry delay from one minute, up to six hours
+func notificationBackoff(attempts int) time.Duration {
+	delay := time.Minute << uint(attempts-1)
+	if delay <= 0 || delay > 6*time.Hour {
+		return 6 * time.Hour
+	}
+	return delay
+}
+
+// RunNotificationDispatcher runs DispatchNotifications every interval; call in a goroutine
+func RunNotificationDispatcher(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		DispatchNotifications()
+	}
+}
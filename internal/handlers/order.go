@@ -1,42 +1,458 @@
 package handlers
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/nilabhsubramaniam/kapas/internal/config"
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+	"github.com/nilabhsubramaniam/kapas/internal/notify"
+	"github.com/nilabhsubramaniam/kapas/internal/utils"
 )
 
-// CreateOrder creates a new order from cart
+// CreateOrderRequest represents checkout request
+type CreateOrderRequest struct {
+	AddressID        uint   `json:"address_id" example:"1"`         // Defaults to the default shipping address
+	BillingAddressID uint   `json:"billing_address_id" example:"2"` // Optional
+	PaymentMethod    string `json:"payment_method" binding:"required,oneof=card upi netbanking wallet cod" example:"upi"`
+	CouponCode       string `json:"coupon_code" example:"FESTIVE10"`
+	CustomerNotes    string `json:"customer_notes" example:"Please gift wrap"`
+}
+
+// CancelOrderRequest represents order cancellation request
+type CancelOrderRequest struct {
+	Reason string `json:"reason" example:"Ordered by mistake"`
+}
+
+// CreateOrder godoc
+// @Summary Place an order
+// @Description Convert the authenticated user's cart into an order in a single transaction
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateOrderRequest true "Checkout details"
+// @Success 201 {object} map[string]interface{} "Order placed successfully"
+// @Failure 400 {object} ErrorResponse "Invalid request, empty cart or no shipping address"
+// @Failure 403 {object} ErrorResponse "Email not verified (when REQUIRE_VERIFIED_EMAIL is on)"
+// @Failure 404 {object} ErrorResponse "Address not found"
+// @Failure 409 {object} ErrorResponse "Product unavailable or insufficient stock"
+// @Router /orders [post]
 func CreateOrder(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Create order endpoint - To be implemented",
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req CreateOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if config.GetEnvBool("REQUIRE_VERIFIED_EMAIL", false) {
+		var user models.User
+		if err := config.DB.Select("id, email_verified").First(&user, userID).Error; err != nil || !user.EmailVerified {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Please verify your email address before placing an order"})
+			return
+		}
+	}
+
+	addressID := req.AddressID
+	if addressID == 0 {
+		var fallback models.Address
+		if err := config.DB.Select("id").
+			Where("user_id = ? AND address_type = ? AND is_default = ?", userID, "shipping", true).
+			First(&fallback).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Please choose a shipping address"})
+			return
+		}
+		addressID = fallback.ID
+	}
+	address, err := loadAddress(config.DB, userID, addressID)
+	if err != nil {
+		respondError(c, err, "Failed to fetch address")
+		return
+	}
+
+	var billingAddress models.JSONB
+	if req.BillingAddressID != 0 {
+		billing, err := loadAddress(config.DB, userID, req.BillingAddressID)
+		if err != nil {
+			respondError(c, err, "Failed to fetch address")
+			return
+		}
+		billingAddress = addressSnapshot(*billing)
+	}
+
+	var order models.Order
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var items []models.CartItem
+		if err := tx.Where("user_id = ?", userID).
+			Preload("Product").
+			Preload("Product.Images").
+			Order("added_at ASC").
+			Find(&items).Error; err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return newHTTPError(http.StatusBadRequest, "Cart is empty")
+		}
+
+		for _, item := range items {
+			if !item.Product.IsActive || item.Product.ID == 0 {
+				return newHTTPError(http.StatusConflict,
+					fmt.Sprintf("Product %q is no longer available", item.Product.Name))
+			}
+		}
+
+		cart := buildCart(items)
+
+		var coupon *models.Coupon
+		var couponDiscount float64
+		if req.CouponCode != "" {
+			var err error
+			coupon, couponDiscount, err = lockCoupon(tx, req.CouponCode, userID, cart.Summary.Total)
+			if err != nil {
+				return err
+			}
+		}
+
+		itemsTotal := roundAmount(cart.Summary.Total - couponDiscount)
+		shipping := shippingCharge(itemsTotal) // Free shipping is judged after the coupon
+		tax := taxAmount(itemsTotal)
+
+		order = models.Order{
+			OrderNumber:     generateOrderNumber(),
+			UserID:          userID,
+			Status:          models.OrderStatusPending,
+			PaymentStatus:   models.PaymentStatusPending,
+			PaymentMethod:   req.PaymentMethod,
+			SubtotalAmount:  cart.Summary.Subtotal,
+			DiscountAmount:  roundAmount(cart.Summary.Discount + couponDiscount),
+			TaxAmount:       tax,
+			ShippingAmount:  shipping,
+			TotalAmount:     roundAmount(itemsTotal + tax + shipping),
+			ShippingAddress: addressSnapshot(*address),
+			BillingAddress:  billingAddress,
+			CustomerNotes:   req.CustomerNotes,
+		}
+		if coupon != nil {
+			order.CouponCode = coupon.Code
+		}
+		if err := tx.Create(&order).Error; err != nil {
+			return err
+		}
+
+		if coupon != nil {
+			if err := recordCouponUsage(tx, coupon, userID, order.ID); err != nil {
+				return err
+			}
+		}
+
+		// Unpaid online orders give their stock back after the reservation TTL
+		var expiresAt *time.Time
+		if req.PaymentMethod != string(models.PaymentMethodCOD) {
+			expiry := time.Now().Add(reservationTTL())
+			expiresAt = &expiry
+		}
+
+		for _, line := range cart.Items {
+			orderItem := models.OrderItem{
+				OrderID:     order.ID,
+				ProductID:   line.ProductID,
+				ProductName: line.Product.Name,
+				Quantity:    line.Quantity,
+				UnitPrice:   line.UnitPrice,
+				TotalPrice:  line.LineTotal,
+				Metadata:    productSnapshot(line.Product),
+			}
+			if err := tx.Create(&orderItem).Error; err != nil {
+				return err
+			}
+
+			if err := reserveStock(tx, order.ID, line.ProductID, line.Quantity, expiresAt); err != nil {
+				if errors.Is(err, errInsufficientStock) {
+					return insufficientStockError(line.Product.Name)
+				}
+				return err
+			}
+		}
+
+		history := models.OrderStatusHistory{
+			OrderID:   order.ID,
+			Status:    models.OrderStatusPending,
+			Comment:   "Order placed",
+			ChangedBy: userID,
+		}
+		if err := tx.Create(&history).Error; err != nil {
+			return err
+		}
+
+		if err := notifyOrder(tx, &order, notify.EventOrderPlaced, nil); err != nil {
+			return err
+		}
+
+		return tx.Where("user_id = ?", userID).Delete(&models.CartItem{}).Error
+	})
+	if err != nil {
+		respondError(c, err, "Failed to place order")
+		return
+	}
+
+	config.DB.Preload("Items").Preload("StatusHistory").First(&order, order.ID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Order placed successfully",
+		"order":   order,
 	})
 }
 
-// ListUserOrders returns user's orders
+// ListUserOrders godoc
+// @Summary List my orders
+// @Description Get paginated order history of the authenticated user
+// @Tags Orders
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(20)
+// @Param status query string false "Filter by status (pending, confirmed, shipped, etc.)"
+// @Success 200 {object} map[string]interface{} "Paginated orders list"
+// @Router /orders [get]
 func ListUserOrders(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "List user orders endpoint - To be implemented",
-	})
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	pagination := utils.GetPaginationParams(c)
+
+	var orders []models.Order
+	var total int64
+
+	query := config.DB.Model(&models.Order{}).Where("user_id = ?", userID)
+
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	query.Count(&total)
+
+	query.Preload("Items").
+		Order("created_at DESC").
+		Limit(pagination.PerPage).
+		Offset(pagination.Offset).
+		Find(&orders)
+
+	c.JSON(http.StatusOK, utils.PaginatedResponse(orders, total, pagination.Page, pagination.PerPage))
 }
 
-// GetOrder returns order details
+// GetOrder godoc
+// @Summary Get order details
+// @Description Get an order of the authenticated user with items, payment, shipment and status history
+// @Tags Orders
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Success 200 {object} models.Order "Order details"
+// @Failure 404 {object} ErrorResponse "Order not found"
+// @Router /orders/{id} [get]
 func GetOrder(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Get order endpoint - To be implemented",
-	})
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var order models.Order
+	if err := config.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).
+		Preload("Items").
+		Preload("Payment").
+		Preload("Shipment").
+		Preload("Shipment.Provider").
+		Preload("StatusHistory", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC")
+		}).
+		First(&order).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
 }
 
-// CancelOrder cancels an order
+// CancelOrder godoc
+// @Summary Cancel order
+// @Description Cancel a pending or confirmed order and release its stock
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Param request body CancelOrderRequest false "Cancellation reason"
+// @Success 200 {object} map[string]interface{} "Order cancelled"
+// @Failure 404 {object} ErrorResponse "Order not found"
+// @Failure 409 {object} ErrorResponse "Order can no longer be cancelled"
+// @Router /orders/{id}/cancel [put]
 func CancelOrder(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Cancel order endpoint - To be implemented",
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req CancelOrderRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var order models.Order
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockOrder(tx, &order, "id = ? AND user_id = ?", c.Param("id"), userID); err != nil {
+			return newHTTPError(http.StatusNotFound, "Order not found")
+		}
+
+		// Customers may only cancel before the order is being prepared
+		if order.Status != models.OrderStatusPending && order.Status != models.OrderStatusConfirmed {
+			return newHTTPError(http.StatusConflict,
+				fmt.Sprintf("Order cannot be cancelled once %s", order.Status))
+		}
+
+		comment := "Cancelled by customer"
+		if req.Reason != "" {
+			comment += ": " + req.Reason
+		}
+
+		return transitionOrder(tx, &order, orderTransition{
+			To:      models.OrderStatusCancelled,
+			ActorID: userID,
+			Comment: comment,
+		})
 	})
+	if err != nil {
+		respondError(c, err, "Failed to cancel order")
+		return
+	}
+
+	response := gin.H{
+		"message": "Order cancelled successfully",
+		"order":   order,
+	}
+	if refund := refundCancelledOrder(c.Request.Context(), &order, userID); refund != nil {
+		response["refund"] = refund
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
-// TrackOrder returns tracking information
+// TrackOrder godoc
+// @Summary Track order
+// @Description Get shipment and tracking events for an order of the authenticated user
+// @Tags Orders
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Success 200 {object} map[string]interface{} "Tracking information"
+// @Failure 404 {object} ErrorResponse "Order not found"
+// @Router /orders/{id}/track [get]
 func TrackOrder(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var order models.Order
+	if err := config.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).
+		Preload("Shipment").
+		Preload("Shipment.Provider").
+		Preload("Shipment.TrackingEvents", func(db *gorm.DB) *gorm.DB {
+			return db.Order("event_time DESC")
+		}).
+		First(&order).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Track order endpoint - To be implemented",
+		"order_id":     order.ID,
+		"order_number": order.OrderNumber,
+		"status":       order.Status,
+		"shipment":     order.Shipment,
 	})
 }
+
+// generateOrderNumber creates a human-readable unique order number (TNT-20240101-A1B2C3)
+func generateOrderNumber() string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return fmt.Sprintf("TNT-%s-%s", time.Now().Format("20060102"), strings.ToUpper(hex.EncodeToString(suffix)))
+}
+
+// shippingCharge returns the flat shipping fee, waived when the items total
+// after all discounts reaches the free-shipping threshold
+func shippingCharge(itemsTotal float64) float64 {
+	if itemsTotal >= config.GetEnvFloat("FREE_SHIPPING_THRESHOLD", 999) {
+		return 0
+	}
+	return config.GetEnvFloat("SHIPPING_FLAT_FEE", 99)
+}
+
+// taxAmount returns tax on top of item prices (prices are GST-inclusive by default)
+func taxAmount(itemsTotal float64) float64 {
+	return roundAmount(itemsTotal * config.GetEnvFloat("ORDER_TAX_PERCENT", 0) / 100)
+}
+
+// productSnapshot captures product details at the time of purchase
+func productSnapshot(product models.Product) models.JSONB {
+	snapshot := models.JSONB{
+		"slug":                product.Slug,
+		"product_type":        product.ProductType,
+		"saree_type":          product.SareeType,
+		"fabric":              product.Fabric,
+		"base_price":          product.BasePrice,
+		"discount_percentage": product.DiscountPercentage,
+		"final_price":         product.FinalPrice,
+		"region_id":           product.RegionID,
+		"vendor_id":           product.VendorID,
+	}
+	for _, image := range product.Images {
+		if image.IsPrimary || snapshot["image_url"] == nil {
+			snapshot["image_url"] = image.ImageURL
+		}
+	}
+	return snapshot
+}
+
+// addressSnapshot copies an address into the order so later edits don't change it
+func addressSnapshot(address models.Address) models.JSONB {
+	return models.JSONB{
+		"address_id":    address.ID,
+		"address_type":  address.AddressType,
+		"full_name":     address.FullName,
+		"phone":         address.Phone,
+		"address_line1": address.AddressLine1,
+		"address_line2": address.AddressLine2,
+		"landmark":      address.Landmark,
+		"district_id":   address.DistrictID,
+		"district":      address.District.Name,
+		"state_id":      address.StateID,
+		"state":         address.State.Name,
+		"country_id":    address.CountryID,
+		"country":       address.Country.Name,
+		"pin_code":      address.PinCode,
+	}
+}
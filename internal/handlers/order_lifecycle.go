@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+	"github.com/nilabhsubramaniam/kapas/internal/notify"
+)
+
+// orderTransition describes a requested order status change
+type orderTransition struct {
+	To      models.OrderStatus
+	ActorID uint   // User who triggered the change (customer, admin or 0 for system)
+	Comment string // Stored on the status history row
+
+	// Used when moving to shipped and no shipment exists yet
+	ProviderID uint
+	AWBNumber  string
+}
+
+// lockOrder loads an order for update within tx
+func lockOrder(tx *gorm.DB, order *models.Order, query interface{}, args ...interface{}) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where(query, args...).
+		First(order).Error
+}
+
+// transitionOrder moves a locked order to a new status, appends history and
+// runs the side effects of the target status within tx
+func transitionOrder(tx *gorm.DB, order *models.Order, t orderTransition) error {
+	if !t.To.IsValid() {
+		return newHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid order status %q", t.To))
+	}
+	if !order.Status.CanTransitionTo(t.To) {
+		return newHTTPError(http.StatusConflict,
+			fmt.Sprintf("Cannot change order status from %s to %s", order.Status, t.To))
+	}
+
+	if err := tx.Model(order).Update("status", t.To).Error; err != nil {
+		return err
+	}
+
+	history := models.OrderStatusHistory{
+		OrderID:   order.ID,
+		Status:    t.To,
+		Comment:   t.Comment,
+		ChangedBy: t.ActorID,
+	}
+	if err := tx.Create(&history).Error; err != nil {
+		return err
+	}
+
+	switch t.To {
+	case models.OrderStatusConfirmed:
+		return commitOrderStock(tx, order.ID, t.ActorID)
+	case models.OrderStatusCancelled:
+		var shipments int64
+		if err := tx.Model(&models.Shipment{}).Where("order_id = ?", order.ID).Count(&shipments).Error; err != nil {
+			return err
+		}
+		if shipments > 0 {
+			return newHTTPError(http.StatusConflict, "Cancel the order's shipment before cancelling the order")
+		}
+		if err := releaseCoupon(tx, order.ID); err != nil {
+			return err
+		}
+		return releaseOrderStock(tx, order.ID, t.ActorID)
+	case models.OrderStatusShipped:
+		if err := shipOrder(tx, order, t); err != nil {
+			return err
+		}
+		return notifyOrderShipped(tx, order)
+	case models.OrderStatusDelivered:
+		if order.PaymentMethod == string(models.PaymentMethodCOD) {
+			// Cash was collected by the courier
+			if err := tx.Model(order).Update("payment_status", models.PaymentStatusCompleted).Error; err != nil {
+				return err
+			}
+		}
+		if err := deliverOrder(tx, order); err != nil {
+			return err
+		}
+		return notifyOrder(tx, order, notify.EventOrderDelivered, nil)
+	}
+
+	return nil
+}
+
+// shipOrder creates the order's shipment if needed and marks it in transit
+func shipOrder(tx *gorm.DB, order *models.Order, t orderTransition) error {
+	now := time.Now()
+
+	var shipment models.Shipment
+	err := tx.Where("order_id = ?", order.ID).First(&shipment).Error
+	if err == nil {
+		updates := map[string]interface{}{
+			"status":      models.ShipmentStatusInTransit,
+			"pickup_date": now,
+		}
+		if t.AWBNumber != "" {
+			updates["awb_number"] = t.AWBNumber
+		}
+		return tx.Model(&shipment).Updates(updates).Error
+	}
+	if err != gorm.ErrRecordNotFound {
+		return err
+	}
+
+	if t.AWBNumber == "" {
+		return newHTTPError(http.StatusBadRequest, "Tracking number is required to ship an order")
+	}
+
+	var provider models.LogisticsProvider
+	query := tx.Where("is_active = ?", true)
+	if t.ProviderID != 0 {
+		query = query.Where("id = ?", t.ProviderID)
+	}
+	if err := query.Order("id ASC").First(&provider).Error; err != nil {
+		return newHTTPError(http.StatusBadRequest, "Active logistics provider not found")
+	}
+
+	shipment = models.Shipment{
+		OrderID:    order.ID,
+		ProviderID: provider.ID,
+		AWBNumber:  t.AWBNumber,
+		Status:     models.ShipmentStatusInTransit,
+		PickupDate: &now,
+	}
+	return tx.Create(&shipment).Error
+}
+
+// deliverOrder marks the order's shipment, if any, as delivered
+func deliverOrder(tx *gorm.DB, order *models.Order) error {
+	return tx.Model(&models.Shipment{}).
+		Where("order_id = ?", order.ID).
+		Updates(map[string]interface{}{
+			"status":          models.ShipmentStatusDelivered,
+			"actual_delivery": time.Now(),
+		}).Error
+}
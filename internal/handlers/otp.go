@@ -0,0 +1,355 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/nilabhsubramaniam/kapas/internal/config"
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+	"github.com/nilabhsubramaniam/kapas/internal/notify"
+	"github.com/nilabhsubramaniam/kapas/internal/utils"
+)
+
+const (
+	// otpLength is the number of digits in a login code
+	otpLength = 6
+
+	// otpResendCooldown is the least time between two codes to one phone
+	otpResendCooldown = 30 * time.Second
+
+	// otpLimitWindow is the window the OTP_*_LIMIT settings count over
+	otpLimitWindow = time.Hour
+)
+
+// SendOTPRequest represents a login code request
+type SendOTPRequest struct {
+	Phone       string `json:"phone" binding:"required" example:"98765 43210"`
+	CountryCode string `json:"country_code" example:"IN"` // ISO 3166-1 alpha-2; defaults to IN
+}
+
+// VerifyOTPRequest represents a login code check. Name (and optionally
+// email) are needed when the phone has no account yet.
+type VerifyOTPRequest struct {
+	Phone       string `json:"phone" binding:"required" example:"98765 43210"`
+	CountryCode string `json:"country_code" example:"IN"`
+	Code        string `json:"code" binding:"required,len=6,numeric" example:"482913"`
+	Name        string `json:"name" example:"Priya Sharma"`
+	Email       string `json:"email" binding:"omitempty,email" example:"priya@example.com"`
+}
+
+// SendOTP godoc
+// @Summary Send login code
+// @Description Text a one-time login code to a mobile number. Numbers without a + are read as national numbers of country_code.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body SendOTPRequest true "Mobile number"
+// @Success 200 {object} map[string]interface{} "Code sent"
+// @Failure 400 {object} ErrorResponse "Invalid phone number"
+// @Failure 429 {object} ErrorResponse "Too many codes requested"
+// @Router /auth/otp/send [post]
+func SendOTP(c *gin.Context) {
+	var req SendOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	phone, err := normalizeOTPPhone(req.Phone, req.CountryCode)
+	if err != nil {
+		respondError(c, err, "Invalid phone number")
+		return
+	}
+	if err := checkOTPSendLimits(phone, c.ClientIP()); err != nil {
+		respondError(c, err, "Failed to send code")
+		return
+	}
+
+	code, err := utils.GenerateOTP(otpLength)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send code"})
+		return
+	}
+	ttl := config.GetEnvDuration("OTP_TTL", 5*time.Minute)
+
+	otp := models.PhoneOTP{
+		Phone:     phone,
+		CodeHash:  utils.HashOTP(phone, code),
+		ExpiresAt: time.Now().Add(ttl),
+		IPAddress: c.ClientIP(),
+	}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Only the newest code works
+		if err := tx.Model(&models.PhoneOTP{}).
+			Where("phone = ? AND consumed_at IS NULL", phone).
+			Update("consumed_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(&otp).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send code"})
+		return
+	}
+
+	msg, err := notify.Render(notify.EventLoginOTP, models.NotificationChannelSMS, map[string]interface{}{
+		"Code":      code,
+		"ExpiresIn": humanizeDuration(ttl),
+	})
+	if err == nil {
+		var sender notify.Sender
+		if sender, err = notify.Get(models.NotificationChannelSMS); err == nil {
+			err = sender.Send(c.Request.Context(), notify.Recipient{Phone: phone}, msg)
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to send login code to %s: %v", phone, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send code"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Code sent",
+		"phone":      phone,
+		"expires_in": int(ttl.Seconds()),
+	})
+}
+
+// VerifyOTP godoc
+// @Summary Log in with code
+// @Description Check a login code and log in. If no account has verified the number, one is created; send name (and optionally email) for that. Returns the same tokens as email login.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body VerifyOTPRequest true "Mobile number and code"
+// @Success 200 {object} LoginResponse "Login successful"
+// @Failure 400 {object} ErrorResponse "Invalid phone number, expired code or name missing for a new account"
+// @Failure 401 {object} ErrorResponse "Incorrect code"
+// @Failure 403 {object} ErrorResponse "Account is inactive"
+// @Failure 409 {object} ErrorResponse "Email already registered"
+// @Failure 429 {object} ErrorResponse "Too many wrong codes"
+// @Router /auth/otp/verify [post]
+func VerifyOTP(c *gin.Context) {
+	var req VerifyOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	phone, err := normalizeOTPPhone(req.Phone, req.CountryCode)
+	if err != nil {
+		respondError(c, err, "Invalid phone number")
+		return
+	}
+	if err := checkOTPFailureLimits(phone, c.ClientIP()); err != nil {
+		respondError(c, err, "Failed to verify code")
+		return
+	}
+
+	// Wrong codes must be counted even though the request fails, so
+	// rejections are returned through this rather than the transaction
+	var rejected error
+	var user models.User
+	var created bool
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var otp models.PhoneOTP
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("phone = ? AND consumed_at IS NULL AND expires_at > ?", phone, time.Now()).
+			Order("id DESC").
+			First(&otp).Error; err != nil {
+			rejected = newHTTPError(http.StatusBadRequest, "Code expired; request a new one")
+			return nil
+		}
+		if otp.Attempts >= config.GetEnvInt("OTP_MAX_ATTEMPTS", 5) {
+			rejected = newHTTPError(http.StatusBadRequest, "Too many wrong codes; request a new one")
+			return nil
+		}
+
+		if subtle.ConstantTimeCompare([]byte(otp.CodeHash), []byte(utils.HashOTP(phone, req.Code))) != 1 {
+			rejected = newHTTPError(http.StatusUnauthorized, "Incorrect code")
+			if err := tx.Model(&otp).Update("attempts", gorm.Expr("attempts + 1")).Error; err != nil {
+				return err
+			}
+			return tx.Create(&models.OTPFailure{Phone: phone, IPAddress: c.ClientIP()}).Error
+		}
+
+		var err error
+		user, created, err = findOrCreateOTPUser(tx, phone, &req)
+		if err != nil {
+			// Leave the code usable so the client can retry with a name
+			var he *httpError
+			if errors.As(err, &he) {
+				rejected = err
+				return nil
+			}
+			return err
+		}
+
+		return tx.Model(&otp).Update("consumed_at", time.Now()).Error
+	})
+	if err == nil {
+		err = rejected
+	}
+	if err != nil {
+		respondError(c, err, "Failed to verify code")
+		return
+	}
+
+	if !user.IsActive {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is inactive"})
+		return
+	}
+
+	now := time.Now()
+	config.DB.Model(&user).Update("last_login", now)
+
+	tokens, err := startSession(config.DB, c, &user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	status, message := http.StatusOK, "Login successful"
+	if created {
+		status, message = http.StatusCreated, "User registered successfully"
+		if user.ContactEmail() != "" {
+			go func(user models.User) {
+				if err := sendAccountToken(context.Background(), &user, models.UserTokenEmailVerification); err != nil {
+					log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+				}
+			}(user)
+		}
+	}
+	c.JSON(status, gin.H{
+		"message": message,
+		"user": gin.H{
+			"id":    user.ID,
+			"email": user.ContactEmail(),
+			"name":  user.Name,
+			"phone": user.Phone,
+			"role":  user.Role,
+		},
+		"token":         tokens.Token,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	})
+}
+
+// findOrCreateOTPUser returns the account that verified phone, creating one
+// from the request if there is none. Numbers saved without a code, e.g. at
+// email registration, are never matched: anyone could have typed them.
+func findOrCreateOTPUser(tx *gorm.DB, phone string, req *VerifyOTPRequest) (models.User, bool, error) {
+	var user models.User
+	err := tx.Where("phone = ? AND phone_verified = ?", phone, true).First(&user).Error
+	if err == nil {
+		return user, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.User{}, false, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return models.User{}, false, newHTTPError(http.StatusBadRequest, "Name is required to create an account")
+	}
+
+	email := models.PlaceholderEmail(phone)
+	if req.Email != "" {
+		var existing int64
+		if err := tx.Model(&models.User{}).Where("email = ?", req.Email).Count(&existing).Error; err != nil {
+			return models.User{}, false, err
+		}
+		if existing > 0 {
+			return models.User{}, false, newHTTPError(http.StatusConflict,
+				"Email already registered; log in with your email instead")
+		}
+		email = req.Email
+	}
+
+	// Phone accounts have no password until one is set through a reset link
+	secret, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return models.User{}, false, err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return models.User{}, false, err
+	}
+
+	user = models.User{
+		Email:         email,
+		PasswordHash:  string(hashedPassword),
+		Name:          name,
+		Phone:         phone,
+		PhoneVerified: true,
+		Role:          models.RoleCustomer,
+		IsActive:      true,
+	}
+	if err := tx.Create(&user).Error; err != nil {
+		return models.User{}, false, err
+	}
+	return user, true, nil
+}
+
+// normalizeOTPPhone puts phone in E.164 form using the country's dialling code
+func normalizeOTPPhone(phone, countryCode string) (string, error) {
+	if countryCode == "" {
+		countryCode = "IN"
+	}
+
+	var country models.Country
+	if err := config.DB.Select("id, phone_code").
+		Where("code = ? AND is_active = ?", strings.ToUpper(countryCode), true).
+		First(&country).Error; err != nil || country.PhoneCode == "" {
+		return "", newHTTPError(http.StatusBadRequest, "Unsupported country")
+	}
+
+	normalized, err := utils.NormalizePhone(phone, country.PhoneCode)
+	if err != nil {
+		return "", newHTTPError(http.StatusBadRequest, "Invalid phone number")
+	}
+	return normalized, nil
+}
+
+// checkOTPSendLimits rejects code requests that come too fast for a phone or an IP
+func checkOTPSendLimits(phone, ip string) error {
+	now := time.Now()
+
+	var last models.PhoneOTP
+	if err := config.DB.Select("created_at").Where("phone = ?", phone).
+		Order("id DESC").First(&last).Error; err == nil && now.Sub(last.CreatedAt) < otpResendCooldown {
+		return newHTTPError(http.StatusTooManyRequests, "Please wait before requesting another code")
+	}
+
+	since := now.Add(-otpLimitWindow)
+	var byPhone, byIP int64
+	config.DB.Model(&models.PhoneOTP{}).Where("phone = ? AND created_at > ?", phone, since).Count(&byPhone)
+	config.DB.Model(&models.PhoneOTP{}).Where("ip_address = ? AND created_at > ?", ip, since).Count(&byIP)
+	if byPhone >= int64(config.GetEnvInt("OTP_PHONE_LIMIT", 5)) || byIP >= int64(config.GetEnvInt("OTP_IP_LIMIT", 20)) {
+		return newHTTPError(http.StatusTooManyRequests, "Too many codes requested; try again later")
+	}
+	return nil
+}
+
+// checkOTPFailureLimits rejects verification once a phone or an IP has
+// entered too many wrong codes recently
+func checkOTPFailureLimits(phone, ip string) error {
+	since := time.Now().Add(-otpLimitWindow)
+	var byPhone, byIP int64
+	config.DB.Model(&models.OTPFailure{}).Where("phone = ? AND created_at > ?", phone, since).Count(&byPhone)
+	config.DB.Model(&models.OTPFailure{}).Where("ip_address = ? AND created_at > ?", ip, since).Count(&byIP)
+	if byPhone >= int64(config.GetEnvInt("OTP_FAILURE_LIMIT", 10)) || byIP >= int64(config.GetEnvInt("OTP_IP_LIMIT", 20)) {
+		return newHTTPError(http.StatusTooManyRequests, "Too many wrong codes; try again later")
+	}
+	return nil
+}
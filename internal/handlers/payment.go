@@ -0,0 +1,581 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/nilabhsubramaniam/kapas/internal/config"
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+	"github.com/nilabhsubramaniam/kapas/internal/payments"
+	"github.com/nilabhsubramaniam/kapas/internal/utils"
+)
+
+// VerifyPaymentRequest represents the payment result returned to the client checkout
+type VerifyPaymentRequest struct {
+	ProviderOrderID   string `json:"provider_order_id" binding:"required" example:"order_NXa1b2c3d4e5f6"`
+	ProviderPaymentID string `json:"provider_payment_id" binding:"required" example:"pay_NXa1b2c3d4e5f6"`
+	Signature         string `json:"signature" binding:"required" example:"9a8b7c6d..."`
+	PaymentMethod     string `json:"payment_method" example:"upi"`
+}
+
+// CreatePayment godoc
+// @Summary Start online payment
+// @Description Create a payment order with the gateway for an unpaid order of the authenticated user
+// @Tags Payments
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Success 200 {object} map[string]interface{} "Gateway order details for the client checkout"
+// @Failure 400 {object} ErrorResponse "Order does not need online payment"
+// @Failure 404 {object} ErrorResponse "Order not found"
+// @Failure 502 {object} ErrorResponse "Payment gateway error"
+// @Router /orders/{id}/payment [post]
+func CreatePayment(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var order models.Order
+	if err := config.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).
+		Preload("Payment").
+		First(&order).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
+	if order.PaymentMethod == string(models.PaymentMethodCOD) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cash on delivery orders are paid on delivery"})
+		return
+	}
+	if order.Status != models.OrderStatusPending || order.PaymentStatus == models.PaymentStatusCompleted {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Order is not awaiting payment"})
+		return
+	}
+
+	provider, err := payments.Default()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Payment provider not configured"})
+		return
+	}
+
+	// Reuse an open gateway order so retries don't create duplicates
+	payment := order.Payment
+	if payment == nil || payment.Status != models.PaymentStatusPending ||
+		payment.PaymentProvider != provider.Name() || payment.ProviderOrderID == "" {
+		providerOrder, err := provider.CreateOrder(c.Request.Context(), payments.OrderRequest{
+			Amount:   payments.ToPaise(order.TotalAmount),
+			Currency: "INR",
+			Receipt:  order.OrderNumber,
+			Notes:    map[string]string{"order_number": order.OrderNumber},
+		})
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to create payment with gateway"})
+			return
+		}
+
+		if payment == nil {
+			payment = &models.Payment{OrderID: order.ID}
+		}
+		payment.PaymentProvider = provider.Name()
+		payment.ProviderOrderID = providerOrder.ID
+		payment.PaymentMethod = models.PaymentMethod(order.PaymentMethod)
+		payment.Amount = order.TotalAmount
+		payment.Currency = "INR"
+		payment.Status = models.PaymentStatusPending
+		payment.ErrorCode = ""
+		payment.ErrorDescription = ""
+
+		// Omit empty provider_payment_id so its unique index sees NULL
+		if err := config.DB.Omit("ProviderPaymentID").Save(payment).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record payment"})
+			return
+		}
+	}
+
+	response := gin.H{
+		"provider":          payment.PaymentProvider,
+		"key_id":            provider.KeyID(),
+		"provider_order_id": payment.ProviderOrderID,
+		"amount":            payments.ToPaise(payment.Amount),
+		"currency":          payment.Currency,
+		"order_number":      order.OrderNumber,
+	}
+
+	// Hand local clients a ready-made payment result to post to /verify
+	if fake, ok := provider.(*payments.Fake); ok {
+		paymentID := fake.NewPaymentID()
+		response["fake_payment"] = gin.H{
+			"provider_payment_id": paymentID,
+			"signature":           fake.SignPayment(payment.ProviderOrderID, paymentID),
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// VerifyPayment godoc
+// @Summary Verify online payment
+// @Description Verify the gateway signature returned to the client and mark the order paid
+// @Tags Payments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Param request body VerifyPaymentRequest true "Gateway payment result"
+// @Success 200 {object} map[string]interface{} "Payment verified"
+// @Failure 400 {object} ErrorResponse "Invalid signature"
+// @Failure 404 {object} ErrorResponse "Payment not found"
+// @Router /orders/{id}/payment/verify [post]
+func VerifyPayment(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req VerifyPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var payment models.Payment
+	if err := config.DB.Joins("JOIN orders ON orders.id = payments.order_id").
+		Where("payments.order_id = ? AND payments.provider_order_id = ? AND orders.user_id = ?",
+			c.Param("id"), req.ProviderOrderID, userID).
+		First(&payment).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		return
+	}
+
+	provider, err := payments.Get(payment.PaymentProvider)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Payment provider not configured"})
+		return
+	}
+
+	if err := provider.VerifyPayment(req.ProviderOrderID, req.ProviderPaymentID, req.Signature); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment signature"})
+		return
+	}
+
+	var cancelled *models.Order
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		cancelled, err = capturePayment(tx, payment.PaymentProvider, req.ProviderOrderID, paymentCapture{
+			ProviderPaymentID: req.ProviderPaymentID,
+			Signature:         req.Signature,
+			Method:            req.PaymentMethod,
+			ActorID:           userID,
+		})
+		return err
+	})
+	if err != nil {
+		respondError(c, err, "Failed to record payment")
+		return
+	}
+
+	if cancelled != nil {
+		refundCancelledOrder(c.Request.Context(), cancelled, 0)
+		c.JSON(http.StatusConflict, gin.H{"error": "This order was cancelled before the payment arrived; the payment will be refunded"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Payment verified successfully"})
+}
+
+// PaymentWebhook godoc
+// @Summary Payment gateway webhook
+// @Description Receive signed payment events from the gateway; every event is stored and duplicates (by event ID) are ignored, as are events for payments this app did not create
+// @Tags Payments
+// @Accept json
+// @Produce json
+// @Param provider path string true "Provider (razorpay, fake)"
+// @Success 200 {object} MessageResponse "Event processed"
+// @Failure 400 {object} ErrorResponse "Invalid signature or payload"
+// @Router /payments/webhook/{provider} [post]
+func PaymentWebhook(c *gin.Context) {
+	provider, err := payments.Get(models.PaymentProvider(c.Param("provider")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown payment provider"})
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read body"})
+		return
+	}
+
+	event, err := provider.ParseWebhook(c.Request.Header, body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook"})
+		return
+	}
+
+	duplicate := false
+	var cancelled *models.Order
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		record := models.PaymentWebhookEvent{
+			Provider:          provider.Name(),
+			EventID:           event.ID,
+			EventType:         event.Type,
+			ProviderOrderID:   event.ProviderOrderID,
+			ProviderPaymentID: event.ProviderPaymentID,
+			Status:            models.WebhookEventReceived,
+			Payload:           models.JSONB(event.Payload),
+		}
+
+		// Insert-then-lock serialises concurrent deliveries of the same event
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&record).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("provider = ? AND event_id = ?", provider.Name(), event.ID).
+			First(&record).Error; err != nil {
+			return err
+		}
+
+		if record.Status == models.WebhookEventProcessed || record.Status == models.WebhookEventIgnored {
+			duplicate = true
+			return nil
+		}
+
+		handled, refundOrder, err := applyPaymentEvent(tx, provider.Name(), event)
+		if err != nil {
+			return err
+		}
+		cancelled = refundOrder
+
+		status := models.WebhookEventProcessed
+		if !handled {
+			status = models.WebhookEventIgnored
+		}
+		now := time.Now()
+		return tx.Model(&record).Updates(map[string]interface{}{
+			"status":       status,
+			"error":        "",
+			"attempts":     gorm.Expr("attempts + 1"),
+			"processed_at": now,
+		}).Error
+	})
+	if err != nil {
+		recordFailedWebhook(provider.Name(), event, err)
+		respondError(c, err, "Failed to process webhook")
+		return
+	}
+
+	if duplicate {
+		c.JSON(http.StatusOK, gin.H{"message": "Duplicate event ignored"})
+		return
+	}
+	if cancelled != nil {
+		refundCancelledOrder(c.Request.Context(), cancelled, 0)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Event processed"})
+}
+
+// ListPaymentWebhookEvents godoc
+// @Summary List payment webhook events
+// @Description Get stored gateway webhook events for reconciliation (Admin only)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(20)
+// @Param status query string false "Filter by status (received, processed, ignored, failed)"
+// @Param event_type query string false "Filter by event type (payment.captured, etc.)"
+// @Param provider_order_id query string false "Filter by gateway order ID"
+// @Success 200 {object} map[string]interface{} "Paginated webhook events"
+// @Router /admin/payments/webhook-events [get]
+func ListPaymentWebhookEvents(c *gin.Context) {
+	pagination := utils.GetPaginationParams(c)
+
+	var events []models.PaymentWebhookEvent
+	var total int64
+
+	query := config.DB.Model(&models.PaymentWebhookEvent{})
+
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if eventType := c.Query("event_type"); eventType != "" {
+		query = query.Where("event_type = ?", eventType)
+	}
+	if providerOrderID := c.Query("provider_order_id"); providerOrderID != "" {
+		query = query.Where("provider_order_id = ?", providerOrderID)
+	}
+
+	query.Count(&total)
+
+	query.Order("created_at DESC").
+		Limit(pagination.PerPage).
+		Offset(pagination.Offset).
+		Find(&events)
+
+	c.JSON(http.StatusOK, utils.PaginatedResponse(events, total, pagination.Page, pagination.PerPage))
+}
+
+// recordFailedWebhook stores an event whose processing failed so a redelivery is retried
+func recordFailedWebhook(provider models.PaymentProvider, event *payments.WebhookEvent, cause error) {
+	record := models.PaymentWebhookEvent{
+		Provider:          provider,
+		EventID:           event.ID,
+		EventType:         event.Type,
+		ProviderOrderID:   event.ProviderOrderID,
+		ProviderPaymentID: event.ProviderPaymentID,
+		Status:            models.WebhookEventFailed,
+		Error:             cause.Error(),
+		Attempts:          1,
+		Payload:           models.JSONB(event.Payload),
+	}
+
+	// Never downgrade an event another delivery already processed
+	if err := config.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "provider"}, {Name: "event_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"status":     models.WebhookEventFailed,
+			"error":      cause.Error(),
+			"attempts":   gorm.Expr("payment_webhook_events.attempts + 1"),
+			"updated_at": time.Now(),
+		}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Neq{Column: "payment_webhook_events.status", Value: models.WebhookEventProcessed},
+		}},
+	}).Create(&record).Error; err != nil {
+		log.Printf("Failed to record webhook event %s/%s: %v", provider, event.ID, err)
+	}
+}
+
+// applyPaymentEvent updates payment and order state for an event from
+// provider; it only touches payments made through that provider. It
+// reports false for event types we don't act on and for payments we don't
+// know, such as another integration's on the same gateway account, so they
+// are ignored rather than retried. It returns the order to refund once tx
+// commits when a payment arrives for a cancelled order.
+func applyPaymentEvent(tx *gorm.DB, provider models.PaymentProvider, event *payments.WebhookEvent) (bool, *models.Order, error) {
+	var cancelled *models.Order
+	var err error
+	switch event.Type {
+	case payments.EventPaymentCaptured:
+		cancelled, err = capturePayment(tx, provider, event.ProviderOrderID, paymentCapture{
+			ProviderPaymentID: event.ProviderPaymentID,
+			Method:            event.Method,
+		})
+	case payments.EventPaymentFailed:
+		err = failPayment(tx, provider, event.ProviderOrderID, event.ErrorCode, event.ErrorDescription)
+	case payments.EventRefundProcessed:
+		err = recordGatewayRefund(tx, provider, event)
+	case payments.EventRefundFailed:
+		err = failGatewayRefund(tx, provider, event)
+	default:
+		return false, nil, nil
+	}
+
+	if errors.Is(err, errPaymentNotFound) {
+		return false, nil, nil
+	}
+	return true, cancelled, err
+}
+
+// paymentCapture holds the details of a successful payment
+type paymentCapture struct {
+	ProviderPaymentID string
+	Signature         string
+	Method            string
+	ActorID           uint // 0 when reported by the gateway
+}
+
+// errPaymentNotFound is returned when no payment of ours has a gateway ID
+var errPaymentNotFound = newHTTPError(http.StatusNotFound, "Payment not found")
+
+// lockPayment loads a provider's payment by gateway order ID for update
+func lockPayment(tx *gorm.DB, payment *models.Payment, provider models.PaymentProvider, providerOrderID string) error {
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("provider_order_id = ? AND payment_provider = ?", providerOrderID, provider).
+		First(payment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errPaymentNotFound
+	}
+	return err
+}
+
+// capturePayment marks a payment completed, the order paid, and confirms a
+// pending order. The order may have been cancelled while the customer was
+// paying (e.g. its reservation expired); the payment is then still recorded
+// and the order returned so the caller refunds it after tx commits.
+func capturePayment(tx *gorm.DB, provider models.PaymentProvider, providerOrderID string, capture paymentCapture) (*models.Order, error) {
+	var payment models.Payment
+	if err := lockPayment(tx, &payment, provider, providerOrderID); err != nil {
+		return nil, err
+	}
+
+	// Already captured via the other channel (client verify vs webhook)
+	if payment.Status == models.PaymentStatusCompleted || payment.Status == models.PaymentStatusRefunded ||
+		payment.Status == models.PaymentStatusPartiallyRefunded {
+		return nil, nil
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":              models.PaymentStatusCompleted,
+		"provider_payment_id": capture.ProviderPaymentID,
+		"paid_at":             now,
+		"error_code":          "",
+		"error_description":   "",
+	}
+	if capture.Signature != "" {
+		updates["payment_signature"] = capture.Signature
+	}
+	if capture.Method != "" {
+		updates["payment_method"] = capture.Method
+	}
+	if err := tx.Model(&payment).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	var order models.Order
+	if err := lockOrder(tx, &order, "id = ?", payment.OrderID); err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&order).Update("payment_status", models.PaymentStatusCompleted).Error; err != nil {
+		return nil, err
+	}
+	order.PaymentStatus = models.PaymentStatusCompleted
+
+	switch order.Status {
+	case models.OrderStatusPending:
+		return nil, transitionOrder(tx, &order, orderTransition{
+			To:      models.OrderStatusConfirmed,
+			ActorID: capture.ActorID,
+			Comment: "Payment received",
+		})
+	case models.OrderStatusCancelled:
+		if err := tx.Create(&models.OrderStatusHistory{
+			OrderID:   order.ID,
+			Status:    order.Status,
+			Comment:   "Payment received after cancellation; refunding",
+			ChangedBy: capture.ActorID,
+		}).Error; err != nil {
+			return nil, err
+		}
+		return &order, nil
+	}
+	return nil, nil
+}
+
+// failPayment records a failed payment attempt on a payment that is not yet captured
+func failPayment(tx *gorm.DB, provider models.PaymentProvider, providerOrderID, code, description string) error {
+	var payment models.Payment
+	if err := lockPayment(tx, &payment, provider, providerOrderID); err != nil {
+		return err
+	}
+
+	if payment.Status != models.PaymentStatusPending && payment.Status != models.PaymentStatusFailed {
+		return nil
+	}
+
+	if err := tx.Model(&payment).Updates(map[string]interface{}{
+		"status":            models.PaymentStatusFailed,
+		"error_code":        code,
+		"error_description": description,
+	}).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.Order{}).
+		Where("id = ? AND payment_status = ?", payment.OrderID, models.PaymentStatusPending).
+		Update("payment_status", models.PaymentStatusFailed).Error
+}
+
+// recordGatewayRefund completes the refund a processed-refund event refers
+// to; refunds started from the gateway dashboard are recorded as new refunds
+func recordGatewayRefund(tx *gorm.DB, provider models.PaymentProvider, event *payments.WebhookEvent) error {
+	refund, err := lockGatewayRefund(tx, provider, event)
+	if err != nil {
+		return err
+	}
+
+	if refund == nil {
+		var payment models.Payment
+		err := tx.Where("provider_payment_id = ? AND payment_provider = ?", event.ProviderPaymentID, provider).
+			First(&payment).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errPaymentNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		refund = &models.Refund{
+			RefundNumber:     generateRefundNumber(),
+			OrderID:          payment.OrderID,
+			PaymentID:        &payment.ID,
+			Method:           models.RefundMethodGateway,
+			Status:           models.RefundStatusPending,
+			Amount:           payments.FromPaise(event.RefundAmount),
+			Currency:         payment.Currency,
+			ProviderRefundID: event.RefundID,
+			Reason:           "Refunded at the payment gateway",
+		}
+		if err := tx.Create(refund).Error; err != nil {
+			return err
+		}
+	}
+
+	if refund.Status == models.RefundStatusProcessed {
+		return nil
+	}
+	return completeRefund(tx, refund, map[string]interface{}{"provider_refund_id": event.RefundID})
+}
+
+// failGatewayRefund marks the refund a failed-refund event refers to as failed
+func failGatewayRefund(tx *gorm.DB, provider models.PaymentProvider, event *payments.WebhookEvent) error {
+	refund, err := lockGatewayRefund(tx, provider, event)
+	if err != nil || refund == nil || refund.Status != models.RefundStatusPending {
+		return err
+	}
+
+	return tx.Model(refund).Updates(map[string]interface{}{
+		"status":             models.RefundStatusFailed,
+		"provider_refund_id": event.RefundID,
+		"failure_reason":     "Refund failed at the payment gateway",
+	}).Error
+}
+
+// lockGatewayRefund finds the refund for a gateway event by refund ID or by
+// our refund number sent as the receipt, among refunds of provider's
+// payments; it returns nil when there is none
+func lockGatewayRefund(tx *gorm.DB, provider models.PaymentProvider, event *payments.WebhookEvent) (*models.Refund, error) {
+	if event.RefundID == "" {
+		return nil, newHTTPError(http.StatusBadRequest, "Refund event has no refund ID")
+	}
+
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("payment_id IN (?)", tx.Model(&models.Payment{}).Select("id").Where("payment_provider = ?", provider))
+	if event.RefundReceipt != "" {
+		query = query.Where("(provider_refund_id = ? OR refund_number = ?)", event.RefundID, event.RefundReceipt)
+	} else {
+		query = query.Where("provider_refund_id = ?", event.RefundID)
+	}
+
+	var refund models.Refund
+	err := query.First(&refund).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
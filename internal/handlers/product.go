@@ -6,6 +6,8 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
 	"github.com/nilabhsubramaniam/kapas/internal/config"
 	"github.com/nilabhsubramaniam/kapas/internal/models"
 	"github.com/nilabhsubramaniam/kapas/internal/utils"
@@ -16,7 +18,10 @@ type CreateProductRequest struct {
 	Name               string              `json:"name" binding:"required"`
 	Description        string              `json:"description"`
 	ProductType        string              `json:"product_type" binding:"required"`
-	StateOrigin        string              `json:"state_origin"`
+	RegionID           *uint               `json:"region_id"`
+	RegionSlug         string              `json:"region_slug"` // Alternative to region_id
+	VendorID           *uint               `json:"vendor_id"`
+	StateOrigin        string              `json:"state_origin"` // Deprecated: used to pick a region when none is given
 	SareeType          string              `json:"saree_type"`
 	BasePrice          float64             `json:"base_price" binding:"required,gt=0"`
 	DiscountPercentage float64             `json:"discount_percentage"`
@@ -42,7 +47,9 @@ type ProductImageInput struct {
 // @Produce json
 // @Param page query int false "Page number" default(1)
 // @Param per_page query int false "Items per page" default(20)
-// @Param state query string false "State origin (UP, KL, TN, KA, WB)"
+// @Param region query string false "Region slug (lucknow, kerala, kanchipuram, etc.)"
+// @Param vendor_id query int false "Vendor ID"
+// @Param state query string false "State code of the product's region (UP, KL, TN, KA, WB)"
 // @Param saree_type query string false "Saree type (Chikankari, Kasavu, Kanchipuram, etc.)"
 // @Param fabric query string false "Fabric type (Cotton, Silk, Georgette, etc.)"
 // @Param product_type query string false "Product type (SAREE, CHIKANKARI_KURTI, etc.)"
@@ -62,9 +69,7 @@ func ListProducts(c *gin.Context) {
 	query := config.DB.Model(&models.Product{}).Where("is_active = ?", true)
 
 	// Filters
-	if state := c.Query("state"); state != "" {
-		query = query.Where("state_origin = ?", state)
-	}
+	query = query.Scopes(productOriginFilters(c.Query("region"), c.Query("vendor_id"), c.Query("state")))
 	if sareeType := c.Query("saree_type"); sareeType != "" {
 		query = query.Where("saree_type = ?", sareeType)
 	}
@@ -103,6 +108,7 @@ func ListProducts(c *gin.Context) {
 
 	// Fetch products with pagination
 	query.Preload("Images").
+		Preload("Region").
 		Limit(pagination.PerPage).
 		Offset(pagination.Offset).
 		Find(&products)
@@ -118,6 +124,7 @@ func GetProduct(c *gin.Context) {
 	if err := config.DB.Where("slug = ? AND is_active = ?", slug, true).
 		Preload("Images").
 		Preload("Reviews").
+		Preload("Region").
 		First(&product).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
 		return
@@ -128,7 +135,7 @@ func GetProduct(c *gin.Context) {
 
 // GetProductsByState godoc
 // @Summary Get products by state
-// @Description Get paginated list of products filtered by state origin
+// @Description Get paginated list of products from a state's craft regions
 // @Tags Products
 // @Produce json
 // @Param state path string true "State code (UP, KL, TN, KA, WB)"
@@ -143,12 +150,14 @@ func GetProductsByState(c *gin.Context) {
 	var products []models.Product
 	var total int64
 
-	config.DB.Model(&models.Product{}).
-		Where("state_origin = ? AND is_active = ?", state, true).
-		Count(&total)
+	query := config.DB.Model(&models.Product{}).
+		Where("is_active = ?", true).
+		Scopes(productOriginFilters("", "", state))
 
-	config.DB.Where("state_origin = ? AND is_active = ?", state, true).
-		Preload("Images").
+	query.Count(&total)
+
+	query.Preload("Images").
+		Preload("Region").
 		Limit(pagination.PerPage).
 		Offset(pagination.Offset).
 		Find(&products)
@@ -175,6 +184,12 @@ func CreateProduct(c *gin.Context) {
 		slug = slug + "-" + strconv.FormatInt(int64(existingProduct.ID), 10)
 	}
 
+	origin, err := resolveProductOrigin(config.DB, &req)
+	if err != nil {
+		respondError(c, err, "Failed to create product")
+		return
+	}
+
 	// Calculate final price
 	finalPrice := req.BasePrice
 	if req.DiscountPercentage > 0 {
@@ -187,7 +202,9 @@ func CreateProduct(c *gin.Context) {
 		Slug:               slug,
 		Description:        req.Description,
 		ProductType:        models.ProductType(req.ProductType),
-		StateOrigin:        req.StateOrigin,
+		RegionID:           origin.RegionID,
+		VendorID:           req.VendorID,
+		StateOrigin:        origin.StateOrigin,
 		SareeType:          req.SareeType,
 		BasePrice:          req.BasePrice,
 		DiscountPercentage: req.DiscountPercentage,
@@ -228,11 +245,58 @@ func CreateProduct(c *gin.Context) {
 		}
 	}
 
+	// Opening stock goes into the default warehouse; StockQuantity is derived from it
+	if req.StockQuantity > 0 {
+		warehouse, err := defaultWarehouse(tx)
+		if err != nil {
+			tx.Rollback()
+			respondError(c, err, "Failed to stock product")
+			return
+		}
+
+		inventory := models.Inventory{
+			ProductID:   product.ID,
+			WarehouseID: warehouse.ID,
+			Quantity:    req.StockQuantity,
+		}
+		if err := tx.Create(&inventory).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stock product"})
+			return
+		}
+
+		adminID, _ := currentUserID(c)
+		if err := recordStockMovement(tx, models.StockMovement{
+			ProductID:   product.ID,
+			WarehouseID: warehouse.ID,
+			Type:        models.StockMovementReceipt,
+			Quantity:    req.StockQuantity,
+			Reason:      "Opening stock",
+			ActorID:     adminID,
+		}); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stock product"})
+			return
+		}
+	}
+
+	if err := recordAudit(tx, c, auditEntry{
+		Action:      "PRODUCT_CREATED",
+		EntityType:  "product",
+		EntityID:    product.ID,
+		Description: "Created product " + product.Name,
+		After:       product,
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create product"})
+		return
+	}
+
 	// Commit transaction
 	tx.Commit()
 
 	// Reload product with images
-	config.DB.Preload("Images").First(&product, product.ID)
+	config.DB.Preload("Images").Preload("Region").First(&product, product.ID)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Product created successfully",
@@ -256,6 +320,14 @@ func UpdateProduct(c *gin.Context) {
 		return
 	}
 
+	origin, err := resolveProductOrigin(config.DB, &req)
+	if err != nil {
+		respondError(c, err, "Failed to update product")
+		return
+	}
+
+	before := auditSnapshot(product)
+
 	// Calculate final price
 	finalPrice := req.BasePrice
 	if req.DiscountPercentage > 0 {
@@ -266,7 +338,14 @@ func UpdateProduct(c *gin.Context) {
 	product.Name = req.Name
 	product.Description = req.Description
 	product.ProductType = models.ProductType(req.ProductType)
-	product.StateOrigin = req.StateOrigin
+	// Origin and vendor are left alone unless the request gives them
+	if req.RegionID != nil || req.RegionSlug != "" || req.StateOrigin != "" {
+		product.RegionID = origin.RegionID
+		product.StateOrigin = origin.StateOrigin
+	}
+	if req.VendorID != nil {
+		product.VendorID = req.VendorID
+	}
 	product.SareeType = req.SareeType
 	product.BasePrice = req.BasePrice
 	product.DiscountPercentage = req.DiscountPercentage
@@ -274,16 +353,29 @@ func UpdateProduct(c *gin.Context) {
 	product.Fabric = req.Fabric
 	product.WeaveType = req.WeaveType
 	product.Occasion = req.Occasion
-	product.StockQuantity = req.StockQuantity
 	product.Metadata = models.JSONB(req.Metadata)
 
-	if err := config.DB.Save(&product).Error; err != nil {
+	// Stock is managed per warehouse through /admin/inventory
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("StockQuantity", "Region", "Vendor").Save(&product).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, auditEntry{
+			Action:      "PRODUCT_UPDATED",
+			EntityType:  "product",
+			EntityID:    product.ID,
+			Description: "Updated product " + product.Name,
+			Before:      before,
+			After:       product,
+		})
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update product"})
 		return
 	}
 
 	// Reload with images
-	config.DB.Preload("Images").First(&product, product.ID)
+	config.DB.Preload("Images").Preload("Region").First(&product, product.ID)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Product updated successfully",
@@ -302,7 +394,19 @@ func DeleteProduct(c *gin.Context) {
 	}
 
 	// Soft delete
-	if err := config.DB.Delete(&product).Error; err != nil {
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&product).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, auditEntry{
+			Action:      "PRODUCT_DELETED",
+			EntityType:  "product",
+			EntityID:    product.ID,
+			Description: "Deleted product " + product.Name,
+			Before:      product,
+		})
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete product"})
 		return
 	}
@@ -312,6 +416,78 @@ func DeleteProduct(c *gin.Context) {
 	})
 }
 
+// productOrigin is where a product comes from, as stored on it
+type productOrigin struct {
+	RegionID    *uint
+	StateOrigin string // Deprecated column, kept in step with the region's state
+}
+
+// resolveProductOrigin validates the region and vendor in req. Requests
+// that only give the deprecated state_origin get a region picked from it.
+func resolveProductOrigin(db *gorm.DB, req *CreateProductRequest) (*productOrigin, error) {
+	if req.VendorID != nil {
+		var vendor models.Vendor
+		if err := db.Select("id").First(&vendor, *req.VendorID).Error; err != nil {
+			return nil, newHTTPError(http.StatusBadRequest, "Vendor not found")
+		}
+	}
+
+	var region *models.Region
+	switch {
+	case req.RegionSlug != "":
+		var found models.Region
+		if err := db.Where("slug = ?", strings.ToLower(req.RegionSlug)).First(&found).Error; err != nil {
+			return nil, newHTTPError(http.StatusBadRequest, "Region not found")
+		}
+		region = &found
+	case req.RegionID != nil:
+		var found models.Region
+		if err := db.First(&found, *req.RegionID).Error; err != nil {
+			return nil, newHTTPError(http.StatusBadRequest, "Region not found")
+		}
+		region = &found
+	case req.StateOrigin != "":
+		var err error
+		if region, err = models.RegionForState(db, req.StateOrigin, req.SareeType); err != nil {
+			return nil, err
+		}
+	}
+
+	origin := &productOrigin{StateOrigin: strings.ToUpper(req.StateOrigin)}
+	if region == nil {
+		return origin, nil
+	}
+	origin.RegionID = &region.ID
+	if region.StateID != nil {
+		var state models.State
+		if err := db.Select("code").First(&state, *region.StateID).Error; err == nil {
+			origin.StateOrigin = state.Code
+		}
+	}
+	return origin, nil
+}
+
+// productOriginFilters filters products by region slug, vendor ID and state
+// code; empty values are ignored. The state is matched through the
+// product's region, falling back to state_origin for products without one.
+func productOriginFilters(region, vendorID, state string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if region != "" {
+			db = db.Where("region_id IN (SELECT id FROM regions WHERE slug = ? AND deleted_at IS NULL)", strings.ToLower(region))
+		}
+		if vendorID != "" {
+			db = db.Where("vendor_id = ?", vendorID)
+		}
+		if state != "" {
+			db = db.Where(`(region_id IN (
+					SELECT regions.id FROM regions JOIN states ON states.id = regions.state_id
+					WHERE UPPER(states.code) = UPPER(?) AND regions.deleted_at IS NULL
+				) OR (region_id IS NULL AND UPPER(state_origin) = UPPER(?)))`, state, state)
+		}
+		return db
+	}
+}
+
 // generateSlug creates URL-friendly slug from name
 func generateSlug(name string) string {
 	slug := strings.ToLower(name)
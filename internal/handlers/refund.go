@@ -0,0 +1,552 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/nilabhsubramaniam/kapas/internal/config"
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+	"github.com/nilabhsubramaniam/kapas/internal/notify"
+	"github.com/nilabhsubramaniam/kapas/internal/payments"
+	"github.com/nilabhsubramaniam/kapas/internal/utils"
+)
+
+// CreateRefundRequest represents an admin refund for an order
+type CreateRefundRequest struct {
+	Amount       float64              `json:"amount" binding:"omitempty,gt=0" example:"499"` // Defaults to everything still refundable
+	Reason       string               `json:"reason" example:"Goodwill refund for late delivery"`
+	BankTransfer *BankTransferDetails `json:"bank_transfer"` // Required for cash on delivery orders
+}
+
+// RefundReturnRequest represents an admin refund for a return
+type RefundReturnRequest struct {
+	BankTransfer *BankTransferDetails `json:"bank_transfer"` // Required for cash on delivery orders
+}
+
+// BankTransferDetails is the customer's account for a manual refund payout
+type BankTransferDetails struct {
+	AccountName   string `json:"account_name" binding:"required" example:"Priya Sharma"`
+	AccountNumber string `json:"account_number" binding:"required,numeric,min=9,max=18" example:"50100012345678"`
+	IFSC          string `json:"ifsc" binding:"required,len=11" example:"HDFC0001234"`
+	BankName      string `json:"bank_name" example:"HDFC Bank"`
+}
+
+// CompleteRefundRequest records the payout of a bank transfer refund
+type CompleteRefundRequest struct {
+	BankReference string `json:"bank_reference" binding:"required" example:"UTR123456789012"`
+}
+
+// refundIntent describes a refund to start for an order
+type refundIntent struct {
+	OrderID  uint
+	ReturnID *uint
+	Amount   float64 // Zero refunds everything still refundable
+	Reason   string
+	ActorID  uint
+	Bank     *BankTransferDetails
+}
+
+// CreateOrderRefund godoc
+// @Summary Refund an order
+// @Description Refund all or part of a paid order. Online payments are refunded through the payment gateway; cash on delivery orders need bank transfer details and are paid out manually (Admin only)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Param request body CreateRefundRequest true "Refund details"
+// @Success 201 {object} models.Refund
+// @Failure 400 {object} ErrorResponse "Invalid amount or missing bank details"
+// @Failure 404 {object} ErrorResponse "Order not found"
+// @Failure 409 {object} ErrorResponse "Nothing to refund"
+// @Failure 502 {object} ErrorResponse "Payment gateway error"
+// @Router /admin/orders/{id}/refunds [post]
+func CreateOrderRefund(c *gin.Context) {
+	adminID, _ := currentUserID(c)
+
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
+	var req CreateRefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	refund, err := startRefund(c.Request.Context(), refundIntent{
+		OrderID: uint(orderID),
+		Amount:  req.Amount,
+		Reason:  req.Reason,
+		ActorID: adminID,
+		Bank:    req.BankTransfer,
+	})
+	if err != nil {
+		respondError(c, err, "Failed to refund order")
+		return
+	}
+	auditRefund(c, refund, "Refund "+refund.RefundNumber+" issued for order")
+
+	c.JSON(http.StatusCreated, refund)
+}
+
+// RefundReturn godoc
+// @Summary Refund a return
+// @Description Refund an approved return's refund amount; the return is marked refunded once the money is returned (Admin only)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Return ID"
+// @Param request body RefundReturnRequest false "Bank details for cash on delivery orders"
+// @Success 201 {object} models.Refund
+// @Failure 404 {object} ErrorResponse "Return not found"
+// @Failure 409 {object} ErrorResponse "Return cannot be refunded"
+// @Failure 502 {object} ErrorResponse "Payment gateway error"
+// @Router /admin/returns/{id}/refund [post]
+func RefundReturn(c *gin.Context) {
+	adminID, _ := currentUserID(c)
+
+	var req RefundReturnRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var ret models.Return
+	if err := config.DB.First(&ret, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Return not found"})
+		return
+	}
+	if !ret.Status.CanTransitionTo(models.ReturnStatusRefunded) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("A %s return cannot be refunded", ret.Status)})
+		return
+	}
+	if ret.RefundAmount <= 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Return has no refund amount"})
+		return
+	}
+
+	refund, err := startRefund(c.Request.Context(), refundIntent{
+		OrderID:  ret.OrderID,
+		ReturnID: &ret.ID,
+		Amount:   ret.RefundAmount,
+		Reason:   "Return " + ret.ReturnNumber,
+		ActorID:  adminID,
+		Bank:     req.BankTransfer,
+	})
+	if err != nil {
+		respondError(c, err, "Failed to refund return")
+		return
+	}
+	auditRefund(c, refund, "Refund "+refund.RefundNumber+" issued for return "+ret.ReturnNumber)
+
+	c.JSON(http.StatusCreated, refund)
+}
+
+// ListRefunds godoc
+// @Summary List refunds
+// @Description Get refunds across all orders (Admin only)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(20)
+// @Param status query string false "Filter by status (pending, processed, failed)"
+// @Param method query string false "Filter by method (gateway, bank_transfer)"
+// @Param order_id query int false "Filter by order"
+// @Success 200 {object} map[string]interface{} "Refunds list"
+// @Router /admin/refunds [get]
+func ListRefunds(c *gin.Context) {
+	pagination := utils.GetPaginationParams(c)
+
+	var refunds []models.Refund
+	var total int64
+
+	query := config.DB.Model(&models.Refund{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if method := c.Query("method"); method != "" {
+		query = query.Where("method = ?", method)
+	}
+	if orderID := c.Query("order_id"); orderID != "" {
+		query = query.Where("order_id = ?", orderID)
+	}
+
+	query.Count(&total)
+
+	query.Preload("Order", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, order_number, user_id, payment_method, payment_status, total_amount")
+	}).
+		Order("created_at DESC").
+		Limit(pagination.PerPage).
+		Offset(pagination.Offset).
+		Find(&refunds)
+
+	c.JSON(http.StatusOK, utils.PaginatedResponse(refunds, total, pagination.Page, pagination.PerPage))
+}
+
+// CompleteRefund godoc
+// @Summary Complete bank transfer refund
+// @Description Record the payout of a pending bank transfer refund (Admin only)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Refund ID"
+// @Param request body CompleteRefundRequest true "Payout reference"
+// @Success 200 {object} models.Refund
+// @Failure 404 {object} ErrorResponse "Refund not found"
+// @Failure 409 {object} ErrorResponse "Refund is not a pending bank transfer"
+// @Router /admin/refunds/{id}/complete [put]
+func CompleteRefund(c *gin.Context) {
+	var req CompleteRefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var refund models.Refund
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&refund, c.Param("id")).Error; err != nil {
+			return newHTTPError(http.StatusNotFound, "Refund not found")
+		}
+		if refund.Method != models.RefundMethodBankTransfer || refund.Status != models.RefundStatusPending {
+			return newHTTPError(http.StatusConflict, "Only pending bank transfer refunds can be completed")
+		}
+
+		before := auditSnapshot(refund)
+		if err := completeRefund(tx, &refund, map[string]interface{}{
+			"bank_reference": strings.TrimSpace(req.BankReference),
+		}); err != nil {
+			return err
+		}
+
+		return recordAudit(tx, c, auditEntry{
+			Action:      "REFUND_COMPLETED",
+			EntityType:  "refund",
+			EntityID:    refund.ID,
+			Description: fmt.Sprintf("Refund %s paid by bank transfer, reference %s", refund.RefundNumber, req.BankReference),
+			Before:      before,
+			After:       refund,
+		})
+	})
+	if err != nil {
+		respondError(c, err, "Failed to complete refund")
+		return
+	}
+
+	config.DB.First(&refund, refund.ID)
+
+	c.JSON(http.StatusOK, refund)
+}
+
+// auditRefund records a refund an admin started. The refund is committed by
+// then, so a failure to audit is logged rather than returned.
+func auditRefund(c *gin.Context, refund *models.Refund, description string) {
+	if err := recordAudit(config.DB, c, auditEntry{
+		Action:      "REFUND_CREATED",
+		EntityType:  "refund",
+		EntityID:    refund.ID,
+		Description: fmt.Sprintf("%s: %.2f by %s", description, refund.Amount, refund.Method),
+		After:       refund,
+		Extra:       map[string]interface{}{"order_id": refund.OrderID},
+	}); err != nil {
+		log.Printf("Failed to audit refund %s: %v", refund.RefundNumber, err)
+	}
+}
+
+// startRefund records a refund and, for online payments, sends it to the
+// payment gateway. Gateway failures are kept on the refund as failed.
+func startRefund(ctx context.Context, intent refundIntent) (*models.Refund, error) {
+	var refund models.Refund
+	var payment *models.Payment
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		payment, err = createRefund(tx, intent, &refund)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if refund.Method == models.RefundMethodBankTransfer {
+		return &refund, nil
+	}
+
+	// The gateway call stays outside the transaction; the pending row
+	// already holds the amount against concurrent refunds
+	result, refundErr := refundAtGateway(ctx, payment, &refund)
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&refund, refund.ID).Error; err != nil {
+			return err
+		}
+
+		if refundErr != nil {
+			return tx.Model(&refund).Updates(map[string]interface{}{
+				"status":         models.RefundStatusFailed,
+				"failure_reason": refundErr.Error(),
+			}).Error
+		}
+
+		updates := map[string]interface{}{"provider_refund_id": result.ID}
+		// The gateway webhook may already have completed it
+		if refund.Status != models.RefundStatusPending {
+			return tx.Model(&refund).Updates(updates).Error
+		}
+		switch result.Status {
+		case payments.RefundStatusProcessed:
+			return completeRefund(tx, &refund, updates)
+		case payments.RefundStatusFailed:
+			updates["status"] = models.RefundStatusFailed
+			updates["failure_reason"] = "Rejected by the payment gateway"
+		}
+		return tx.Model(&refund).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if refundErr != nil {
+		log.Printf("Refund %s failed at the gateway: %v", refund.RefundNumber, refundErr)
+		return nil, newHTTPError(http.StatusBadGateway, "Payment gateway could not process the refund")
+	}
+	return &refund, nil
+}
+
+// createRefund checks how much of the order can still be refunded and records
+// a pending refund; it returns the gateway payment for online orders
+func createRefund(tx *gorm.DB, intent refundIntent, refund *models.Refund) (*models.Payment, error) {
+	var order models.Order
+	if err := lockOrder(tx, &order, "id = ?", intent.OrderID); err != nil {
+		return nil, newHTTPError(http.StatusNotFound, "Order not found")
+	}
+	if order.PaymentStatus == models.PaymentStatusPending || order.PaymentStatus == models.PaymentStatusFailed {
+		return nil, newHTTPError(http.StatusConflict, "Order has not been paid")
+	}
+
+	*refund = models.Refund{
+		RefundNumber: generateRefundNumber(),
+		OrderID:      order.ID,
+		ReturnID:     intent.ReturnID,
+		Status:       models.RefundStatusPending,
+		Currency:     "INR",
+		Reason:       intent.Reason,
+		InitiatedBy:  intent.ActorID,
+	}
+
+	var payment *models.Payment
+	paid := order.TotalAmount
+	if order.PaymentMethod == string(models.PaymentMethodCOD) {
+		if intent.Bank == nil {
+			return nil, newHTTPError(http.StatusBadRequest, "Bank transfer details are required to refund a cash on delivery order")
+		}
+		refund.Method = models.RefundMethodBankTransfer
+		refund.BankAccountName = strings.TrimSpace(intent.Bank.AccountName)
+		refund.BankAccountNumber = intent.Bank.AccountNumber
+		refund.BankIFSC = strings.ToUpper(intent.Bank.IFSC)
+		refund.BankName = intent.Bank.BankName
+	} else {
+		payment = &models.Payment{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("order_id = ?", order.ID).
+			First(payment).Error; err != nil {
+			return nil, newHTTPError(http.StatusConflict, "Order has no captured payment")
+		}
+		if payment.ProviderPaymentID == "" {
+			return nil, newHTTPError(http.StatusConflict, "Order has no captured payment")
+		}
+		refund.Method = models.RefundMethodGateway
+		refund.PaymentID = &payment.ID
+		paid = payment.Amount
+	}
+
+	if intent.ReturnID != nil {
+		var existing int64
+		if err := tx.Model(&models.Refund{}).
+			Where("return_id = ? AND status <> ?", *intent.ReturnID, models.RefundStatusFailed).
+			Count(&existing).Error; err != nil {
+			return nil, err
+		}
+		if existing > 0 {
+			return nil, newHTTPError(http.StatusConflict, "Return already has a refund")
+		}
+	}
+
+	var refunded float64
+	if err := tx.Model(&models.Refund{}).
+		Where("order_id = ? AND status <> ?", order.ID, models.RefundStatusFailed).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&refunded).Error; err != nil {
+		return nil, err
+	}
+
+	remaining := roundAmount(paid - refunded)
+	amount := roundAmount(intent.Amount)
+	if amount == 0 {
+		amount = remaining
+	}
+	if remaining <= 0 {
+		return nil, newHTTPError(http.StatusConflict, "Order has already been fully refunded")
+	}
+	if amount > remaining {
+		return nil, newHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("Only %.2f of this order can still be refunded", remaining))
+	}
+	refund.Amount = amount
+
+	return payment, tx.Create(refund).Error
+}
+
+// refundAtGateway sends a refund to the provider that captured the payment
+func refundAtGateway(ctx context.Context, payment *models.Payment, refund *models.Refund) (*payments.ProviderRefund, error) {
+	provider, err := payments.Get(payment.PaymentProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return provider.Refund(ctx, payments.RefundRequest{
+		ProviderPaymentID: payment.ProviderPaymentID,
+		Amount:            payments.ToPaise(refund.Amount),
+		Receipt:           refund.RefundNumber,
+		Notes:             map[string]string{"refund_number": refund.RefundNumber},
+	})
+}
+
+// completeRefund marks a locked refund processed, then updates the payment,
+// order and return it belongs to
+func completeRefund(tx *gorm.DB, refund *models.Refund, updates map[string]interface{}) error {
+	now := time.Now()
+	if updates == nil {
+		updates = map[string]interface{}{}
+	}
+	updates["status"] = models.RefundStatusProcessed
+	updates["failure_reason"] = ""
+	updates["processed_at"] = now
+	if err := tx.Model(refund).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	// Lock the return before the order, as UpdateReturnStatus does
+	if refund.ReturnID != nil {
+		var ret models.Return
+		if err := lockReturn(tx, &ret, "id = ?", *refund.ReturnID); err != nil {
+			return err
+		}
+		if ret.Status.CanTransitionTo(models.ReturnStatusRefunded) {
+			if err := tx.Model(&ret).Updates(map[string]interface{}{
+				"status":        models.ReturnStatusRefunded,
+				"refund_amount": refund.Amount,
+				"refunded_at":   now,
+			}).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := syncRefundStatus(tx, refund.OrderID); err != nil {
+		return err
+	}
+
+	var order models.Order
+	if err := tx.First(&order, refund.OrderID).Error; err != nil {
+		return err
+	}
+	bankReference, _ := updates["bank_reference"].(string)
+	return notifyOrder(tx, &order, notify.EventOrderRefunded, map[string]interface{}{
+		"Amount":        refund.Amount,
+		"Method":        string(refund.Method),
+		"BankReference": bankReference,
+	})
+}
+
+// syncRefundStatus sets the order's and payment's status from the refunds
+// processed so far
+func syncRefundStatus(tx *gorm.DB, orderID uint) error {
+	var order models.Order
+	if err := lockOrder(tx, &order, "id = ?", orderID); err != nil {
+		return err
+	}
+
+	var refunded float64
+	if err := tx.Model(&models.Refund{}).
+		Where("order_id = ? AND status = ?", orderID, models.RefundStatusProcessed).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&refunded).Error; err != nil {
+		return err
+	}
+
+	var payment models.Payment
+	paid := order.TotalAmount
+	err := tx.Where("order_id = ?", orderID).First(&payment).Error
+	hasPayment := err == nil
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if hasPayment {
+		paid = payment.Amount
+	}
+
+	status := models.PaymentStatusCompleted
+	switch {
+	case roundAmount(refunded) >= roundAmount(paid):
+		status = models.PaymentStatusRefunded
+	case refunded > 0:
+		status = models.PaymentStatusPartiallyRefunded
+	}
+
+	if err := tx.Model(&order).Update("payment_status", status).Error; err != nil {
+		return err
+	}
+	if !hasPayment {
+		return nil
+	}
+	return tx.Model(&payment).Update("status", status).Error
+}
+
+// refundCancelledOrder refunds an online payment in full after its order is
+// cancelled; failures are logged and left on the refund for an admin to retry
+func refundCancelledOrder(ctx context.Context, order *models.Order, actorID uint) *models.Refund {
+	if order.PaymentMethod == string(models.PaymentMethodCOD) ||
+		(order.PaymentStatus != models.PaymentStatusCompleted && order.PaymentStatus != models.PaymentStatusPartiallyRefunded) {
+		return nil
+	}
+
+	refund, err := startRefund(ctx, refundIntent{
+		OrderID: order.ID,
+		Reason:  "Order cancelled",
+		ActorID: actorID,
+	})
+	if err != nil {
+		log.Printf("Failed to refund cancelled order %s: %v", order.OrderNumber, err)
+		return nil
+	}
+	return refund
+}
+
+// generateRefundNumber creates a readable unique refund number
+func generateRefundNumber() string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return fmt.Sprintf("RFD-%s-%s", time.Now().Format("20060102"), strings.ToUpper(hex.EncodeToString(suffix)))
+}
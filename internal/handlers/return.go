@@ -0,0 +1,599 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/nilabhsubramaniam/kapas/internal/config"
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+	"github.com/nilabhsubramaniam/kapas/internal/utils"
+)
+
+// CreateReturnRequest represents a customer return request
+type CreateReturnRequest struct {
+	OrderID       uint                `json:"order_id" binding:"required" example:"1"`
+	Reason        string              `json:"reason" binding:"required,oneof=defective wrong_item not_as_described size_issue other" example:"defective"`
+	ReasonDetails string              `json:"reason_details" example:"Zari work torn near the pallu"`
+	Items         []ReturnItemRequest `json:"items" binding:"required,min=1,dive"`
+}
+
+// ReturnItemRequest represents one order item being returned
+type ReturnItemRequest struct {
+	OrderItemID uint   `json:"order_item_id" binding:"required" example:"1"`
+	Quantity    int    `json:"quantity" binding:"required,min=1" example:"1"`
+	Reason      string `json:"reason" example:"Torn fabric"`
+}
+
+// UpdateReturnStatusRequest represents an admin return status change
+type UpdateReturnStatusRequest struct {
+	Status       string   `json:"status" binding:"required,oneof=approved rejected picked_up received" example:"approved"`
+	AdminNotes   string   `json:"admin_notes" example:"Photos confirm the defect"`
+	RefundAmount *float64 `json:"refund_amount" binding:"omitempty,min=0" example:"2499"` // Lower the refund when approving or receiving, e.g. for damage
+}
+
+// returnWindow is how long after delivery customers may open a return
+func returnWindow() time.Duration {
+	return time.Duration(config.GetEnvInt("RETURN_WINDOW_DAYS", 7)) * 24 * time.Hour
+}
+
+// CreateReturn godoc
+// @Summary Request a return
+// @Description Open a return for items of a delivered order within the return window
+// @Tags Returns
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateReturnRequest true "Return details"
+// @Success 201 {object} models.Return
+// @Failure 400 {object} ErrorResponse "Invalid items or quantities"
+// @Failure 404 {object} ErrorResponse "Order not found"
+// @Failure 409 {object} ErrorResponse "Order not returnable"
+// @Router /returns [post]
+func CreateReturn(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req CreateReturnRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var ret models.Return
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		// Locking the order serialises returns so quantities can't be over-claimed
+		if err := lockOrder(tx, &order, "id = ? AND user_id = ?", req.OrderID, userID); err != nil {
+			return newHTTPError(http.StatusNotFound, "Order not found")
+		}
+		if order.Status != models.OrderStatusDelivered {
+			return newHTTPError(http.StatusConflict, "Only delivered orders can be returned")
+		}
+
+		deliveredAt, err := orderDeliveredAt(tx, &order)
+		if err != nil {
+			return err
+		}
+		if deadline := deliveredAt.Add(returnWindow()); time.Now().After(deadline) {
+			return newHTTPError(http.StatusConflict,
+				fmt.Sprintf("The return window closed on %s", deadline.Format("02 Jan 2006")))
+		}
+
+		var orderItems []models.OrderItem
+		if err := tx.Where("order_id = ?", order.ID).Find(&orderItems).Error; err != nil {
+			return err
+		}
+		itemsByID := make(map[uint]models.OrderItem, len(orderItems))
+		for _, item := range orderItems {
+			itemsByID[item.ID] = item
+		}
+
+		returned, err := returnedQuantities(tx, order.ID)
+		if err != nil {
+			return err
+		}
+
+		// Merge repeated lines before checking quantities
+		requested := map[uint]*models.ReturnItem{}
+		var lines []*models.ReturnItem
+		for _, r := range req.Items {
+			orderItem, ok := itemsByID[r.OrderItemID]
+			if !ok {
+				return newHTTPError(http.StatusBadRequest, fmt.Sprintf("Order item %d is not part of this order", r.OrderItemID))
+			}
+			if line, ok := requested[r.OrderItemID]; ok {
+				line.Quantity += r.Quantity
+				continue
+			}
+			line := &models.ReturnItem{
+				OrderItemID: orderItem.ID,
+				ProductID:   orderItem.ProductID,
+				Quantity:    r.Quantity,
+				Reason:      r.Reason,
+			}
+			requested[r.OrderItemID] = line
+			lines = append(lines, line)
+		}
+
+		for _, line := range lines {
+			orderItem := itemsByID[line.OrderItemID]
+			if remaining := orderItem.Quantity - returned[line.OrderItemID]; line.Quantity > remaining {
+				return newHTTPError(http.StatusBadRequest,
+					fmt.Sprintf("Only %d of %q can still be returned", remaining, orderItem.ProductName))
+			}
+		}
+
+		ret = models.Return{
+			OrderID:       order.ID,
+			UserID:        userID,
+			ReturnNumber:  generateReturnNumber(),
+			Reason:        models.ReturnReason(req.Reason),
+			ReasonDetails: req.ReasonDetails,
+			Status:        models.ReturnStatusRequested,
+			RefundAmount:  returnRefundAmount(&order, itemsByID, lines),
+		}
+		if err := tx.Create(&ret).Error; err != nil {
+			return err
+		}
+
+		for _, line := range lines {
+			line.ReturnID = ret.ID
+			if err := tx.Create(line).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		respondError(c, err, "Failed to create return")
+		return
+	}
+
+	config.DB.Preload("Items.OrderItem").First(&ret, ret.ID)
+
+	c.JSON(http.StatusCreated, ret)
+}
+
+// ListUserReturns godoc
+// @Summary List my returns
+// @Description Get the current user's return requests
+// @Tags Returns
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(20)
+// @Param status query string false "Filter by status"
+// @Success 200 {object} map[string]interface{} "Returns list"
+// @Router /returns [get]
+func ListUserReturns(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	pagination := utils.GetPaginationParams(c)
+
+	var returns []models.Return
+	var total int64
+
+	query := config.DB.Model(&models.Return{}).Where("user_id = ?", userID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	query.Count(&total)
+
+	query.Preload("Items.OrderItem").
+		Order("created_at DESC").
+		Limit(pagination.PerPage).
+		Offset(pagination.Offset).
+		Find(&returns)
+
+	c.JSON(http.StatusOK, utils.PaginatedResponse(returns, total, pagination.Page, pagination.PerPage))
+}
+
+// GetReturn godoc
+// @Summary Get return
+// @Description Get one of the current user's return requests
+// @Tags Returns
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Return ID"
+// @Success 200 {object} models.Return
+// @Failure 404 {object} ErrorResponse "Return not found"
+// @Router /returns/{id} [get]
+func GetReturn(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var ret models.Return
+	if err := config.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).
+		Preload("Items.OrderItem").
+		First(&ret).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Return not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ret)
+}
+
+// ============================================
+// ADMIN RETURN MANAGEMENT
+// ============================================
+
+// ListReturns godoc
+// @Summary List all returns
+// @Description Get return requests across all customers (Admin only)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(20)
+// @Param status query string false "Filter by status"
+// @Param order_id query int false "Filter by order"
+// @Success 200 {object} map[string]interface{} "Returns list"
+// @Router /admin/returns [get]
+func ListReturns(c *gin.Context) {
+	pagination := utils.GetPaginationParams(c)
+
+	var returns []models.Return
+	var total int64
+
+	query := config.DB.Model(&models.Return{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if orderID := c.Query("order_id"); orderID != "" {
+		query = query.Where("order_id = ?", orderID)
+	}
+
+	query.Count(&total)
+
+	if err := query.Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, email, name, phone")
+	}).
+		Preload("Items.OrderItem").
+		Order("created_at DESC").
+		Limit(pagination.PerPage).
+		Offset(pagination.Offset).
+		Find(&returns).Error; err != nil {
+		log.Printf("Failed to list returns: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch returns"})
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.PaginatedResponse(returns, total, pagination.Page, pagination.PerPage))
+}
+
+// GetReturnDetails godoc
+// @Summary Get return details
+// @Description Get a return request with its order (Admin only)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Return ID"
+// @Success 200 {object} models.Return
+// @Failure 404 {object} ErrorResponse "Return not found"
+// @Router /admin/returns/{id} [get]
+func GetReturnDetails(c *gin.Context) {
+	returnID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Return not found"})
+		return
+	}
+
+	var ret models.Return
+	if err := config.DB.Preload("Items.OrderItem").
+		Preload("Order").
+		Preload("User", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, email, name, phone")
+		}).
+		First(&ret, returnID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Return not found"})
+			return
+		}
+		log.Printf("Failed to load return %d: %v", returnID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch return"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ret)
+}
+
+// UpdateReturnStatus godoc
+// @Summary Update return status
+// @Description Move a return through approved, rejected, picked_up and received. Receiving puts the items back into stock; refunds go through POST /admin/returns/{id}/refund (Admin only)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Return ID"
+// @Param request body UpdateReturnStatusRequest true "Status update"
+// @Success 200 {object} map[string]interface{} "Return updated successfully"
+// @Failure 400 {object} ErrorResponse "Invalid refund amount"
+// @Failure 404 {object} ErrorResponse "Return not found"
+// @Failure 409 {object} ErrorResponse "Transition not allowed"
+// @Router /admin/returns/{id}/status [put]
+func UpdateReturnStatus(c *gin.Context) {
+	adminID, _ := currentUserID(c)
+
+	var req UpdateReturnStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var ret models.Return
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockReturn(tx, &ret, "id = ?", c.Param("id")); err != nil {
+			return newHTTPError(http.StatusNotFound, "Return not found")
+		}
+
+		before := auditSnapshot(ret)
+		to := models.ReturnStatus(req.Status)
+		if !ret.Status.CanTransitionTo(to) {
+			return newHTTPError(http.StatusConflict,
+				fmt.Sprintf("Cannot change return status from %s to %s", ret.Status, to))
+		}
+
+		now := time.Now()
+		updates := map[string]interface{}{"status": to}
+		if req.AdminNotes != "" {
+			updates["admin_notes"] = req.AdminNotes
+		}
+
+		if req.RefundAmount != nil {
+			if to != models.ReturnStatusApproved && to != models.ReturnStatusReceived {
+				return newHTTPError(http.StatusBadRequest, "The refund amount can only be changed when approving or receiving a return")
+			}
+			maximum, err := maxReturnRefund(tx, &ret)
+			if err != nil {
+				return err
+			}
+			if *req.RefundAmount > maximum {
+				return newHTTPError(http.StatusBadRequest,
+					fmt.Sprintf("Refund cannot exceed %.2f for the returned items", maximum))
+			}
+			updates["refund_amount"] = roundAmount(*req.RefundAmount)
+		}
+
+		switch to {
+		case models.ReturnStatusApproved:
+			updates["approved_by"] = adminID
+			updates["approved_at"] = now
+		case models.ReturnStatusReceived:
+			if err := restockReturn(tx, &ret, adminID); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&ret).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		if to == models.ReturnStatusReceived {
+			if err := closeFullyReturnedOrder(tx, ret.OrderID, adminID); err != nil {
+				return err
+			}
+		}
+
+		return recordAudit(tx, c, auditEntry{
+			Action:      "RETURN_STATUS_UPDATED",
+			EntityType:  "return",
+			EntityID:    ret.ID,
+			Description: fmt.Sprintf("Return %s moved to %s. %s", ret.ReturnNumber, to, req.AdminNotes),
+			Before:      before,
+			After:       ret,
+		})
+	})
+	if err != nil {
+		respondError(c, err, "Failed to update return")
+		return
+	}
+
+	config.DB.Preload("Items.OrderItem").First(&ret, ret.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Return updated successfully",
+		"return":  ret,
+	})
+}
+
+// lockReturn loads a return with its items for update within tx
+func lockReturn(tx *gorm.DB, ret *models.Return, query interface{}, args ...interface{}) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Items").
+		Where(query, args...).
+		First(ret).Error
+}
+
+// orderDeliveredAt returns when an order was marked delivered
+func orderDeliveredAt(tx *gorm.DB, order *models.Order) (time.Time, error) {
+	var history models.OrderStatusHistory
+	err := tx.Where("order_id = ? AND status = ?", order.ID, models.OrderStatusDelivered).
+		Order("created_at DESC").
+		First(&history).Error
+	if err == gorm.ErrRecordNotFound {
+		return order.UpdatedAt, nil
+	}
+	return history.CreatedAt, err
+}
+
+// returnedQuantities sums quantities per order item across an order's
+// returns that haven't been rejected
+func returnedQuantities(tx *gorm.DB, orderID uint) (map[uint]int, error) {
+	var rows []struct {
+		OrderItemID uint
+		Quantity    int
+	}
+	err := tx.Table("return_items").
+		Select("return_items.order_item_id, SUM(return_items.quantity) AS quantity").
+		Joins("JOIN returns ON returns.id = return_items.return_id AND returns.deleted_at IS NULL").
+		Where("returns.order_id = ? AND returns.status <> ?", orderID, models.ReturnStatusRejected).
+		Group("return_items.order_item_id").
+		Scan(&rows).Error
+
+	returned := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		returned[row.OrderItemID] = row.Quantity
+	}
+	return returned, err
+}
+
+// returnRefundAmount is the value paid for the returned items: their share of
+// the order's discounted subtotal plus tax; shipping is not refunded
+func returnRefundAmount(order *models.Order, itemsByID map[uint]models.OrderItem, lines []*models.ReturnItem) float64 {
+	if order.SubtotalAmount <= 0 {
+		return 0
+	}
+
+	value := 0.0
+	for _, line := range lines {
+		value += itemsByID[line.OrderItemID].UnitPrice * float64(line.Quantity)
+	}
+
+	share := value / order.SubtotalAmount
+	return roundAmount(share * (order.SubtotalAmount - order.DiscountAmount + order.TaxAmount))
+}
+
+// maxReturnRefund recomputes the full refund for a return's items
+func maxReturnRefund(tx *gorm.DB, ret *models.Return) (float64, error) {
+	var order models.Order
+	if err := tx.First(&order, ret.OrderID).Error; err != nil {
+		return 0, err
+	}
+
+	var orderItems []models.OrderItem
+	if err := tx.Where("order_id = ?", order.ID).Find(&orderItems).Error; err != nil {
+		return 0, err
+	}
+	itemsByID := make(map[uint]models.OrderItem, len(orderItems))
+	for _, item := range orderItems {
+		itemsByID[item.ID] = item
+	}
+
+	lines := make([]*models.ReturnItem, 0, len(ret.Items))
+	for i := range ret.Items {
+		lines = append(lines, &ret.Items[i])
+	}
+	return returnRefundAmount(&order, itemsByID, lines), nil
+}
+
+// restockReturn puts a return's items back into the warehouses they shipped from
+func restockReturn(tx *gorm.DB, ret *models.Return, actorID uint) error {
+	productIDs := make([]uint, 0, len(ret.Items))
+	for _, item := range ret.Items {
+		warehouseID, err := returnWarehouse(tx, ret.OrderID, item.ProductID)
+		if err != nil {
+			return err
+		}
+
+		var row models.Inventory
+		if err := lockInventoryRow(tx, &row, item.ProductID, warehouseID); err != nil {
+			return err
+		}
+		if err := tx.Model(&row).Update("quantity", gorm.Expr("quantity + ?", item.Quantity)).Error; err != nil {
+			return err
+		}
+
+		if err := recordStockMovement(tx, models.StockMovement{
+			ProductID:     item.ProductID,
+			WarehouseID:   warehouseID,
+			Type:          models.StockMovementReturn,
+			Quantity:      item.Quantity,
+			ReferenceType: models.StockReferenceReturn,
+			ReferenceID:   ret.ID,
+			Reason:        "Return " + ret.ReturnNumber + " received",
+			ActorID:       actorID,
+		}); err != nil {
+			return err
+		}
+		productIDs = append(productIDs, item.ProductID)
+	}
+
+	return syncProductStock(tx, productIDs...)
+}
+
+// returnWarehouse picks the warehouse a returned product goes back to: the
+// one it was sold from, or the default warehouse
+func returnWarehouse(tx *gorm.DB, orderID, productID uint) (uint, error) {
+	var reservation models.StockReservation
+	err := tx.Joins("JOIN warehouses ON warehouses.id = stock_reservations.warehouse_id AND warehouses.deleted_at IS NULL").
+		Where("stock_reservations.order_id = ? AND stock_reservations.product_id = ? AND stock_reservations.status = ?",
+			orderID, productID, models.ReservationStatusCommitted).
+		Order("stock_reservations.quantity DESC").
+		First(&reservation).Error
+	if err == nil {
+		return reservation.WarehouseID, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return 0, err
+	}
+
+	warehouse, err := defaultWarehouse(tx)
+	if err != nil {
+		return 0, err
+	}
+	return warehouse.ID, nil
+}
+
+// closeFullyReturnedOrder marks an order returned once every unit has come back
+func closeFullyReturnedOrder(tx *gorm.DB, orderID, actorID uint) error {
+	var ordered, received int64
+	if err := tx.Model(&models.OrderItem{}).
+		Where("order_id = ?", orderID).
+		Select("COALESCE(SUM(quantity), 0)").
+		Scan(&ordered).Error; err != nil {
+		return err
+	}
+	if err := tx.Table("return_items").
+		Joins("JOIN returns ON returns.id = return_items.return_id AND returns.deleted_at IS NULL").
+		Where("returns.order_id = ? AND returns.status IN ?", orderID,
+			[]models.ReturnStatus{models.ReturnStatusReceived, models.ReturnStatusRefunded}).
+		Select("COALESCE(SUM(return_items.quantity), 0)").
+		Scan(&received).Error; err != nil {
+		return err
+	}
+	if received < ordered {
+		return nil
+	}
+
+	var order models.Order
+	if err := lockOrder(tx, &order, "id = ?", orderID); err != nil {
+		return err
+	}
+	if !order.Status.CanTransitionTo(models.OrderStatusReturned) {
+		return nil
+	}
+	return transitionOrder(tx, &order, orderTransition{
+		To:      models.OrderStatusReturned,
+		ActorID: actorID,
+		Comment: "All items returned",
+	})
+}
+
+// generateReturnNumber creates a readable unique return number
+func generateReturnNumber() string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return fmt.Sprintf("RMA-%s-%s", time.Now().Format("20060102"), strings.ToUpper(hex.EncodeToString(suffix)))
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/nilabhsubramaniam/kapas/internal/config"
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+	"github.com/nilabhsubramaniam/kapas/internal/utils"
+)
+
+// RefreshTokenRequest represents a token refresh or logout request
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required" example:"q3Jx0m4y...7TfA"`
+}
+
+// startSession begins a new refresh token family for a user and returns its
+// first token pair
+func startSession(db *gorm.DB, c *gin.Context, user *models.User) (*TokenResponse, error) {
+	familyID, err := utils.RandomID()
+	if err != nil {
+		return nil, err
+	}
+	return issueTokens(db, c, user, familyID)
+}
+
+// issueTokens creates an access token and a refresh token in the given family
+func issueTokens(db *gorm.DB, c *gin.Context, user *models.User, familyID string) (*TokenResponse, error) {
+	accessToken, jti, err := utils.GenerateToken(user.ID, user.Email, string(user.Role), familyID)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	row := models.RefreshToken{
+		UserID:          user.ID,
+		FamilyID:        familyID,
+		TokenHash:       utils.HashToken(refreshToken),
+		AccessJTI:       jti,
+		AccessExpiresAt: now.Add(utils.AccessTokenTTL()),
+		ExpiresAt:       now.Add(utils.RefreshTokenTTL()),
+		IPAddress:       c.ClientIP(),
+		UserAgent:       c.Request.UserAgent(),
+	}
+	if err := db.Create(&row).Error; err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(utils.AccessTokenTTL().Seconds()),
+	}, nil
+}
+
+// revokeSessions revokes the refresh tokens matching query and denylists the
+// access tokens issued with them that haven't expired yet
+func revokeSessions(tx *gorm.DB, query interface{}, args ...interface{}) error {
+	now := time.Now()
+
+	var rows []models.RefreshToken
+	if err := tx.Where(query, args...).
+		Where("access_expires_at > ?", now).
+		Find(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		denied := models.RevokedToken{JTI: row.AccessJTI, UserID: row.UserID, ExpiresAt: row.AccessExpiresAt}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&denied).Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.Model(&models.RefreshToken{}).
+		Where(query, args...).
+		Where("revoked_at IS NULL").
+		Update("revoked_at", now).Error
+}
+
+// RefreshToken godoc
+// @Summary Refresh access token
+// @Description Exchange a refresh token for a new access token and refresh token. Each refresh token works once; presenting a used one ends the whole session.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body RefreshTokenRequest true "Refresh token"
+// @Success 200 {object} TokenResponse "New token pair"
+// @Failure 401 {object} ErrorResponse "Invalid, expired or revoked refresh token"
+// @Failure 403 {object} ErrorResponse "Account is inactive"
+// @Router /auth/refresh [post]
+func RefreshToken(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Revocations must commit even though the request is rejected, so
+	// rejections are returned through this rather than the transaction
+	var rejected error
+	var tokens *TokenResponse
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var current models.RefreshToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", utils.HashToken(req.RefreshToken)).
+			First(&current).Error; err != nil {
+			rejected = newHTTPError(http.StatusUnauthorized, "Invalid refresh token")
+			return nil
+		}
+
+		switch {
+		case current.RevokedAt != nil:
+			rejected = newHTTPError(http.StatusUnauthorized, "Session has been revoked")
+			return nil
+		case current.UsedAt != nil:
+			// A rotated token came back, so it was copied: end the session
+			log.Printf("Refresh token reuse in session %s of user %d; revoking it", current.FamilyID, current.UserID)
+			rejected = newHTTPError(http.StatusUnauthorized, "Session has been revoked")
+			return revokeSessions(tx, "family_id = ?", current.FamilyID)
+		case time.Now().After(current.ExpiresAt):
+			rejected = newHTTPError(http.StatusUnauthorized, "Refresh token expired")
+			return nil
+		}
+
+		var user models.User
+		if err := tx.First(&user, current.UserID).Error; err != nil || !user.IsActive {
+			rejected = newHTTPError(http.StatusForbidden, "Account is inactive")
+			return revokeSessions(tx, "family_id = ?", current.FamilyID)
+		}
+
+		if err := tx.Model(&current).Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		var err error
+		tokens, err = issueTokens(tx, c, &user, current.FamilyID)
+		return err
+	})
+	if err == nil {
+		err = rejected
+	}
+	if err != nil {
+		respondError(c, err, "Failed to refresh token")
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// PurgeExpiredTokens deletes expired denylist entries, refresh tokens,
+// emailed account tokens and old login codes
+func PurgeExpiredTokens() {
+	now := time.Now()
+	if err := config.DB.Where("expires_at < ?", now).Delete(&models.RevokedToken{}).Error; err != nil {
+		log.Printf("Failed to purge revoked tokens: %v", err)
+	}
+	if err := config.DB.Where("expires_at < ?", now).Delete(&models.RefreshToken{}).Error; err != nil {
+		log.Printf("Failed to purge refresh tokens: %v", err)
+	}
+	if err := config.DB.Where("expires_at < ?", now).Delete(&models.UserToken{}).Error; err != nil {
+		log.Printf("Failed to purge account tokens: %v", err)
+	}
+	// OTP rows feed the hourly rate limits, so keep a day of them
+	dayAgo := now.Add(-24 * time.Hour)
+	if err := config.DB.Where("created_at < ?", dayAgo).Delete(&models.PhoneOTP{}).Error; err != nil {
+		log.Printf("Failed to purge login codes: %v", err)
+	}
+	if err := config.DB.Where("created_at < ?", dayAgo).Delete(&models.OTPFailure{}).Error; err != nil {
+		log.Printf("Failed to purge login code failures: %v", err)
+	}
+}
+
+// RunTokenCleanup runs PurgeExpiredTokens every interval; call in a goroutine
+func RunTokenCleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		PurgeExpiredTokens()
+	}
+}
@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/nilabhsubramaniam/kapas/internal/config"
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+	"github.com/nilabhsubramaniam/kapas/internal/shipping"
+)
+
+// CreateShipmentRequest represents an admin request to book a courier pickup
+type CreateShipmentRequest struct {
+	ProviderID  uint    `json:"provider_id" example:"1"`  // Defaults to the first active provider with an integration
+	WarehouseID uint    `json:"warehouse_id" example:"1"` // Pickup warehouse; defaults to where the order's stock is held
+	WeightKg    float64 `json:"weight_kg" binding:"omitempty,gt=0" example:"1.2"`
+	LengthCm    float64 `json:"length_cm" binding:"omitempty,gt=0" example:"35"`
+	WidthCm     float64 `json:"width_cm" binding:"omitempty,gt=0" example:"25"`
+	HeightCm    float64 `json:"height_cm" binding:"omitempty,gt=0" example:"8"`
+}
+
+// CreateShipment godoc
+// @Summary Create shipment
+// @Description Book a courier pickup for a confirmed order; the order moves to processing (Admin only)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Param request body CreateShipmentRequest false "Courier and parcel details"
+// @Success 201 {object} map[string]interface{} "Shipment created successfully"
+// @Failure 400 {object} ErrorResponse "No usable courier or warehouse"
+// @Failure 404 {object} ErrorResponse "Order not found"
+// @Failure 409 {object} ErrorResponse "Order not ready to ship or already has a shipment"
+// @Failure 502 {object} ErrorResponse "Courier booking failed"
+// @Router /admin/orders/{id}/shipment [post]
+func CreateShipment(c *gin.Context) {
+	adminID, _ := currentUserID(c)
+
+	var req CreateShipmentRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var order models.Order
+	if err := config.DB.Preload("Items").First(&order, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+	if err := checkShippable(config.DB, &order); err != nil {
+		respondError(c, err, "Failed to create shipment")
+		return
+	}
+
+	provider, courier, err := resolveCourier(req.ProviderID)
+	if err != nil {
+		respondError(c, err, "Failed to create shipment")
+		return
+	}
+
+	warehouse, err := pickupWarehouse(order.ID, req.WarehouseID)
+	if err != nil {
+		respondError(c, err, "Failed to create shipment")
+		return
+	}
+
+	parcel := shipmentParcel(&order, &req)
+	codAmount := 0.0
+	if order.PaymentMethod == string(models.PaymentMethodCOD) && order.PaymentStatus != models.PaymentStatusCompleted {
+		codAmount = order.TotalAmount
+	}
+
+	// Book outside the transaction; the courier call can be slow
+	booking, err := courier.Book(c.Request.Context(), shipping.BookingRequest{
+		OrderNumber:   order.OrderNumber,
+		Pickup:        warehouseAddress(warehouse),
+		Delivery:      orderDeliveryAddress(&order),
+		Parcel:        parcel,
+		DeclaredValue: order.TotalAmount,
+		CODAmount:     codAmount,
+	})
+	if err != nil {
+		log.Printf("Courier %s booking failed for order %s: %v", provider.Code, order.OrderNumber, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Courier booking failed: " + err.Error()})
+		return
+	}
+
+	var shipment models.Shipment
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockOrder(tx, &order, "id = ?", order.ID); err != nil {
+			return err
+		}
+		// Another admin may have booked while we waited on the courier
+		if err := checkShippable(tx, &order); err != nil {
+			return err
+		}
+
+		estimated := booking.EstimatedDelivery
+		shipment = models.Shipment{
+			OrderID:    order.ID,
+			ProviderID: provider.ID,
+			AWBNumber:  booking.AWBNumber,
+			Status:     models.ShipmentStatusPending,
+			Weight:     parcel.WeightKg,
+			Dimensions: models.JSONB{
+				"length_cm": parcel.LengthCm,
+				"width_cm":  parcel.WidthCm,
+				"height_cm": parcel.HeightCm,
+			},
+			ShippingCost:      booking.ShippingCost,
+			EstimatedDelivery: &estimated,
+			PickupDate:        booking.PickupDate,
+			TrackingURL:       booking.TrackingURL,
+			Metadata: models.JSONB{
+				"courier_reference": booking.Reference,
+				"warehouse_id":      warehouse.ID,
+				"cod_amount":        codAmount,
+				"booked_by":         adminID,
+			},
+		}
+		if err := tx.Create(&shipment).Error; err != nil {
+			return err
+		}
+
+		if order.Status == models.OrderStatusConfirmed {
+			if err := transitionOrder(tx, &order, orderTransition{
+				To:      models.OrderStatusProcessing,
+				ActorID: adminID,
+				Comment: fmt.Sprintf("Shipment booked with %s, AWB %s", provider.Name, booking.AWBNumber),
+			}); err != nil {
+				return err
+			}
+		}
+
+		return recordAudit(tx, c, auditEntry{
+			Action:      "SHIPMENT_CREATED",
+			EntityType:  "order",
+			EntityID:    order.ID,
+			Description: fmt.Sprintf("Booked %s AWB %s", provider.Name, booking.AWBNumber),
+			After:       shipment,
+		})
+	})
+	if err != nil {
+		// Don't leave an orphaned pickup at the courier
+		if cancelErr := courier.Cancel(c.Request.Context(), booking.AWBNumber); cancelErr != nil {
+			log.Printf("Failed to cancel orphaned AWB %s with %s: %v", booking.AWBNumber, provider.Code, cancelErr)
+		}
+		respondError(c, err, "Failed to create shipment")
+		return
+	}
+
+	shipment.Provider = *provider
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Shipment created successfully",
+		"shipment": shipment,
+	})
+}
+
+// CancelShipment godoc
+// @Summary Cancel shipment
+// @Description Cancel a courier booking that has not been picked up, so the order can be rebooked or cancelled (Admin only)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Success 200 {object} map[string]interface{} "Shipment cancelled successfully"
+// @Failure 404 {object} ErrorResponse "Shipment not found"
+// @Failure 409 {object} ErrorResponse "Shipment already picked up"
+// @Failure 502 {object} ErrorResponse "Courier cancellation failed"
+// @Router /admin/orders/{id}/shipment [delete]
+func CancelShipment(c *gin.Context) {
+	var shipment models.Shipment
+	if err := config.DB.Preload("Provider").Where("order_id = ?", c.Param("id")).First(&shipment).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipment not found"})
+		return
+	}
+	if shipment.Status != models.ShipmentStatusPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "Shipment has already been picked up"})
+		return
+	}
+
+	courier, err := shipping.For(shipment.Provider)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No integration for courier " + shipment.Provider.Code})
+		return
+	}
+	if err := courier.Cancel(c.Request.Context(), shipment.AWBNumber); err != nil {
+		log.Printf("Courier %s cancellation failed for AWB %s: %v", shipment.Provider.Code, shipment.AWBNumber, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Courier cancellation failed: " + err.Error()})
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Hard delete: order_id and awb_number are unique even across soft-deleted rows
+		if err := tx.Unscoped().Delete(&shipment).Error; err != nil {
+			return err
+		}
+
+		return recordAudit(tx, c, auditEntry{
+			Action:      "SHIPMENT_CANCELLED",
+			EntityType:  "order",
+			EntityID:    shipment.OrderID,
+			Description: fmt.Sprintf("Cancelled %s AWB %s", shipment.Provider.Name, shipment.AWBNumber),
+			Before:      shipment,
+		})
+	})
+	if err != nil {
+		respondError(c, err, "Failed to cancel shipment")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Shipment cancelled successfully"})
+}
+
+// checkShippable reports whether an order can have a shipment booked
+func checkShippable(db *gorm.DB, order *models.Order) error {
+	if order.Status != models.OrderStatusConfirmed && order.Status != models.OrderStatusProcessing {
+		return newHTTPError(http.StatusConflict,
+			fmt.Sprintf("Only confirmed or processing orders can be shipped (order is %s)", order.Status))
+	}
+
+	var count int64
+	if err := db.Model(&models.Shipment{}).Where("order_id = ?", order.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return newHTTPError(http.StatusConflict, "Order already has a shipment")
+	}
+	return nil
+}
+
+// resolveCourier returns the requested logistics provider and its courier,
+// or the first active provider that has an integration
+func resolveCourier(providerID uint) (*models.LogisticsProvider, shipping.Courier, error) {
+	var providers []models.LogisticsProvider
+	query := config.DB.Where("is_active = ?", true)
+	if providerID != 0 {
+		query = query.Where("id = ?", providerID)
+	}
+	if err := query.Order("id ASC").Find(&providers).Error; err != nil {
+		return nil, nil, err
+	}
+
+	for i := range providers {
+		if courier, err := shipping.For(providers[i]); err == nil {
+			return &providers[i], courier, nil
+		}
+	}
+
+	if providerID != 0 {
+		return nil, nil, newHTTPError(http.StatusBadRequest, "Logistics provider not found, inactive or not integrated")
+	}
+	return nil, nil, newHTTPError(http.StatusBadRequest, "No active logistics provider with a courier integration")
+}
+
+// pickupWarehouse returns the requested warehouse, or the one holding most
+// of the order's stock
+func pickupWarehouse(orderID, warehouseID uint) (*models.Warehouse, error) {
+	if warehouseID == 0 {
+		var held struct{ WarehouseID uint }
+		config.DB.Model(&models.StockReservation{}).
+			Select("warehouse_id, SUM(quantity) AS total").
+			Where("order_id = ? AND status <> ?", orderID, models.ReservationStatusReleased).
+			Group("warehouse_id").
+			Order("total DESC").
+			Limit(1).
+			Scan(&held)
+		warehouseID = held.WarehouseID
+	}
+	if warehouseID == 0 {
+		return defaultWarehouse(config.DB)
+	}
+
+	var warehouse models.Warehouse
+	if err := config.DB.First(&warehouse, warehouseID).Error; err != nil {
+		return nil, newHTTPError(http.StatusBadRequest, "Pickup warehouse not found")
+	}
+	return &warehouse, nil
+}
+
+// shipmentParcel fills parcel details the admin didn't give from per-item defaults
+func shipmentParcel(order *models.Order, req *CreateShipmentRequest) shipping.Parcel {
+	units := 0
+	for _, item := range order.Items {
+		units += item.Quantity
+	}
+	if units == 0 {
+		units = 1
+	}
+
+	parcel := shipping.Parcel{
+		WeightKg: req.WeightKg,
+		LengthCm: req.LengthCm,
+		WidthCm:  req.WidthCm,
+		HeightCm: req.HeightCm,
+	}
+	if parcel.WeightKg == 0 {
+		parcel.WeightKg = math.Round(float64(units)*config.GetEnvFloat("SHIPMENT_ITEM_WEIGHT_KG", 0.6)*100) / 100
+	}
+	if parcel.LengthCm == 0 {
+		parcel.LengthCm = 35
+	}
+	if parcel.WidthCm == 0 {
+		parcel.WidthCm = 25
+	}
+	if parcel.HeightCm == 0 {
+		parcel.HeightCm = float64(4 * units)
+	}
+	return parcel
+}
+
+// orderDeliveryAddress reads the address snapshot stored on the order
+func orderDeliveryAddress(order *models.Order) shipping.Address {
+	field := func(key string) string {
+		if v, ok := order.ShippingAddress[key].(string); ok {
+			return v
+		}
+		return ""
+	}
+
+	return shipping.Address{
+		Name:         field("full_name"),
+		Phone:        field("phone"),
+		AddressLine1: field("address_line1"),
+		AddressLine2: field("address_line2"),
+		City:         field("district"),
+		State:        field("state"),
+		PinCode:      field("pin_code"),
+		Country:      field("country"),
+	}
+}
+
+func warehouseAddress(warehouse *models.Warehouse) shipping.Address {
+	return shipping.Address{
+		Name:         warehouse.Name,
+		Phone:        warehouse.Phone,
+		AddressLine1: warehouse.Address,
+		City:         warehouse.City,
+		State:        warehouse.State,
+		PinCode:      warehouse.PinCode,
+		Country:      "India",
+	}
+}
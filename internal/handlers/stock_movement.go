@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/nilabhsubramaniam/kapas/internal/config"
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+	"github.com/nilabhsubramaniam/kapas/internal/utils"
+)
+
+// ReconcileStockRequest represents a request to post correcting ledger entries
+type ReconcileStockRequest struct {
+	ProductID   uint   `json:"product_id" example:"1"`   // Limit to one product
+	WarehouseID uint   `json:"warehouse_id" example:"1"` // Limit to one warehouse
+	Reason      string `json:"reason" example:"Opening balance before the stock ledger"`
+}
+
+// StockAuditLine compares on-hand stock with the stock recomputed from the ledger
+type StockAuditLine struct {
+	ProductID      uint `json:"product_id"`
+	WarehouseID    uint `json:"warehouse_id"`
+	Quantity       int  `json:"quantity"`        // Inventory.Quantity
+	LedgerQuantity int  `json:"ledger_quantity"` // Sum of stock movements
+	Drift          int  `json:"drift"`           // Quantity - LedgerQuantity
+}
+
+// ListStockMovements godoc
+// @Summary List stock movements
+// @Description Query the stock movement ledger (Admin only)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(50)
+// @Param product_id query int false "Filter by product"
+// @Param warehouse_id query int false "Filter by warehouse"
+// @Param type query string false "Filter by type (receipt, sale, return, adjustment, transfer)"
+// @Param reference_type query string false "Filter by reference type (order, return, transfer)"
+// @Param reference_id query int false "Filter by reference ID"
+// @Param from query string false "Created on or after (YYYY-MM-DD)"
+// @Param to query string false "Created before the end of (YYYY-MM-DD)"
+// @Success 200 {object} map[string]interface{} "Stock movements"
+// @Router /admin/stock-movements [get]
+func ListStockMovements(c *gin.Context) {
+	pagination := utils.GetPaginationParams(c)
+
+	var movements []models.StockMovement
+	var total int64
+
+	query := config.DB.Model(&models.StockMovement{})
+	if productID := c.Query("product_id"); productID != "" {
+		query = query.Where("product_id = ?", productID)
+	}
+	if warehouseID := c.Query("warehouse_id"); warehouseID != "" {
+		query = query.Where("warehouse_id = ?", warehouseID)
+	}
+	if movementType := c.Query("type"); movementType != "" {
+		query = query.Where("type = ?", movementType)
+	}
+	if referenceType := c.Query("reference_type"); referenceType != "" {
+		query = query.Where("reference_type = ?", referenceType)
+	}
+	if referenceID := c.Query("reference_id"); referenceID != "" {
+		query = query.Where("reference_id = ?", referenceID)
+	}
+	if from := c.Query("from"); from != "" {
+		query = query.Where("created_at >= ?", from)
+	}
+	if to := c.Query("to"); to != "" {
+		query = query.Where("created_at < ?::date + INTERVAL '1 day'", to)
+	}
+
+	query.Count(&total)
+
+	query.Preload("Product", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, name, slug")
+	}).
+		Preload("Warehouse").
+		Order("created_at DESC, id DESC").
+		Limit(pagination.PerPage).
+		Offset(pagination.Offset).
+		Find(&movements)
+
+	c.JSON(http.StatusOK, utils.PaginatedResponse(movements, total, pagination.Page, pagination.PerPage))
+}
+
+// AuditStock godoc
+// @Summary Audit stock against the ledger
+// @Description Recompute on-hand stock from the movement ledger and compare it with inventory (Admin only)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param product_id query int false "Filter by product"
+// @Param warehouse_id query int false "Filter by warehouse"
+// @Param drift_only query bool false "Only return rows where inventory and ledger disagree"
+// @Success 200 {object} map[string]interface{} "Stock audit"
+// @Router /admin/stock-movements/audit [get]
+func AuditStock(c *gin.Context) {
+	productID, _ := strconv.ParseUint(c.Query("product_id"), 10, 64)
+	warehouseID, _ := strconv.ParseUint(c.Query("warehouse_id"), 10, 64)
+
+	query := stockAuditQuery(config.DB, uint(productID), uint(warehouseID))
+	if c.Query("drift_only") == "true" {
+		query = query.Having("inventory.quantity <> COALESCE(SUM(stock_movements.quantity), 0)")
+	}
+
+	var lines []StockAuditLine
+	if err := query.Scan(&lines).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to audit stock"})
+		return
+	}
+
+	mismatched := 0
+	for _, line := range lines {
+		if line.Drift != 0 {
+			mismatched++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"lines":      lines,
+		"checked":    len(lines),
+		"mismatched": mismatched,
+	})
+}
+
+// ReconcileStock godoc
+// @Summary Reconcile stock ledger
+// @Description Post adjustment movements so the ledger matches on-hand stock, e.g. opening balances for stock that predates the ledger (Admin only)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ReconcileStockRequest false "Scope and reason"
+// @Success 200 {object} map[string]interface{} "Ledger reconciled"
+// @Router /admin/stock-movements/reconcile [post]
+func ReconcileStock(c *gin.Context) {
+	adminID, _ := currentUserID(c)
+
+	var req ReconcileStockRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Reason == "" {
+		req.Reason = "Ledger reconciliation"
+	}
+
+	var adjusted []StockAuditLine
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Hold inventory steady while the drift is measured and booked
+		lockQuery := tx.Model(&models.Inventory{}).Clauses(clause.Locking{Strength: "UPDATE"})
+		if req.ProductID != 0 {
+			lockQuery = lockQuery.Where("product_id = ?", req.ProductID)
+		}
+		if req.WarehouseID != 0 {
+			lockQuery = lockQuery.Where("warehouse_id = ?", req.WarehouseID)
+		}
+		var ids []uint
+		if err := lockQuery.Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+
+		if err := stockAuditQuery(tx, req.ProductID, req.WarehouseID).
+			Having("inventory.quantity <> COALESCE(SUM(stock_movements.quantity), 0)").
+			Scan(&adjusted).Error; err != nil {
+			return err
+		}
+
+		for _, line := range adjusted {
+			if err := recordStockMovement(tx, models.StockMovement{
+				ProductID:   line.ProductID,
+				WarehouseID: line.WarehouseID,
+				Type:        models.StockMovementAdjustment,
+				Quantity:    line.Drift,
+				Reason:      req.Reason,
+				ActorID:     adminID,
+			}); err != nil {
+				return err
+			}
+		}
+
+		return recordAudit(tx, c, auditEntry{
+			Action:      "STOCK_RECONCILED",
+			EntityType:  "stock_movement",
+			Description: fmt.Sprintf("Reconciled the stock ledger: %d rows adjusted", len(adjusted)),
+			Extra: map[string]interface{}{
+				"product_id":   req.ProductID,
+				"warehouse_id": req.WarehouseID,
+				"reason":       req.Reason,
+				"adjusted":     adjusted,
+			},
+		})
+	})
+	if err != nil {
+		respondError(c, err, "Failed to reconcile stock ledger")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Ledger reconciled",
+		"adjusted": adjusted,
+	})
+}
+
+// stockAuditQuery builds the per-row comparison of inventory and ledger totals;
+// zero IDs are not filtered on
+func stockAuditQuery(db *gorm.DB, productID, warehouseID uint) *gorm.DB {
+	query := db.Table("inventory").
+		Select(`inventory.product_id, inventory.warehouse_id, inventory.quantity,
+			COALESCE(SUM(stock_movements.quantity), 0) AS ledger_quantity,
+			inventory.quantity - COALESCE(SUM(stock_movements.quantity), 0) AS drift`).
+		Joins(`LEFT JOIN stock_movements ON stock_movements.product_id = inventory.product_id
+			AND stock_movements.warehouse_id = inventory.warehouse_id`).
+		Where("inventory.deleted_at IS NULL")
+
+	if productID != 0 {
+		query = query.Where("inventory.product_id = ?", productID)
+	}
+	if warehouseID != 0 {
+		query = query.Where("inventory.warehouse_id = ?", warehouseID)
+	}
+
+	return query.
+		Group("inventory.id, inventory.product_id, inventory.warehouse_id, inventory.quantity").
+		Order("inventory.product_id ASC, inventory.warehouse_id ASC")
+}
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/nilabhsubramaniam/kapas/internal/config"
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+	"github.com/nilabhsubramaniam/kapas/internal/shipping"
+)
+
+// trackingBatchSize is how many AWBs are polled per courier call
+const trackingBatchSize = 50
+
+// shipmentProgress orders shipment statuses so late or replayed scans
+// never move a shipment backwards
+var shipmentProgress = map[models.ShipmentStatus]int{
+	models.ShipmentStatusPending:        0,
+	models.ShipmentStatusPickedUp:       1,
+	models.ShipmentStatusInTransit:      2,
+	models.ShipmentStatusOutForDelivery: 3,
+	models.ShipmentStatusFailed:         3, // Failed attempts alternate with out for delivery
+	models.ShipmentStatusDelivered:      4,
+	models.ShipmentStatusReturned:       4,
+}
+
+// CourierWebhook godoc
+// @Summary Courier tracking webhook
+// @Description Receive tracking updates pushed by a courier; the path takes the logistics provider code
+// @Tags Shipping
+// @Accept json
+// @Produce json
+// @Param code path string true "Logistics provider code (e.g. shiprocket, fake)"
+// @Success 200 {object} map[string]interface{} "Updates processed"
+// @Failure 400 {object} ErrorResponse "Invalid payload"
+// @Failure 401 {object} ErrorResponse "Invalid signature"
+// @Failure 404 {object} ErrorResponse "Courier does not send webhooks"
+// @Router /shipping/webhook/{code} [post]
+func CourierWebhook(c *gin.Context) {
+	var provider models.LogisticsProvider
+	if err := config.DB.Where("code = ?", c.Param("code")).First(&provider).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown courier"})
+		return
+	}
+
+	courier, err := shipping.For(provider)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown courier"})
+		return
+	}
+	receiver, ok := courier.(shipping.WebhookReceiver)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Courier does not send webhooks"})
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read body"})
+		return
+	}
+
+	updates, err := receiver.ParseWebhook(c.Request.Header, body)
+	if err != nil {
+		if errors.Is(err, shipping.ErrInvalidSignature) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook payload"})
+		return
+	}
+
+	recorded, err := applyTrackingUpdates(&provider, updates)
+	if err != nil {
+		log.Printf("Failed to apply %s tracking webhook: %v", provider.Code, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process tracking update"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Tracking updates processed",
+		"received": len(updates),
+		"recorded": recorded,
+	})
+}
+
+// applyTrackingUpdates records courier scans, oldest first, and moves
+// shipments and their orders forward; it returns how many new events were stored
+func applyTrackingUpdates(provider *models.LogisticsProvider, updates []shipping.TrackingUpdate) (int, error) {
+	sort.SliceStable(updates, func(i, j int) bool {
+		return updates[i].EventTime.Before(updates[j].EventTime)
+	})
+
+	recorded := 0
+	for _, update := range updates {
+		if update.AWBNumber == "" {
+			continue
+		}
+
+		var stored bool
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			var err error
+			stored, err = applyTrackingUpdate(tx, provider, update)
+			return err
+		})
+		if err != nil {
+			return recorded, fmt.Errorf("AWB %s: %w", update.AWBNumber, err)
+		}
+		if stored {
+			recorded++
+		}
+	}
+	return recorded, nil
+}
+
+// applyTrackingUpdate stores one scan and applies its status within tx;
+// duplicates and scans for unknown AWBs are skipped
+func applyTrackingUpdate(tx *gorm.DB, provider *models.LogisticsProvider, update shipping.TrackingUpdate) (bool, error) {
+	var shipment models.Shipment
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("awb_number = ? AND provider_id = ?", update.AWBNumber, provider.ID).
+		First(&shipment).Error
+	if err == gorm.ErrRecordNotFound {
+		log.Printf("Tracking update for unknown %s AWB %s", provider.Code, update.AWBNumber)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	status := update.RawStatus
+	if status == "" {
+		status = string(update.Status)
+	}
+	event := models.TrackingEvent{
+		ShipmentID:  shipment.ID,
+		Status:      status,
+		Location:    update.Location,
+		Description: update.Description,
+		EventTime:   update.EventTime,
+	}
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&event)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	next, known := shipmentProgress[update.Status]
+	if !known || update.Status == shipment.Status ||
+		next < shipmentProgress[shipment.Status] ||
+		shipment.Status == models.ShipmentStatusDelivered || shipment.Status == models.ShipmentStatusReturned {
+		return true, nil
+	}
+
+	// Move the order first: shipOrder and deliverOrder also stamp the shipment
+	if err := advanceOrderForShipment(tx, &shipment, update); err != nil {
+		return false, err
+	}
+
+	updates := map[string]interface{}{"status": update.Status}
+	switch update.Status {
+	case models.ShipmentStatusPickedUp:
+		updates["pickup_date"] = update.EventTime
+	case models.ShipmentStatusDelivered:
+		updates["actual_delivery"] = update.EventTime
+	}
+	return true, tx.Model(&shipment).Updates(updates).Error
+}
+
+// advanceOrderForShipment moves the shipment's order to shipped, delivered
+// or returned to match the courier status
+func advanceOrderForShipment(tx *gorm.DB, shipment *models.Shipment, update shipping.TrackingUpdate) error {
+	var order models.Order
+	if err := lockOrder(tx, &order, "id = ?", shipment.OrderID); err != nil {
+		return err
+	}
+
+	comment := fmt.Sprintf("Courier update: %s", update.RawStatus)
+	if update.Location != "" {
+		comment += " at " + update.Location
+	}
+
+	var targets []models.OrderStatus
+	switch update.Status {
+	case models.ShipmentStatusPickedUp, models.ShipmentStatusInTransit, models.ShipmentStatusOutForDelivery:
+		targets = []models.OrderStatus{models.OrderStatusShipped}
+	case models.ShipmentStatusDelivered:
+		targets = []models.OrderStatus{models.OrderStatusShipped, models.OrderStatusDelivered}
+	case models.ShipmentStatusReturned:
+		targets = []models.OrderStatus{models.OrderStatusShipped, models.OrderStatusReturned}
+	}
+
+	for _, to := range targets {
+		if order.Status == to || !order.Status.CanTransitionTo(to) {
+			continue
+		}
+		if err := transitionOrder(tx, &order, orderTransition{To: to, Comment: comment}); err != nil {
+			return err
+		}
+		order.Status = to
+	}
+	return nil
+}
+
+// PollShipmentTracking pulls tracking for open shipments from couriers that
+// can be polled but don't push webhooks
+func PollShipmentTracking() {
+	var shipments []models.Shipment
+	if err := config.DB.Preload("Provider").
+		Where("status NOT IN ?", []models.ShipmentStatus{models.ShipmentStatusDelivered, models.ShipmentStatusReturned}).
+		Find(&shipments).Error; err != nil {
+		log.Printf("Failed to load shipments for tracking: %v", err)
+		return
+	}
+
+	byProvider := map[uint][]models.Shipment{}
+	for _, shipment := range shipments {
+		byProvider[shipment.ProviderID] = append(byProvider[shipment.ProviderID], shipment)
+	}
+
+	for _, group := range byProvider {
+		provider := group[0].Provider
+
+		courier, err := shipping.For(provider)
+		if err != nil {
+			continue
+		}
+		tracker, ok := courier.(shipping.Tracker)
+		if !ok {
+			continue
+		}
+		if _, pushes := courier.(shipping.WebhookReceiver); pushes {
+			continue
+		}
+
+		for start := 0; start < len(group); start += trackingBatchSize {
+			end := start + trackingBatchSize
+			if end > len(group) {
+				end = len(group)
+			}
+			awbs := make([]string, 0, end-start)
+			for _, shipment := range group[start:end] {
+				awbs = append(awbs, shipment.AWBNumber)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			updates, err := tracker.Track(ctx, awbs)
+			cancel()
+			if err != nil {
+				log.Printf("Failed to poll %s tracking: %v", provider.Code, err)
+				continue
+			}
+
+			if _, err := applyTrackingUpdates(&provider, updates); err != nil {
+				log.Printf("Failed to apply %s tracking: %v", provider.Code, err)
+			}
+		}
+	}
+}
+
+// RunTrackingPoller runs PollShipmentTracking every interval; call in a goroutine
+func RunTrackingPoller(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		PollShipmentTracking()
+	}
+}
@@ -21,7 +21,10 @@ type ProductResponse struct {
 	Slug               string                 `json:"slug" example:"lucknow-white-chikankari-cotton-saree"`
 	Description        string                 `json:"description" example:"Beautiful handcrafted Chikankari saree"`
 	ProductType        string                 `json:"product_type" example:"SAREE"`
-	StateOrigin        string                 `json:"state_origin" example:"UP"`
+	RegionID           *uint                  `json:"region_id" example:"1"`
+	VendorID           *uint                  `json:"vendor_id" example:"3"`
+	Region             *RegionResponse        `json:"region,omitempty"`
+	StateOrigin        string                 `json:"state_origin,omitempty" example:"UP"` // Deprecated: the region's state code
 	SareeType          string                 `json:"saree_type" example:"Chikankari"`
 	BasePrice          float64                `json:"base_price" example:"4999.00"`
 	DiscountPercentage float64                `json:"discount_percentage" example:"20.00"`
@@ -35,6 +38,18 @@ type ProductResponse struct {
 	CreatedAt          time.Time              `json:"created_at" example:"2024-01-01T00:00:00Z"`
 }
 
+// RegionResponse represents a craft region in API responses
+type RegionResponse struct {
+	ID           uint   `json:"id" example:"1"`
+	Name         string `json:"name" example:"Lucknow"`
+	Slug         string `json:"slug" example:"lucknow"`
+	Type         string `json:"type" example:"City"`
+	StateID      *uint  `json:"state_id" example:"1"`
+	FamousFor    string `json:"famous_for" example:"Chikankari Sarees, Chikankari Kurtis"`
+	ImageURL     string `json:"image_url" example:"https://cdn.example.com/regions/lucknow.jpg"`
+	DisplayOrder int    `json:"display_order" example:"1"`
+}
+
 // PaginatedProductsResponse represents paginated products response
 type PaginatedProductsResponse struct {
 	Data       []ProductResponse `json:"data"`
@@ -49,18 +64,25 @@ type PaginationMeta struct {
 	TotalPages int   `json:"total_pages" example:"5"`
 }
 
+// TokenResponse represents an access token and refresh token pair
+type TokenResponse struct {
+	Token        string `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	RefreshToken string `json:"refresh_token" example:"q3Jx0m4y...7TfA"`
+	ExpiresIn    int    `json:"expires_in" example:"900"` // Access token lifetime in seconds
+}
+
 // LoginResponse represents login response
 type LoginResponse struct {
 	Message string       `json:"message" example:"Login successful"`
 	User    UserResponse `json:"user"`
-	Token   string       `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	TokenResponse
 }
 
 // RegisterResponse represents registration response
 type RegisterResponse struct {
 	Message string       `json:"message" example:"User registered successfully"`
 	User    UserResponse `json:"user"`
-	Token   string       `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	TokenResponse
 }
 
 // ErrorResponse represents error response
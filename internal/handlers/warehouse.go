@@ -0,0 +1,526 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/nilabhsubramaniam/kapas/internal/config"
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+	"github.com/nilabhsubramaniam/kapas/internal/utils"
+)
+
+// WarehouseRequest represents admin warehouse create/update request
+type WarehouseRequest struct {
+	Name     string `json:"name" binding:"required" example:"Lucknow Fulfilment Centre"`
+	Code     string `json:"code" binding:"required,max=20" example:"LKO1"`
+	Address  string `json:"address" binding:"required" example:"Plot 12, Transport Nagar"`
+	City     string `json:"city" binding:"required" example:"Lucknow"`
+	State    string `json:"state" binding:"required" example:"Uttar Pradesh"`
+	PinCode  string `json:"pin_code" binding:"required,numeric,len=6" example:"226012"`
+	Phone    string `json:"phone" example:"+915224000000"`
+	Email    string `json:"email" binding:"omitempty,email" example:"lko1@tantuka.com"`
+	IsActive *bool  `json:"is_active" example:"true"`
+}
+
+// StockTransferRequest represents a stock move between warehouses
+type StockTransferRequest struct {
+	ProductID       uint   `json:"product_id" binding:"required" example:"1"`
+	FromWarehouseID uint   `json:"from_warehouse_id" binding:"required" example:"1"`
+	ToWarehouseID   uint   `json:"to_warehouse_id" binding:"required" example:"2"`
+	Quantity        int    `json:"quantity" binding:"required,min=1" example:"20"`
+	Notes           string `json:"notes" example:"Stocking the Varanasi hub for Diwali"`
+}
+
+// ============================================
+// ADMIN WAREHOUSE MANAGEMENT
+// ============================================
+
+// ListWarehouses godoc
+// @Summary List warehouses
+// @Description Get all warehouses (Admin only)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param is_active query bool false "Filter by active flag"
+// @Success 200 {object} map[string]interface{} "Warehouse list"
+// @Router /admin/warehouses [get]
+func ListWarehouses(c *gin.Context) {
+	var warehouses []models.Warehouse
+
+	query := config.DB.Model(&models.Warehouse{})
+	if isActive := c.Query("is_active"); isActive != "" {
+		query = query.Where("is_active = ?", isActive == "true")
+	}
+
+	if err := query.Order("id ASC").Find(&warehouses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch warehouses"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"warehouses": warehouses})
+}
+
+// GetWarehouse godoc
+// @Summary Get warehouse
+// @Description Get a warehouse with its stock totals (Admin only)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Warehouse ID"
+// @Success 200 {object} map[string]interface{} "Warehouse details"
+// @Failure 404 {object} ErrorResponse "Warehouse not found"
+// @Router /admin/warehouses/{id} [get]
+func GetWarehouse(c *gin.Context) {
+	var warehouse models.Warehouse
+	if err := config.DB.First(&warehouse, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Warehouse not found"})
+		return
+	}
+
+	var totals struct {
+		Products int64 `json:"products"`
+		Quantity int64 `json:"quantity"`
+		Reserved int64 `json:"reserved"`
+	}
+	config.DB.Model(&models.Inventory{}).
+		Select("COUNT(*) AS products, COALESCE(SUM(quantity), 0) AS quantity, COALESCE(SUM(reserved_quantity), 0) AS reserved").
+		Where("warehouse_id = ?", warehouse.ID).
+		Scan(&totals)
+
+	c.JSON(http.StatusOK, gin.H{
+		"warehouse": warehouse,
+		"stock":     totals,
+	})
+}
+
+// CreateWarehouse godoc
+// @Summary Create warehouse
+// @Description Add a fulfilment warehouse (Admin only)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body WarehouseRequest true "Warehouse details"
+// @Success 201 {object} map[string]interface{} "Warehouse created successfully"
+// @Failure 409 {object} ErrorResponse "Warehouse code already exists"
+// @Router /admin/warehouses [post]
+func CreateWarehouse(c *gin.Context) {
+	var req WarehouseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var existing models.Warehouse
+	if err := config.DB.Unscoped().Where("code = ?", normalizeWarehouseCode(req.Code)).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Warehouse code already exists"})
+		return
+	}
+
+	warehouse := models.Warehouse{IsActive: true}
+	applyWarehouseRequest(&warehouse, &req)
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&warehouse).Error; err != nil {
+			return err
+		}
+
+		// GORM skips false for columns with default:true on insert
+		if !warehouse.IsActive {
+			if err := tx.Model(&warehouse).Update("is_active", false).Error; err != nil {
+				return err
+			}
+		}
+
+		return recordAudit(tx, c, auditEntry{
+			Action:      "WAREHOUSE_CREATED",
+			EntityType:  "warehouse",
+			EntityID:    warehouse.ID,
+			Description: "Created warehouse " + warehouse.Code,
+			After:       warehouse,
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create warehouse"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "Warehouse created successfully",
+		"warehouse": warehouse,
+	})
+}
+
+// UpdateWarehouse godoc
+// @Summary Update warehouse
+// @Description Update a warehouse; deactivating it removes its stock from sale (Admin only)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Warehouse ID"
+// @Param request body WarehouseRequest true "Warehouse details"
+// @Success 200 {object} map[string]interface{} "Warehouse updated successfully"
+// @Failure 404 {object} ErrorResponse "Warehouse not found"
+// @Failure 409 {object} ErrorResponse "Warehouse code already exists"
+// @Router /admin/warehouses/{id} [put]
+func UpdateWarehouse(c *gin.Context) {
+	var req WarehouseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var warehouse models.Warehouse
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&warehouse, c.Param("id")).Error; err != nil {
+			return newHTTPError(http.StatusNotFound, "Warehouse not found")
+		}
+
+		var existing models.Warehouse
+		if err := tx.Unscoped().
+			Where("code = ? AND id <> ?", normalizeWarehouseCode(req.Code), warehouse.ID).
+			First(&existing).Error; err == nil {
+			return newHTTPError(http.StatusConflict, "Warehouse code already exists")
+		}
+
+		before := auditSnapshot(warehouse)
+		wasActive := warehouse.IsActive
+		applyWarehouseRequest(&warehouse, &req)
+
+		if err := tx.Save(&warehouse).Error; err != nil {
+			return err
+		}
+
+		if warehouse.IsActive != wasActive {
+			if err := syncWarehouseProducts(tx, warehouse.ID); err != nil {
+				return err
+			}
+		}
+
+		return recordAudit(tx, c, auditEntry{
+			Action:      "WAREHOUSE_UPDATED",
+			EntityType:  "warehouse",
+			EntityID:    warehouse.ID,
+			Description: "Updated warehouse " + warehouse.Code,
+			Before:      before,
+			After:       warehouse,
+		})
+	})
+	if err != nil {
+		respondError(c, err, "Failed to update warehouse")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Warehouse updated successfully",
+		"warehouse": warehouse,
+	})
+}
+
+// DeleteWarehouse godoc
+// @Summary Delete warehouse
+// @Description Delete an empty warehouse; transfer its stock out first (Admin only)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Warehouse ID"
+// @Success 200 {object} map[string]interface{} "Warehouse deleted successfully"
+// @Failure 404 {object} ErrorResponse "Warehouse not found"
+// @Failure 409 {object} ErrorResponse "Warehouse still holds stock"
+// @Router /admin/warehouses/{id} [delete]
+func DeleteWarehouse(c *gin.Context) {
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var warehouse models.Warehouse
+		if err := tx.First(&warehouse, c.Param("id")).Error; err != nil {
+			return newHTTPError(http.StatusNotFound, "Warehouse not found")
+		}
+
+		var stocked int64
+		if err := tx.Model(&models.Inventory{}).
+			Where("warehouse_id = ? AND (quantity > 0 OR reserved_quantity > 0)", warehouse.ID).
+			Count(&stocked).Error; err != nil {
+			return err
+		}
+		if stocked > 0 {
+			return newHTTPError(http.StatusConflict,
+				fmt.Sprintf("Warehouse still holds stock for %d products; transfer it out first", stocked))
+		}
+
+		if err := tx.Delete(&warehouse).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, auditEntry{
+			Action:      "WAREHOUSE_DELETED",
+			EntityType:  "warehouse",
+			EntityID:    warehouse.ID,
+			Description: "Deleted warehouse " + warehouse.Code,
+			Before:      warehouse,
+		})
+	})
+	if err != nil {
+		respondError(c, err, "Failed to delete warehouse")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Warehouse deleted successfully"})
+}
+
+// GetWarehouseStock godoc
+// @Summary Get warehouse stock
+// @Description Get stock levels for every product held in a warehouse (Admin only)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Warehouse ID"
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(50)
+// @Param low_stock query bool false "Show only rows at or below their low stock threshold"
+// @Success 200 {object} map[string]interface{} "Warehouse stock"
+// @Failure 404 {object} ErrorResponse "Warehouse not found"
+// @Router /admin/warehouses/{id}/stock [get]
+func GetWarehouseStock(c *gin.Context) {
+	var warehouse models.Warehouse
+	if err := config.DB.First(&warehouse, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Warehouse not found"})
+		return
+	}
+
+	pagination := utils.GetPaginationParams(c)
+
+	var rows []models.Inventory
+	var total int64
+
+	query := config.DB.Model(&models.Inventory{}).Where("warehouse_id = ?", warehouse.ID)
+	if c.Query("low_stock") == "true" {
+		query = query.Where("quantity - reserved_quantity <= low_stock_threshold")
+	}
+
+	query.Count(&total)
+
+	query.Preload("Product", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, name, slug, product_type, stock_quantity, final_price, is_active")
+	}).
+		Order("product_id ASC").
+		Limit(pagination.PerPage).
+		Offset(pagination.Offset).
+		Find(&rows)
+
+	response := utils.PaginatedResponse(rows, total, pagination.Page, pagination.PerPage)
+	response["warehouse"] = warehouse
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ============================================
+// STOCK TRANSFERS
+// ============================================
+
+// TransferStock godoc
+// @Summary Transfer stock
+// @Description Move on-hand stock of a product from one warehouse to another (Admin only)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body StockTransferRequest true "Transfer details"
+// @Success 201 {object} map[string]interface{} "Stock transferred successfully"
+// @Failure 400 {object} ErrorResponse "Invalid transfer"
+// @Failure 404 {object} ErrorResponse "Product or warehouse not found"
+// @Failure 409 {object} ErrorResponse "Insufficient stock"
+// @Router /admin/stock-transfers [post]
+func TransferStock(c *gin.Context) {
+	adminID, _ := currentUserID(c)
+
+	var req StockTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.FromWarehouseID == req.ToWarehouseID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Source and destination warehouses must differ"})
+		return
+	}
+
+	var product models.Product
+	if err := config.DB.First(&product, req.ProductID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	var transfer models.StockTransfer
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var from, to models.Warehouse
+		if err := tx.First(&from, req.FromWarehouseID).Error; err != nil {
+			return newHTTPError(http.StatusNotFound, "Source warehouse not found")
+		}
+		if err := tx.First(&to, req.ToWarehouseID).Error; err != nil {
+			return newHTTPError(http.StatusNotFound, "Destination warehouse not found")
+		}
+		if !to.IsActive {
+			return newHTTPError(http.StatusBadRequest, "Destination warehouse is inactive")
+		}
+
+		// Lock in warehouse ID order so concurrent opposite transfers can't deadlock
+		var source, destination models.Inventory
+		first, second := &source, &destination
+		firstID, secondID := from.ID, to.ID
+		if firstID > secondID {
+			first, second = second, first
+			firstID, secondID = secondID, firstID
+		}
+		if err := lockInventoryRow(tx, first, product.ID, firstID); err != nil {
+			return err
+		}
+		if err := lockInventoryRow(tx, second, product.ID, secondID); err != nil {
+			return err
+		}
+
+		if source.Available() < req.Quantity {
+			return newHTTPError(http.StatusConflict,
+				fmt.Sprintf("Only %d units available to transfer from %s", source.Available(), from.Code))
+		}
+
+		if err := tx.Model(&source).Update("quantity", gorm.Expr("quantity - ?", req.Quantity)).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&destination).Update("quantity", gorm.Expr("quantity + ?", req.Quantity)).Error; err != nil {
+			return err
+		}
+
+		transfer = models.StockTransfer{
+			ProductID:       product.ID,
+			FromWarehouseID: from.ID,
+			ToWarehouseID:   to.ID,
+			Quantity:        req.Quantity,
+			Notes:           req.Notes,
+			CreatedBy:       adminID,
+		}
+		if err := tx.Create(&transfer).Error; err != nil {
+			return err
+		}
+
+		for _, leg := range []struct {
+			warehouseID uint
+			quantity    int
+		}{{from.ID, -req.Quantity}, {to.ID, req.Quantity}} {
+			if err := recordStockMovement(tx, models.StockMovement{
+				ProductID:     product.ID,
+				WarehouseID:   leg.warehouseID,
+				Type:          models.StockMovementTransfer,
+				Quantity:      leg.quantity,
+				ReferenceType: models.StockReferenceTransfer,
+				ReferenceID:   transfer.ID,
+				Reason:        req.Notes,
+				ActorID:       adminID,
+			}); err != nil {
+				return err
+			}
+		}
+
+		if err := recordAudit(tx, c, auditEntry{
+			Action:      "STOCK_TRANSFERRED",
+			EntityType:  "stock_transfer",
+			EntityID:    transfer.ID,
+			Description: fmt.Sprintf("Moved %d x %s from %s to %s", req.Quantity, product.Name, from.Code, to.Code),
+			After:       transfer,
+			Extra: map[string]interface{}{
+				"product_id":        product.ID,
+				"from_warehouse_id": from.ID,
+				"to_warehouse_id":   to.ID,
+				"quantity":          req.Quantity,
+				"notes":             req.Notes,
+			},
+		}); err != nil {
+			return err
+		}
+
+		// Totals only change when one side is inactive, but keep the cache exact
+		return syncProductStock(tx, product.ID)
+	})
+	if err != nil {
+		respondError(c, err, "Failed to transfer stock")
+		return
+	}
+
+	config.DB.Preload("FromWarehouse").Preload("ToWarehouse").First(&transfer, transfer.ID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Stock transferred successfully",
+		"transfer": transfer,
+	})
+}
+
+// ListStockTransfers godoc
+// @Summary List stock transfers
+// @Description Get the history of stock moved between warehouses (Admin only)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(50)
+// @Param product_id query int false "Filter by product"
+// @Param warehouse_id query int false "Filter by source or destination warehouse"
+// @Success 200 {object} map[string]interface{} "Transfer list"
+// @Router /admin/stock-transfers [get]
+func ListStockTransfers(c *gin.Context) {
+	pagination := utils.GetPaginationParams(c)
+
+	var transfers []models.StockTransfer
+	var total int64
+
+	query := config.DB.Model(&models.StockTransfer{})
+	if productID := c.Query("product_id"); productID != "" {
+		query = query.Where("product_id = ?", productID)
+	}
+	if warehouseID := c.Query("warehouse_id"); warehouseID != "" {
+		query = query.Where("from_warehouse_id = ? OR to_warehouse_id = ?", warehouseID, warehouseID)
+	}
+
+	query.Count(&total)
+
+	query.Preload("Product", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, name, slug")
+	}).
+		Preload("FromWarehouse").
+		Preload("ToWarehouse").
+		Order("created_at DESC").
+		Limit(pagination.PerPage).
+		Offset(pagination.Offset).
+		Find(&transfers)
+
+	c.JSON(http.StatusOK, utils.PaginatedResponse(transfers, total, pagination.Page, pagination.PerPage))
+}
+
+// syncWarehouseProducts refreshes cached stock for every product held in a warehouse
+func syncWarehouseProducts(tx *gorm.DB, warehouseID uint) error {
+	var productIDs []uint
+	if err := tx.Model(&models.Inventory{}).
+		Where("warehouse_id = ?", warehouseID).
+		Pluck("product_id", &productIDs).Error; err != nil {
+		return err
+	}
+	return syncProductStock(tx, productIDs...)
+}
+
+func normalizeWarehouseCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func applyWarehouseRequest(warehouse *models.Warehouse, req *WarehouseRequest) {
+	warehouse.Name = req.Name
+	warehouse.Code = normalizeWarehouseCode(req.Code)
+	warehouse.Address = req.Address
+	warehouse.City = req.City
+	warehouse.State = req.State
+	warehouse.PinCode = req.PinCode
+	warehouse.Phone = req.Phone
+	warehouse.Email = req.Email
+	if req.IsActive != nil {
+		warehouse.IsActive = *req.IsActive
+	}
+}
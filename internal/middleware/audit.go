@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nilabhsubramaniam/kapas/internal/config"
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+)
+
+// AuditRecordedKey is set on the context by handlers that wrote their own,
+// more detailed audit entry
+const AuditRecordedKey = "audit_recorded"
+
+// AuditWrites records every successful write request in the activity log
+// unless the handler already did. Use after AuthMiddleware.
+func AuditWrites() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return
+		}
+		if c.GetBool(AuditRecordedKey) || c.Writer.Status() >= http.StatusBadRequest {
+			return
+		}
+
+		userID, _ := c.Get("user_id")
+		actorID, _ := userID.(uint)
+		entityID, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+
+		entry := models.ActivityLog{
+			UserID:      actorID,
+			Action:      "ADMIN_REQUEST",
+			EntityType:  auditEntityType(c.FullPath()),
+			EntityID:    uint(entityID),
+			Description: c.Request.Method + " " + c.FullPath(),
+			IPAddress:   c.ClientIP(),
+			UserAgent:   c.Request.UserAgent(),
+			Metadata: models.JSONB{
+				"method": c.Request.Method,
+				"path":   c.Request.URL.Path,
+				"status": c.Writer.Status(),
+			},
+		}
+		if err := config.DB.Create(&entry).Error; err != nil {
+			log.Printf("Failed to audit %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+		}
+	}
+}
+
+// auditEntityType guesses the entity from a route such as
+// /api/admin/warehouses/:id, giving "warehouse"
+func auditEntityType(route string) string {
+	for _, segment := range strings.Split(route, "/") {
+		if segment == "" || segment == "api" || segment == "admin" || strings.HasPrefix(segment, ":") {
+			continue
+		}
+		segment = strings.ReplaceAll(segment, "-", "_")
+		switch {
+		case strings.HasSuffix(segment, "ies"):
+			return strings.TrimSuffix(segment, "ies") + "y"
+		case strings.HasSuffix(segment, "s"):
+			return strings.TrimSuffix(segment, "s")
+		}
+		return segment
+	}
+	return "unknown"
+}
@@ -5,6 +5,9 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/nilabhsubramaniam/kapas/internal/config"
+	"github.com/nilabhsubramaniam/kapas/internal/models"
 	"github.com/nilabhsubramaniam/kapas/internal/utils"
 )
 
@@ -33,9 +36,9 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		tokenString := parts[1]
 
-		// Validate token
+		// Validate token; tokens without an ID predate revocation and can't be trusted
 		claims, err := utils.ValidateToken(tokenString)
-		if err != nil {
+		if err != nil || claims.ID == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "Invalid or expired token",
 			})
@@ -43,15 +46,40 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Reject tokens revoked by logout or account changes
+		var revoked int64
+		if err := config.DB.Model(&models.RevokedToken{}).Where("jti = ?", claims.ID).Count(&revoked).Error; err != nil || revoked > 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Token has been revoked",
+			})
+			c.Abort()
+			return
+		}
+
 		// Set user info in context
 		c.Set("user_id", claims.UserID)
 		c.Set("user_email", claims.Email)
 		c.Set("user_role", claims.Role)
+		c.Set("session_id", claims.SessionID)
+		c.Set("token_id", claims.ID)
+		c.Set("token_expires_at", claims.ExpiresAt.Time)
 
 		c.Next()
 	}
 }
 
+// TokenFromQuery lets clients that can't set headers, such as browser
+// EventSource, pass the JWT as the access_token query parameter.
+// Use it before AuthMiddleware.
+func TokenFromQuery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := c.Query("access_token"); token != "" && c.GetHeader("Authorization") == "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+		c.Next()
+	}
+}
+
 // AdminOnly ensures only admins can access the endpoint
 func AdminOnly() gin.HandlerFunc {
 	return func(c *gin.Context) {
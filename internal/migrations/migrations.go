@@ -0,0 +1,307 @@
+// Package migrations applies the versioned SQL files in sql/ to the database
+// and records them in the schema_migrations table. Runs hold a Postgres
+// advisory lock so replicas starting together apply each migration once.
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Dir is where the migration files live in the source tree
+const Dir = "internal/migrations/sql"
+
+// lockKey identifies the advisory lock held while migrating
+const lockKey int64 = 7_426_583_104
+
+//go:embed sql/*.sql
+var files embed.FS
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+var namePattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Migration is one schema change with the SQL to apply and revert it
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status reports whether a migration has been applied
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time // Nil while pending
+	Missing   bool       // Applied, but this build has no file for it
+}
+
+// schemaMigration is a row of the schema_migrations table
+type schemaMigration struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"size:255;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// String formats the migration as its file name prefix, e.g. 0001_initial_schema
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// All returns the migrations built into the binary, oldest first
+func All() ([]Migration, error) {
+	return load(files, "sql")
+}
+
+// load reads the *.up.sql and *.down.sql pairs in dir of fsys
+func load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration %s: name must look like 0001_add_column.up.sql", entry.Name())
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		body, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d: used by both %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			return nil, fmt.Errorf("migration %s: needs a non-empty up and down file", m)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Up applies pending migrations oldest first, at most limit of them when
+// limit is positive, and returns the ones it applied
+func Up(db *gorm.DB, limit int) ([]Migration, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	err = withLock(db, func(conn *gorm.DB) error {
+		done, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range all {
+			if _, ok := done[m.Version]; ok {
+				continue
+			}
+			if limit > 0 && len(applied) == limit {
+				break
+			}
+			if err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(m.Up).Error; err != nil {
+					return err
+				}
+				return tx.Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now().UTC()}).Error
+			}); err != nil {
+				return fmt.Errorf("migration %s: %w", m, err)
+			}
+			applied = append(applied, m)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the latest steps applied migrations, newest first, and
+// returns the ones it reverted
+func Down(db *gorm.DB, steps int) ([]Migration, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[int64]Migration, len(all))
+	for _, m := range all {
+		known[m.Version] = m
+	}
+
+	var reverted []Migration
+	err = withLock(db, func(conn *gorm.DB) error {
+		var rows []schemaMigration
+		if err := conn.Order("version DESC").Limit(steps).Find(&rows).Error; err != nil {
+			return err
+		}
+		for _, row := range rows {
+			m, ok := known[row.Version]
+			if !ok {
+				return fmt.Errorf("migration %s: no down file in this build", Migration{Version: row.Version, Name: row.Name})
+			}
+			if err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(m.Down).Error; err != nil {
+					return err
+				}
+				return tx.Delete(&schemaMigration{}, "version = ?", m.Version).Error
+			}); err != nil {
+				return fmt.Errorf("migration %s: %w", m, err)
+			}
+			reverted = append(reverted, m)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// List returns every known or applied migration, oldest first
+func List(db *gorm.DB) ([]Status, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+	done, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(all))
+	for _, m := range all {
+		status := Status{Version: m.Version, Name: m.Name}
+		if row, ok := done[m.Version]; ok {
+			status.AppliedAt = &row.AppliedAt
+			delete(done, m.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, row := range done {
+		appliedAt := row.AppliedAt
+		statuses = append(statuses, Status{Version: row.Version, Name: row.Name, AppliedAt: &appliedAt, Missing: true})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Pending returns the migrations that haven't been applied yet
+func Pending(db *gorm.DB) ([]Migration, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+	done, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, m := range all {
+		if _, ok := done[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Create writes empty up and down files for a new migration to dir,
+// numbered after the newest one there, and returns their paths
+func Create(dir, name string) (string, string, error) {
+	name = strings.Trim(namePattern.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return "", "", errors.New("migration name is required")
+	}
+
+	existing, err := load(os.DirFS(dir), ".")
+	if err != nil {
+		return "", "", fmt.Errorf("read %s: %w", dir, err)
+	}
+	m := Migration{Version: 1, Name: name}
+	if len(existing) > 0 {
+		m.Version = existing[len(existing)-1].Version + 1
+	}
+
+	up := filepath.Join(dir, m.String()+".up.sql")
+	down := filepath.Join(dir, m.String()+".down.sql")
+	if err := os.WriteFile(up, []byte("-- "+name+"\n"), 0o644); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(down, []byte("-- Revert "+name+"\n"), 0o644); err != nil {
+		return "", "", err
+	}
+	return up, down, nil
+}
+
+// withLock runs fc on a single connection holding the migration advisory
+// lock, after making sure the schema_migrations table exists
+func withLock(db *gorm.DB, fc func(conn *gorm.DB) error) error {
+	return db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", lockKey).Error; err != nil {
+			return fmt.Errorf("acquire migration lock: %w", err)
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", lockKey)
+
+		if err := conn.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL
+		)`).Error; err != nil {
+			return err
+		}
+		return fc(conn)
+	})
+}
+
+// appliedVersions loads schema_migrations by version; the table not existing
+// yet means nothing has been applied
+func appliedVersions(db *gorm.DB) (map[int64]schemaMigration, error) {
+	done := map[int64]schemaMigration{}
+	if !db.Migrator().HasTable(&schemaMigration{}) {
+		return done, nil
+	}
+
+	var rows []schemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		done[row.Version] = row
+	}
+	return done, nil
+}
@@ -0,0 +1,136 @@
+package migrations
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	file := func(body string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(body)} }
+
+	tests := []struct {
+		name     string
+		files    fstest.MapFS
+		versions []int64
+		wantErr  string
+	}{
+		{
+			name: "sorted pairs",
+			files: fstest.MapFS{
+				"sql/0010_add_sku.up.sql":       file("ALTER TABLE products ADD COLUMN sku text;"),
+				"sql/0010_add_sku.down.sql":     file("ALTER TABLE products DROP COLUMN sku;"),
+				"sql/0002_add_index.up.sql":     file("CREATE INDEX a ON b (c);"),
+				"sql/0002_add_index.down.sql":   file("DROP INDEX a;"),
+				"sql/README.md":                 file("ignored"),
+				"sql/0003_nested/0003.up.sql":   file("ignored"),
+				"sql/0003_nested/0003.down.sql": file("ignored"),
+			},
+			versions: []int64{2, 10},
+		},
+		{
+			name: "bad name",
+			files: fstest.MapFS{
+				"sql/add_sku.up.sql": file("SELECT 1;"),
+			},
+			wantErr: "name must look like",
+		},
+		{
+			name: "missing down",
+			files: fstest.MapFS{
+				"sql/0001_add_sku.up.sql": file("SELECT 1;"),
+			},
+			wantErr: "needs a non-empty up and down file",
+		},
+		{
+			name: "blank up",
+			files: fstest.MapFS{
+				"sql/0001_add_sku.up.sql":   file("  \n"),
+				"sql/0001_add_sku.down.sql": file("SELECT 1;"),
+			},
+			wantErr: "needs a non-empty up and down file",
+		},
+		{
+			name: "shared version",
+			files: fstest.MapFS{
+				"sql/0001_add_sku.up.sql":     file("SELECT 1;"),
+				"sql/0001_add_sku.down.sql":   file("SELECT 1;"),
+				"sql/0001_add_index.up.sql":   file("SELECT 1;"),
+				"sql/0001_add_index.down.sql": file("SELECT 1;"),
+			},
+			wantErr: "used by both",
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := load(tt.files, "sql")
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: got error %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if len(got) != len(tt.versions) {
+			t.Errorf("%s: got %d migrations, want %d", tt.name, len(got), len(tt.versions))
+			continue
+		}
+		for i, m := range got {
+			if m.Version != tt.versions[i] || m.Up == "" || m.Down == "" {
+				t.Errorf("%s: migration %d = %+v, want version %d with both files", tt.name, i, m, tt.versions[i])
+			}
+		}
+	}
+}
+
+func TestAllEmbedded(t *testing.T) {
+	all, err := All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) == 0 || all[0].String() != "0001_initial_schema" {
+		t.Fatalf("got %v, want 0001_initial_schema first", all)
+	}
+	for i, m := range all {
+		if m.Version != int64(i+1) {
+			t.Errorf("%s: versions should run 1, 2, 3... without gaps", m)
+		}
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+
+	up, down, err := Create(dir, "Add product SKU!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(up) != "0001_add_product_sku.up.sql" || filepath.Base(down) != "0001_add_product_sku.down.sql" {
+		t.Errorf("got %s and %s", up, down)
+	}
+
+	up, _, err = Create(dir, "drop-legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(up) != "0002_drop_legacy.up.sql" {
+		t.Errorf("got %s, want the next version", up)
+	}
+
+	if _, _, err := Create(dir, " !! "); err == nil {
+		t.Error("expected an error for a name without letters or digits")
+	}
+	if _, _, err := Create(filepath.Join(dir, "missing"), "x"); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 4 {
+		t.Errorf("got %d files, want 4", len(entries))
+	}
+}
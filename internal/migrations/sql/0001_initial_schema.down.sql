@@ -0,0 +1,29 @@
+DROP TABLE IF EXISTS "activity_logs";
+DROP TABLE IF EXISTS "notifications";
+DROP TABLE IF EXISTS "return_items";
+DROP TABLE IF EXISTS "returns";
+DROP TABLE IF EXISTS "tracking_events";
+DROP TABLE IF EXISTS "shipments";
+DROP TABLE IF EXISTS "logistics_providers";
+DROP TABLE IF EXISTS "inventory";
+DROP TABLE IF EXISTS "warehouses";
+DROP TABLE IF EXISTS "coupon_usages";
+DROP TABLE IF EXISTS "coupons";
+DROP TABLE IF EXISTS "payments";
+DROP TABLE IF EXISTS "order_status_history";
+DROP TABLE IF EXISTS "order_items";
+DROP TABLE IF EXISTS "orders";
+DROP TABLE IF EXISTS "wishlist_items";
+DROP TABLE IF EXISTS "cart_items";
+DROP TABLE IF EXISTS "reviews";
+DROP TABLE IF EXISTS "product_images";
+DROP TABLE IF EXISTS "product_categories";
+DROP TABLE IF EXISTS "categories";
+DROP TABLE IF EXISTS "products";
+DROP TABLE IF EXISTS "vendors";
+DROP TABLE IF EXISTS "addresses";
+DROP TABLE IF EXISTS "users";
+DROP TABLE IF EXISTS "regions";
+DROP TABLE IF EXISTS "districts";
+DROP TABLE IF EXISTS "states";
+DROP TABLE IF EXISTS "countries";
@@ -0,0 +1,594 @@
+-- Schema of the last release that created tables with GORM AutoMigrate.
+-- Everything is IF NOT EXISTS so databases created by that release adopt
+-- it without changes.
+
+CREATE TABLE IF NOT EXISTS "countries" (
+    "id" bigserial,
+    "name" varchar(100) NOT NULL,
+    "code" varchar(3) NOT NULL,
+    "phone_code" varchar(10),
+    "currency" varchar(3),
+    "is_active" boolean DEFAULT true,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "uni_countries_name" UNIQUE ("name"),
+    CONSTRAINT "uni_countries_code" UNIQUE ("code")
+);
+CREATE INDEX IF NOT EXISTS "idx_countries_deleted_at" ON "countries" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "states" (
+    "id" bigserial,
+    "country_id" bigint NOT NULL,
+    "name" varchar(100) NOT NULL,
+    "code" varchar(10) NOT NULL,
+    "is_active" boolean DEFAULT true,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_countries_states" FOREIGN KEY ("country_id") REFERENCES "countries"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_states_deleted_at" ON "states" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_states_country_id" ON "states" ("country_id");
+
+CREATE TABLE IF NOT EXISTS "districts" (
+    "id" bigserial,
+    "state_id" bigint NOT NULL,
+    "name" varchar(100) NOT NULL,
+    "code" varchar(20),
+    "is_active" boolean DEFAULT true,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_states_districts" FOREIGN KEY ("state_id") REFERENCES "states"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_districts_deleted_at" ON "districts" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_districts_state_id" ON "districts" ("state_id");
+
+CREATE TABLE IF NOT EXISTS "regions" (
+    "id" bigserial,
+    "name" varchar(100) NOT NULL,
+    "slug" varchar(100) NOT NULL,
+    "type" varchar(50),
+    "state_id" bigint,
+    "description" text,
+    "famous_for" text,
+    "image_url" text,
+    "is_active" boolean DEFAULT true,
+    "display_order" bigint DEFAULT 0,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_states_regions" FOREIGN KEY ("state_id") REFERENCES "states"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_regions_state_id" ON "regions" ("state_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_regions_slug" ON "regions" ("slug");
+CREATE INDEX IF NOT EXISTS "idx_regions_deleted_at" ON "regions" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "users" (
+    "id" bigserial,
+    "email" text NOT NULL,
+    "password_hash" text NOT NULL,
+    "name" text NOT NULL,
+    "phone" varchar(15),
+    "role" varchar(20) DEFAULT 'customer',
+    "email_verified" boolean DEFAULT false,
+    "is_active" boolean DEFAULT true,
+    "last_login" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_users_deleted_at" ON "users" ("deleted_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_email" ON "users" ("email");
+
+CREATE TABLE IF NOT EXISTS "addresses" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "full_name" text NOT NULL,
+    "phone" text NOT NULL,
+    "address_line1" text NOT NULL,
+    "address_line2" text,
+    "landmark" text,
+    "district_id" bigint NOT NULL,
+    "state_id" bigint NOT NULL,
+    "country_id" bigint NOT NULL,
+    "pin_code" text NOT NULL,
+    "address_type" varchar(20) DEFAULT 'shipping',
+    "is_default" boolean DEFAULT false,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_addresses_country" FOREIGN KEY ("country_id") REFERENCES "countries"("id"),
+    CONSTRAINT "fk_addresses_state" FOREIGN KEY ("state_id") REFERENCES "states"("id"),
+    CONSTRAINT "fk_addresses_district" FOREIGN KEY ("district_id") REFERENCES "districts"("id"),
+    CONSTRAINT "fk_users_addresses" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_addresses_country_id" ON "addresses" ("country_id");
+CREATE INDEX IF NOT EXISTS "idx_addresses_state_id" ON "addresses" ("state_id");
+CREATE INDEX IF NOT EXISTS "idx_addresses_district_id" ON "addresses" ("district_id");
+CREATE INDEX IF NOT EXISTS "idx_addresses_user_id" ON "addresses" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_addresses_deleted_at" ON "addresses" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "vendors" (
+    "id" bigserial,
+    "user_id" bigint,
+    "business_name" varchar(200) NOT NULL,
+    "owner_name" varchar(100) NOT NULL,
+    "email" varchar(100) NOT NULL,
+    "phone" varchar(20) NOT NULL,
+    "alternate_phone" varchar(20),
+    "gst_number" varchar(15),
+    "pan_number" varchar(10),
+    "business_type" varchar(50),
+    "year_established" bigint,
+    "address_line1" varchar(255) NOT NULL,
+    "address_line2" varchar(255),
+    "locality" varchar(100),
+    "district_id" bigint NOT NULL,
+    "state_id" bigint NOT NULL,
+    "country_id" bigint NOT NULL,
+    "pincode" varchar(10) NOT NULL,
+    "bank_name" varchar(100),
+    "bank_account_no" varchar(50),
+    "bank_ifsc" varchar(15),
+    "bank_branch" varchar(100),
+    "status" varchar(20) DEFAULT 'PENDING',
+    "is_verified" boolean DEFAULT false,
+    "verified_at" timestamptz,
+    "verified_by" bigint,
+    "rejection_reason" text,
+    "permissions" jsonb,
+    "commission" decimal(5,2) DEFAULT 10,
+    "description" text,
+    "logo" text,
+    "banner_image" text,
+    "website" text,
+    "rating" decimal(3,2) DEFAULT 0,
+    "total_reviews" bigint DEFAULT 0,
+    "total_products" bigint DEFAULT 0,
+    "total_orders" bigint DEFAULT 0,
+    "total_revenue" bigint DEFAULT 0,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_vendors_country" FOREIGN KEY ("country_id") REFERENCES "countries"("id"),
+    CONSTRAINT "fk_vendors_state" FOREIGN KEY ("state_id") REFERENCES "states"("id"),
+    CONSTRAINT "fk_vendors_district" FOREIGN KEY ("district_id") REFERENCES "districts"("id"),
+    CONSTRAINT "fk_vendors_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_vendors_district_id" ON "vendors" ("district_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_vendors_gst_number" ON "vendors" ("gst_number");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_vendors_email" ON "vendors" ("email");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_vendors_user_id" ON "vendors" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_vendors_deleted_at" ON "vendors" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_vendors_status" ON "vendors" ("status");
+CREATE INDEX IF NOT EXISTS "idx_vendors_country_id" ON "vendors" ("country_id");
+CREATE INDEX IF NOT EXISTS "idx_vendors_state_id" ON "vendors" ("state_id");
+
+CREATE TABLE IF NOT EXISTS "products" (
+    "id" bigserial,
+    "name" text NOT NULL,
+    "slug" text NOT NULL,
+    "description" text,
+    "product_type" varchar(50) NOT NULL,
+    "region_id" bigint,
+    "vendor_id" bigint,
+    "state_origin" varchar(10),
+    "saree_type" text,
+    "base_price" decimal NOT NULL,
+    "discount_percentage" decimal DEFAULT 0,
+    "final_price" decimal NOT NULL,
+    "fabric" text,
+    "weave_type" text,
+    "occasion" text,
+    "stock_quantity" bigint DEFAULT 0,
+    "is_active" boolean DEFAULT true,
+    "metadata" jsonb,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_regions_products" FOREIGN KEY ("region_id") REFERENCES "regions"("id"),
+    CONSTRAINT "fk_vendors_products" FOREIGN KEY ("vendor_id") REFERENCES "vendors"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_products_deleted_at" ON "products" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_products_is_active" ON "products" ("is_active");
+CREATE INDEX IF NOT EXISTS "idx_products_saree_type" ON "products" ("saree_type");
+CREATE INDEX IF NOT EXISTS "idx_products_state_origin" ON "products" ("state_origin");
+CREATE INDEX IF NOT EXISTS "idx_products_region_id" ON "products" ("region_id");
+CREATE INDEX IF NOT EXISTS "idx_products_vendor_id" ON "products" ("vendor_id");
+CREATE INDEX IF NOT EXISTS "idx_products_product_type" ON "products" ("product_type");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_products_slug" ON "products" ("slug");
+CREATE INDEX IF NOT EXISTS "idx_products_name" ON "products" ("name");
+
+CREATE TABLE IF NOT EXISTS "categories" (
+    "id" bigserial,
+    "name" text NOT NULL,
+    "slug" text NOT NULL,
+    "parent_id" bigint,
+    "category_type" varchar(50),
+    "state_code" varchar(10),
+    "description" text,
+    "display_order" bigint DEFAULT 0,
+    "is_active" boolean DEFAULT true,
+    "metadata" jsonb,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_categories_children" FOREIGN KEY ("parent_id") REFERENCES "categories"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_categories_name" ON "categories" ("name");
+CREATE INDEX IF NOT EXISTS "idx_categories_deleted_at" ON "categories" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_categories_parent_id" ON "categories" ("parent_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_categories_slug" ON "categories" ("slug");
+
+CREATE TABLE IF NOT EXISTS "product_categories" (
+    "product_id" bigint,
+    "category_id" bigint,
+    PRIMARY KEY ("product_id","category_id"),
+    CONSTRAINT "fk_product_categories_product" FOREIGN KEY ("product_id") REFERENCES "products"("id"),
+    CONSTRAINT "fk_product_categories_category" FOREIGN KEY ("category_id") REFERENCES "categories"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "product_images" (
+    "id" bigserial,
+    "product_id" bigint NOT NULL,
+    "image_url" text NOT NULL,
+    "alt_text" text,
+    "display_order" bigint DEFAULT 0,
+    "is_primary" boolean DEFAULT false,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_products_images" FOREIGN KEY ("product_id") REFERENCES "products"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_product_images_product_id" ON "product_images" ("product_id");
+
+CREATE TABLE IF NOT EXISTS "reviews" (
+    "id" bigserial,
+    "product_id" bigint NOT NULL,
+    "user_id" bigint NOT NULL,
+    "rating" bigint NOT NULL,
+    "comment" text,
+    "is_verified_purchase" boolean DEFAULT false,
+    "is_approved" boolean DEFAULT true,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_users_reviews" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+    CONSTRAINT "fk_products_reviews" FOREIGN KEY ("product_id") REFERENCES "products"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_reviews_deleted_at" ON "reviews" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_reviews_user_id" ON "reviews" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_reviews_product_id" ON "reviews" ("product_id");
+
+CREATE TABLE IF NOT EXISTS "cart_items" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "product_id" bigint NOT NULL,
+    "quantity" bigint NOT NULL DEFAULT 1,
+    "added_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_cart_items_product" FOREIGN KEY ("product_id") REFERENCES "products"("id"),
+    CONSTRAINT "fk_users_cart_items" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_cart_items_deleted_at" ON "cart_items" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_cart_items_product_id" ON "cart_items" ("product_id");
+CREATE INDEX IF NOT EXISTS "idx_cart_items_user_id" ON "cart_items" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "wishlist_items" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "product_id" bigint NOT NULL,
+    "added_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_wishlist_items_product" FOREIGN KEY ("product_id") REFERENCES "products"("id"),
+    CONSTRAINT "fk_users_wishlists" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_wishlist_items_product_id" ON "wishlist_items" ("product_id");
+CREATE INDEX IF NOT EXISTS "idx_wishlist_items_user_id" ON "wishlist_items" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_wishlist_items_deleted_at" ON "wishlist_items" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "orders" (
+    "id" bigserial,
+    "order_number" text NOT NULL,
+    "user_id" bigint NOT NULL,
+    "status" varchar(20) DEFAULT 'pending',
+    "payment_status" varchar(20) DEFAULT 'pending',
+    "payment_method" varchar(50),
+    "subtotal_amount" decimal NOT NULL,
+    "discount_amount" decimal DEFAULT 0,
+    "tax_amount" decimal DEFAULT 0,
+    "shipping_amount" decimal DEFAULT 0,
+    "total_amount" decimal NOT NULL,
+    "coupon_code" text,
+    "shipping_address" jsonb,
+    "billing_address" jsonb,
+    "customer_notes" text,
+    "admin_notes" text,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_users_orders" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_orders_deleted_at" ON "orders" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_orders_status" ON "orders" ("status");
+CREATE INDEX IF NOT EXISTS "idx_orders_user_id" ON "orders" ("user_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_orders_order_number" ON "orders" ("order_number");
+
+CREATE TABLE IF NOT EXISTS "order_items" (
+    "id" bigserial,
+    "order_id" bigint NOT NULL,
+    "product_id" bigint NOT NULL,
+    "product_name" text NOT NULL,
+    "quantity" bigint NOT NULL,
+    "unit_price" decimal NOT NULL,
+    "total_price" decimal NOT NULL,
+    "metadata" jsonb,
+    "created_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_order_items_product" FOREIGN KEY ("product_id") REFERENCES "products"("id"),
+    CONSTRAINT "fk_orders_items" FOREIGN KEY ("order_id") REFERENCES "orders"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_order_items_product_id" ON "order_items" ("product_id");
+CREATE INDEX IF NOT EXISTS "idx_order_items_order_id" ON "order_items" ("order_id");
+CREATE INDEX IF NOT EXISTS "idx_order_items_deleted_at" ON "order_items" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "order_status_history" (
+    "id" bigserial,
+    "order_id" bigint NOT NULL,
+    "status" varchar(20) NOT NULL,
+    "comment" text,
+    "changed_by" bigint,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_orders_status_history" FOREIGN KEY ("order_id") REFERENCES "orders"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_order_status_history_order_id" ON "order_status_history" ("order_id");
+
+CREATE TABLE IF NOT EXISTS "payments" (
+    "id" bigserial,
+    "order_id" bigint NOT NULL,
+    "payment_provider" varchar(50) NOT NULL,
+    "provider_order_id" text,
+    "provider_payment_id" text,
+    "payment_method" varchar(50),
+    "amount" decimal NOT NULL,
+    "currency" text DEFAULT 'INR',
+    "status" varchar(20) DEFAULT 'pending',
+    "payment_signature" text,
+    "error_code" text,
+    "error_description" text,
+    "metadata" jsonb,
+    "paid_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_orders_payment" FOREIGN KEY ("order_id") REFERENCES "orders"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_payments_provider_order_id" ON "payments" ("provider_order_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_payments_order_id" ON "payments" ("order_id");
+CREATE INDEX IF NOT EXISTS "idx_payments_deleted_at" ON "payments" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_payments_status" ON "payments" ("status");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_payments_provider_payment_id" ON "payments" ("provider_payment_id");
+
+CREATE TABLE IF NOT EXISTS "coupons" (
+    "id" bigserial,
+    "code" text NOT NULL,
+    "description" text,
+    "discount_type" varchar(20) NOT NULL,
+    "discount_value" decimal NOT NULL,
+    "min_order_amount" decimal DEFAULT 0,
+    "max_discount" decimal,
+    "usage_limit" bigint DEFAULT 0,
+    "used_count" bigint DEFAULT 0,
+    "valid_from" timestamptz,
+    "valid_until" timestamptz,
+    "is_active" boolean DEFAULT true,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_coupons_deleted_at" ON "coupons" ("deleted_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_coupons_code" ON "coupons" ("code");
+
+CREATE TABLE IF NOT EXISTS "coupon_usages" (
+    "id" bigserial,
+    "coupon_id" bigint NOT NULL,
+    "user_id" bigint NOT NULL,
+    "order_id" bigint NOT NULL,
+    "used_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_coupon_usages_order" FOREIGN KEY ("order_id") REFERENCES "orders"("id"),
+    CONSTRAINT "fk_coupons_usages" FOREIGN KEY ("coupon_id") REFERENCES "coupons"("id"),
+    CONSTRAINT "fk_coupon_usages_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_coupon_usages_coupon_id" ON "coupon_usages" ("coupon_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_coupon_usages_order_id" ON "coupon_usages" ("order_id");
+CREATE INDEX IF NOT EXISTS "idx_coupon_usages_user_id" ON "coupon_usages" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "warehouses" (
+    "id" bigserial,
+    "name" text NOT NULL,
+    "code" text NOT NULL,
+    "address" text NOT NULL,
+    "city" text NOT NULL,
+    "state" text NOT NULL,
+    "pin_code" text NOT NULL,
+    "phone" text,
+    "email" text,
+    "is_active" boolean DEFAULT true,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_warehouses_deleted_at" ON "warehouses" ("deleted_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_warehouses_code" ON "warehouses" ("code");
+
+CREATE TABLE IF NOT EXISTS "inventory" (
+    "id" bigserial,
+    "product_id" bigint NOT NULL,
+    "warehouse_id" bigint NOT NULL,
+    "quantity" bigint NOT NULL DEFAULT 0,
+    "reserved_quantity" bigint DEFAULT 0,
+    "low_stock_threshold" bigint DEFAULT 10,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_warehouses_inventory" FOREIGN KEY ("warehouse_id") REFERENCES "warehouses"("id"),
+    CONSTRAINT "fk_inventory_product" FOREIGN KEY ("product_id") REFERENCES "products"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_inventory_product_id" ON "inventory" ("product_id");
+CREATE INDEX IF NOT EXISTS "idx_inventory_deleted_at" ON "inventory" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_inventory_warehouse_id" ON "inventory" ("warehouse_id");
+
+CREATE TABLE IF NOT EXISTS "logistics_providers" (
+    "id" bigserial,
+    "name" text NOT NULL,
+    "code" text NOT NULL,
+    "api_endpoint" text,
+    "is_active" boolean DEFAULT true,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_logistics_providers_deleted_at" ON "logistics_providers" ("deleted_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_logistics_providers_code" ON "logistics_providers" ("code");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_logistics_providers_name" ON "logistics_providers" ("name");
+
+CREATE TABLE IF NOT EXISTS "shipments" (
+    "id" bigserial,
+    "order_id" bigint NOT NULL,
+    "provider_id" bigint NOT NULL,
+    "awb_number" text,
+    "status" varchar(30) DEFAULT 'pending',
+    "weight" decimal,
+    "dimensions" jsonb,
+    "shipping_cost" decimal DEFAULT 0,
+    "estimated_delivery" timestamptz,
+    "actual_delivery" timestamptz,
+    "pickup_date" timestamptz,
+    "tracking_url" text,
+    "metadata" jsonb,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_logistics_providers_shipments" FOREIGN KEY ("provider_id") REFERENCES "logistics_providers"("id"),
+    CONSTRAINT "fk_orders_shipment" FOREIGN KEY ("order_id") REFERENCES "orders"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_shipments_deleted_at" ON "shipments" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_shipments_status" ON "shipments" ("status");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_shipments_awb_number" ON "shipments" ("awb_number");
+CREATE INDEX IF NOT EXISTS "idx_shipments_provider_id" ON "shipments" ("provider_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_shipments_order_id" ON "shipments" ("order_id");
+
+CREATE TABLE IF NOT EXISTS "tracking_events" (
+    "id" bigserial,
+    "shipment_id" bigint NOT NULL,
+    "status" text NOT NULL,
+    "location" text,
+    "description" text,
+    "event_time" timestamptz NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_shipments_tracking_events" FOREIGN KEY ("shipment_id") REFERENCES "shipments"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_tracking_events_shipment_id" ON "tracking_events" ("shipment_id");
+
+CREATE TABLE IF NOT EXISTS "returns" (
+    "id" bigserial,
+    "order_id" bigint NOT NULL,
+    "user_id" bigint NOT NULL,
+    "return_number" text NOT NULL,
+    "reason" varchar(50) NOT NULL,
+    "reason_details" text,
+    "status" varchar(20) DEFAULT 'requested',
+    "refund_amount" decimal,
+    "admin_notes" text,
+    "approved_by" bigint,
+    "approved_at" timestamptz,
+    "refunded_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_returns_order" FOREIGN KEY ("order_id") REFERENCES "orders"("id"),
+    CONSTRAINT "fk_returns_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_returns_user_id" ON "returns" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_returns_order_id" ON "returns" ("order_id");
+CREATE INDEX IF NOT EXISTS "idx_returns_deleted_at" ON "returns" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_returns_status" ON "returns" ("status");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_returns_return_number" ON "returns" ("return_number");
+
+CREATE TABLE IF NOT EXISTS "return_items" (
+    "id" bigserial,
+    "return_id" bigint NOT NULL,
+    "order_item_id" bigint NOT NULL,
+    "product_id" bigint NOT NULL,
+    "quantity" bigint NOT NULL,
+    "reason" text,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_return_items_order_item" FOREIGN KEY ("order_item_id") REFERENCES "order_items"("id"),
+    CONSTRAINT "fk_return_items_product" FOREIGN KEY ("product_id") REFERENCES "products"("id"),
+    CONSTRAINT "fk_returns_items" FOREIGN KEY ("return_id") REFERENCES "returns"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_return_items_order_item_id" ON "return_items" ("order_item_id");
+CREATE INDEX IF NOT EXISTS "idx_return_items_return_id" ON "return_items" ("return_id");
+
+CREATE TABLE IF NOT EXISTS "notifications" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "type" varchar(30) NOT NULL,
+    "channel" varchar(20) NOT NULL,
+    "title" text NOT NULL,
+    "message" text NOT NULL,
+    "data" jsonb,
+    "is_read" boolean DEFAULT false,
+    "read_at" timestamptz,
+    "sent_at" timestamptz,
+    "created_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_notifications_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_notifications_deleted_at" ON "notifications" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_notifications_is_read" ON "notifications" ("is_read");
+CREATE INDEX IF NOT EXISTS "idx_notifications_user_id" ON "notifications" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "activity_logs" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "action" text NOT NULL,
+    "entity_type" text NOT NULL,
+    "entity_id" bigint,
+    "description" text,
+    "ip_address" text,
+    "user_agent" text,
+    "metadata" jsonb,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_activity_logs_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_activity_logs_action" ON "activity_logs" ("action");
+CREATE INDEX IF NOT EXISTS "idx_activity_logs_user_id" ON "activity_logs" ("user_id");
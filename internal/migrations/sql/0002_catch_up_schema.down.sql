@@ -0,0 +1,27 @@
+DROP TABLE IF EXISTS "pin_code_coverage";
+DROP TABLE IF EXISTS "pin_codes";
+DROP TABLE IF EXISTS "stock_movements";
+DROP TABLE IF EXISTS "stock_transfers";
+DROP TABLE IF EXISTS "stock_reservations";
+DROP TABLE IF EXISTS "refunds";
+DROP TABLE IF EXISTS "payment_webhook_events";
+DROP TABLE IF EXISTS "otp_failures";
+DROP TABLE IF EXISTS "phone_otps";
+DROP TABLE IF EXISTS "user_tokens";
+DROP TABLE IF EXISTS "revoked_tokens";
+DROP TABLE IF EXISTS "refresh_tokens";
+
+DROP INDEX IF EXISTS "idx_activity_entity";
+DROP INDEX IF EXISTS "idx_activity_logs_created_at";
+DROP INDEX IF EXISTS "idx_notifications_event";
+DROP INDEX IF EXISTS "idx_notification_outbox";
+DROP INDEX IF EXISTS "idx_tracking_event_scan";
+DROP INDEX IF EXISTS "idx_inventory_product_warehouse";
+DROP INDEX IF EXISTS "idx_users_phone";
+ALTER TABLE "users" ALTER COLUMN "phone" TYPE varchar(15);
+ALTER TABLE "users" DROP COLUMN IF EXISTS "phone_verified";
+ALTER TABLE "notifications" DROP COLUMN IF EXISTS "event";
+ALTER TABLE "notifications" DROP COLUMN IF EXISTS "status";
+ALTER TABLE "notifications" DROP COLUMN IF EXISTS "attempts";
+ALTER TABLE "notifications" DROP COLUMN IF EXISTS "next_attempt_at";
+ALTER TABLE "notifications" DROP COLUMN IF EXISTS "last_error";
@@ -0,0 +1,238 @@
+-- Tables, columns and indexes added since the initial schema. Also IF NOT
+-- EXISTS, so databases that AutoMigrate already brought up to date adopt it
+-- without changes.
+
+ALTER TABLE "users" ALTER COLUMN "phone" TYPE varchar(16);
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "phone_verified" boolean DEFAULT false;
+CREATE INDEX IF NOT EXISTS "idx_users_phone" ON "users" ("phone");
+
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_inventory_product_warehouse" ON "inventory" ("product_id","warehouse_id");
+
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_tracking_event_scan" ON "tracking_events" ("shipment_id","status","event_time");
+
+ALTER TABLE "notifications" ADD COLUMN IF NOT EXISTS "event" varchar(50);
+ALTER TABLE "notifications" ADD COLUMN IF NOT EXISTS "status" varchar(20) DEFAULT 'pending';
+ALTER TABLE "notifications" ADD COLUMN IF NOT EXISTS "attempts" bigint DEFAULT 0;
+ALTER TABLE "notifications" ADD COLUMN IF NOT EXISTS "next_attempt_at" timestamptz;
+ALTER TABLE "notifications" ADD COLUMN IF NOT EXISTS "last_error" text;
+CREATE INDEX IF NOT EXISTS "idx_notification_outbox" ON "notifications" ("status","next_attempt_at");
+CREATE INDEX IF NOT EXISTS "idx_notifications_event" ON "notifications" ("event");
+
+CREATE INDEX IF NOT EXISTS "idx_activity_logs_created_at" ON "activity_logs" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_activity_entity" ON "activity_logs" ("entity_type","entity_id");
+
+CREATE TABLE IF NOT EXISTS "refresh_tokens" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "family_id" varchar(32) NOT NULL,
+    "token_hash" varchar(64) NOT NULL,
+    "access_jti" varchar(32) NOT NULL,
+    "access_expires_at" timestamptz NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "used_at" timestamptz,
+    "revoked_at" timestamptz,
+    "ip_address" text,
+    "user_agent" text,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_refresh_tokens_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_refresh_tokens_expires_at" ON "refresh_tokens" ("expires_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_refresh_tokens_token_hash" ON "refresh_tokens" ("token_hash");
+CREATE INDEX IF NOT EXISTS "idx_refresh_tokens_family_id" ON "refresh_tokens" ("family_id");
+CREATE INDEX IF NOT EXISTS "idx_refresh_tokens_user_id" ON "refresh_tokens" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "revoked_tokens" (
+    "jti" varchar(32),
+    "user_id" bigint NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("jti")
+);
+CREATE INDEX IF NOT EXISTS "idx_revoked_tokens_expires_at" ON "revoked_tokens" ("expires_at");
+CREATE INDEX IF NOT EXISTS "idx_revoked_tokens_user_id" ON "revoked_tokens" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "user_tokens" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "purpose" varchar(30) NOT NULL,
+    "token_hash" varchar(64) NOT NULL,
+    "email" text NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "used_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_user_tokens_expires_at" ON "user_tokens" ("expires_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_user_tokens_token_hash" ON "user_tokens" ("token_hash");
+CREATE INDEX IF NOT EXISTS "idx_user_tokens_user_id" ON "user_tokens" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "phone_otps" (
+    "id" bigserial,
+    "phone" varchar(16) NOT NULL,
+    "code_hash" varchar(64) NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "attempts" bigint DEFAULT 0,
+    "consumed_at" timestamptz,
+    "ip_address" varchar(45),
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_phone_otps_created_at" ON "phone_otps" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_phone_otps_ip_address" ON "phone_otps" ("ip_address");
+CREATE INDEX IF NOT EXISTS "idx_phone_otps_phone" ON "phone_otps" ("phone");
+
+CREATE TABLE IF NOT EXISTS "otp_failures" (
+    "id" bigserial,
+    "phone" varchar(16) NOT NULL,
+    "ip_address" varchar(45),
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_otp_failures_created_at" ON "otp_failures" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_otp_failures_ip_address" ON "otp_failures" ("ip_address");
+CREATE INDEX IF NOT EXISTS "idx_otp_failures_phone" ON "otp_failures" ("phone");
+
+CREATE TABLE IF NOT EXISTS "payment_webhook_events" (
+    "id" bigserial,
+    "provider" varchar(50) NOT NULL,
+    "event_id" text NOT NULL,
+    "event_type" varchar(50) NOT NULL,
+    "provider_order_id" text,
+    "provider_payment_id" text,
+    "status" varchar(20) DEFAULT 'received',
+    "error" text,
+    "attempts" bigint DEFAULT 0,
+    "payload" jsonb,
+    "processed_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_payment_webhook_events_status" ON "payment_webhook_events" ("status");
+CREATE INDEX IF NOT EXISTS "idx_payment_webhook_events_provider_payment_id" ON "payment_webhook_events" ("provider_payment_id");
+CREATE INDEX IF NOT EXISTS "idx_payment_webhook_events_provider_order_id" ON "payment_webhook_events" ("provider_order_id");
+CREATE INDEX IF NOT EXISTS "idx_payment_webhook_events_event_type" ON "payment_webhook_events" ("event_type");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_payment_webhook_event" ON "payment_webhook_events" ("provider","event_id");
+
+CREATE TABLE IF NOT EXISTS "refunds" (
+    "id" bigserial,
+    "refund_number" text NOT NULL,
+    "order_id" bigint NOT NULL,
+    "payment_id" bigint,
+    "return_id" bigint,
+    "method" varchar(20) NOT NULL,
+    "status" varchar(20) DEFAULT 'pending',
+    "amount" decimal NOT NULL,
+    "currency" text DEFAULT 'INR',
+    "provider_refund_id" text,
+    "reason" text,
+    "failure_reason" text,
+    "bank_account_name" text,
+    "bank_account_number" text,
+    "bank_ifsc" text,
+    "bank_name" text,
+    "bank_reference" text,
+    "initiated_by" bigint,
+    "processed_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_refunds_payment" FOREIGN KEY ("payment_id") REFERENCES "payments"("id"),
+    CONSTRAINT "fk_refunds_order" FOREIGN KEY ("order_id") REFERENCES "orders"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_refunds_provider_refund_id" ON "refunds" ("provider_refund_id");
+CREATE INDEX IF NOT EXISTS "idx_refunds_status" ON "refunds" ("status");
+CREATE INDEX IF NOT EXISTS "idx_refunds_return_id" ON "refunds" ("return_id");
+CREATE INDEX IF NOT EXISTS "idx_refunds_payment_id" ON "refunds" ("payment_id");
+CREATE INDEX IF NOT EXISTS "idx_refunds_order_id" ON "refunds" ("order_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_refunds_refund_number" ON "refunds" ("refund_number");
+
+CREATE TABLE IF NOT EXISTS "stock_reservations" (
+    "id" bigserial,
+    "order_id" bigint NOT NULL,
+    "product_id" bigint NOT NULL,
+    "warehouse_id" bigint NOT NULL,
+    "quantity" bigint NOT NULL,
+    "status" varchar(20) DEFAULT 'active',
+    "expires_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_stock_reservations_order" FOREIGN KEY ("order_id") REFERENCES "orders"("id"),
+    CONSTRAINT "fk_stock_reservations_product" FOREIGN KEY ("product_id") REFERENCES "products"("id"),
+    CONSTRAINT "fk_stock_reservations_warehouse" FOREIGN KEY ("warehouse_id") REFERENCES "warehouses"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_stock_reservations_expires_at" ON "stock_reservations" ("expires_at");
+CREATE INDEX IF NOT EXISTS "idx_stock_reservations_status" ON "stock_reservations" ("status");
+CREATE INDEX IF NOT EXISTS "idx_stock_reservations_warehouse_id" ON "stock_reservations" ("warehouse_id");
+CREATE INDEX IF NOT EXISTS "idx_stock_reservations_product_id" ON "stock_reservations" ("product_id");
+CREATE INDEX IF NOT EXISTS "idx_stock_reservations_order_id" ON "stock_reservations" ("order_id");
+
+CREATE TABLE IF NOT EXISTS "stock_transfers" (
+    "id" bigserial,
+    "product_id" bigint NOT NULL,
+    "from_warehouse_id" bigint NOT NULL,
+    "to_warehouse_id" bigint NOT NULL,
+    "quantity" bigint NOT NULL,
+    "notes" text,
+    "created_by" bigint NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_stock_transfers_from_warehouse" FOREIGN KEY ("from_warehouse_id") REFERENCES "warehouses"("id"),
+    CONSTRAINT "fk_stock_transfers_to_warehouse" FOREIGN KEY ("to_warehouse_id") REFERENCES "warehouses"("id"),
+    CONSTRAINT "fk_stock_transfers_product" FOREIGN KEY ("product_id") REFERENCES "products"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_stock_transfers_created_by" ON "stock_transfers" ("created_by");
+CREATE INDEX IF NOT EXISTS "idx_stock_transfers_to_warehouse_id" ON "stock_transfers" ("to_warehouse_id");
+CREATE INDEX IF NOT EXISTS "idx_stock_transfers_from_warehouse_id" ON "stock_transfers" ("from_warehouse_id");
+CREATE INDEX IF NOT EXISTS "idx_stock_transfers_product_id" ON "stock_transfers" ("product_id");
+
+CREATE TABLE IF NOT EXISTS "stock_movements" (
+    "id" bigserial,
+    "product_id" bigint NOT NULL,
+    "warehouse_id" bigint NOT NULL,
+    "type" varchar(20) NOT NULL,
+    "quantity" bigint NOT NULL,
+    "reference_type" varchar(20),
+    "reference_id" bigint,
+    "reason" text,
+    "actor_id" bigint,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_stock_movements_product" FOREIGN KEY ("product_id") REFERENCES "products"("id"),
+    CONSTRAINT "fk_stock_movements_warehouse" FOREIGN KEY ("warehouse_id") REFERENCES "warehouses"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_stock_movement_product_warehouse" ON "stock_movements" ("product_id","warehouse_id");
+CREATE INDEX IF NOT EXISTS "idx_stock_movements_created_at" ON "stock_movements" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_stock_movements_actor_id" ON "stock_movements" ("actor_id");
+CREATE INDEX IF NOT EXISTS "idx_stock_movement_reference" ON "stock_movements" ("reference_type","reference_id");
+CREATE INDEX IF NOT EXISTS "idx_stock_movements_type" ON "stock_movements" ("type");
+
+CREATE TABLE IF NOT EXISTS "pin_codes" (
+    "code" varchar(6),
+    "district_id" bigint NOT NULL,
+    "area" varchar(100),
+    "latitude" decimal,
+    "longitude" decimal,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("code"),
+    CONSTRAINT "fk_pin_codes_district" FOREIGN KEY ("district_id") REFERENCES "districts"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_pin_codes_district_id" ON "pin_codes" ("district_id");
+
+CREATE TABLE IF NOT EXISTS "pin_code_coverage" (
+    "id" bigserial,
+    "pin_code" varchar(6) NOT NULL,
+    "provider_id" bigint NOT NULL,
+    "prepaid" boolean NOT NULL,
+    "cod" boolean NOT NULL,
+    "extra_days" bigint DEFAULT 0,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_pin_code_coverage_provider" FOREIGN KEY ("provider_id") REFERENCES "logistics_providers"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_pin_code_coverage_provider_id" ON "pin_code_coverage" ("provider_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_pin_code_coverage" ON "pin_code_coverage" ("pin_code","provider_id");
@@ -0,0 +1,2 @@
+-- The old formats aren't kept, so numbers stay in E.164 form
+SELECT 1;
@@ -0,0 +1,13 @@
+-- Rewrite Indian numbers saved before OTP login, e.g. 098765 43210 or
+-- 91-9876543210, in E.164 form. They stay unverified: OTP login only
+-- matches numbers confirmed with a code.
+UPDATE "users"
+SET "phone" = '+91' || right(legacy.digits, 10)
+FROM (
+    SELECT "id", regexp_replace("phone", '[ ().-]', '', 'g') AS digits
+    FROM "users"
+    WHERE "phone_verified" = false AND "phone" <> ''
+) AS legacy
+WHERE "users"."id" = legacy."id"
+  AND legacy.digits ~ '^(\+91|0091|91|0)?[6-9][0-9]{9}$'
+  AND "users"."phone" <> '+91' || right(legacy.digits, 10);
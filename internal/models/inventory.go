@@ -1,6 +1,7 @@
 package models
 
 import (
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
@@ -29,8 +30,8 @@ type Warehouse struct {
 // Inventory represents stock levels per warehouse
 type Inventory struct {
 	ID                uint           `gorm:"primaryKey" json:"id"`
-	ProductID         uint           `gorm:"not null;index" json:"product_id"`
-	WarehouseID       uint           `gorm:"not null;index" json:"warehouse_id"`
+	ProductID         uint           `gorm:"not null;index;uniqueIndex:idx_inventory_product_warehouse" json:"product_id"`
+	WarehouseID       uint           `gorm:"not null;index;uniqueIndex:idx_inventory_product_warehouse" json:"warehouse_id"`
 	Quantity          int            `gorm:"not null;default:0" json:"quantity"`
 	ReservedQuantity  int            `gorm:"default:0" json:"reserved_quantity"` // Items in pending orders
 	LowStockThreshold int            `gorm:"default:10" json:"low_stock_threshold"`
@@ -42,6 +43,104 @@ type Inventory struct {
 	Warehouse Warehouse `gorm:"foreignKey:WarehouseID" json:"warehouse,omitempty"`
 }
 
+type ReservationStatus string
+
+const (
+	ReservationStatusActive    ReservationStatus = "active"    // Held for a pending order
+	ReservationStatusCommitted ReservationStatus = "committed" // Deducted from stock on confirmation
+	ReservationStatusReleased  ReservationStatus = "released"  // Returned to available stock
+)
+
+// StockReservation holds warehouse stock for an order until it is paid or cancelled
+type StockReservation struct {
+	ID          uint              `gorm:"primaryKey" json:"id"`
+	OrderID     uint              `gorm:"not null;index" json:"order_id"`
+	ProductID   uint              `gorm:"not null;index" json:"product_id"`
+	WarehouseID uint              `gorm:"not null;index" json:"warehouse_id"`
+	Quantity    int               `gorm:"not null" json:"quantity"`
+	Status      ReservationStatus `gorm:"type:varchar(20);default:'active';index" json:"status"`
+	ExpiresAt   *time.Time        `gorm:"index" json:"expires_at,omitempty"` // Nil for orders that don't wait on payment (COD)
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+
+	// Relationships
+	Order     Order     `gorm:"foreignKey:OrderID" json:"-"`
+	Product   Product   `gorm:"foreignKey:ProductID" json:"-"`
+	Warehouse Warehouse `gorm:"foreignKey:WarehouseID" json:"warehouse,omitempty"`
+}
+
+// StockTransfer records stock moved between warehouses
+type StockTransfer struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	ProductID       uint      `gorm:"not null;index" json:"product_id"`
+	FromWarehouseID uint      `gorm:"not null;index" json:"from_warehouse_id"`
+	ToWarehouseID   uint      `gorm:"not null;index" json:"to_warehouse_id"`
+	Quantity        int       `gorm:"not null" json:"quantity"`
+	Notes           string    `gorm:"type:text" json:"notes,omitempty"`
+	CreatedBy       uint      `gorm:"not null;index" json:"created_by"` // Admin user ID
+	CreatedAt       time.Time `json:"created_at"`
+
+	// Relationships
+	Product       Product   `gorm:"foreignKey:ProductID" json:"product,omitempty"`
+	FromWarehouse Warehouse `gorm:"foreignKey:FromWarehouseID" json:"from_warehouse,omitempty"`
+	ToWarehouse   Warehouse `gorm:"foreignKey:ToWarehouseID" json:"to_warehouse,omitempty"`
+}
+
+type StockMovementType string
+
+const (
+	StockMovementReceipt    StockMovementType = "receipt"    // Goods received into a warehouse
+	StockMovementSale       StockMovementType = "sale"       // Stock committed to a confirmed order
+	StockMovementReturn     StockMovementType = "return"     // Stock back from a cancelled or returned order
+	StockMovementAdjustment StockMovementType = "adjustment" // Manual correction, e.g. after a stock count
+	StockMovementTransfer   StockMovementType = "transfer"   // One leg of a move between warehouses
+)
+
+// Stock movement reference types
+const (
+	StockReferenceOrder    = "order"
+	StockReferenceReturn   = "return"
+	StockReferenceTransfer = "transfer"
+)
+
+// ErrStockMovementImmutable is returned when code tries to change the ledger
+var ErrStockMovementImmutable = errors.New("stock movements are append-only")
+
+// StockMovement is an append-only ledger entry for a change in on-hand
+// warehouse stock; summing Quantity per product and warehouse gives
+// Inventory.Quantity
+type StockMovement struct {
+	ID            uint              `gorm:"primaryKey" json:"id"`
+	ProductID     uint              `gorm:"not null;index:idx_stock_movement_product_warehouse" json:"product_id"`
+	WarehouseID   uint              `gorm:"not null;index:idx_stock_movement_product_warehouse" json:"warehouse_id"`
+	Type          StockMovementType `gorm:"type:varchar(20);not null;index" json:"type"`
+	Quantity      int               `gorm:"not null" json:"quantity"` // Signed change in on-hand stock
+	ReferenceType string            `gorm:"type:varchar(20);index:idx_stock_movement_reference" json:"reference_type,omitempty"`
+	ReferenceID   uint              `gorm:"index:idx_stock_movement_reference" json:"reference_id,omitempty"`
+	Reason        string            `gorm:"type:text" json:"reason,omitempty"`
+	ActorID       uint              `gorm:"index" json:"actor_id"` // 0 for system changes
+	CreatedAt     time.Time         `gorm:"index" json:"created_at"`
+
+	// Relationships
+	Product   Product   `gorm:"foreignKey:ProductID" json:"product,omitempty"`
+	Warehouse Warehouse `gorm:"foreignKey:WarehouseID" json:"warehouse,omitempty"`
+}
+
+// BeforeUpdate keeps the ledger append-only
+func (StockMovement) BeforeUpdate(tx *gorm.DB) error {
+	return ErrStockMovementImmutable
+}
+
+// BeforeDelete keeps the ledger append-only
+func (StockMovement) BeforeDelete(tx *gorm.DB) error {
+	return ErrStockMovementImmutable
+}
+
+// Available returns the stock that can still be reserved
+func (i Inventory) Available() int {
+	return i.Quantity - i.ReservedQuantity
+}
+
 func (Warehouse) TableName() string {
 	return "warehouses"
 }
@@ -49,3 +148,15 @@ func (Warehouse) TableName() string {
 func (Inventory) TableName() string {
 	return "inventory"
 }
+
+func (StockReservation) TableName() string {
+	return "stock_reservations"
+}
+
+func (StockTransfer) TableName() string {
+	return "stock_transfers"
+}
+
+func (StockMovement) TableName() string {
+	return "stock_movements"
+}
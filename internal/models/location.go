@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -76,6 +77,40 @@ type Region struct {
 	Products []Product `json:"products,omitempty" gorm:"foreignKey:RegionID"`
 }
 
+// RegionForState picks the craft region for a product that only has the
+// deprecated StateOrigin code, e.g. "UP". When the state has several
+// regions, hint (such as the saree type "Chikankari") is matched against
+// their names and what they're famous for; failing that a state-wide region
+// or the first in display order is used. Returns nil if the state has none.
+func RegionForState(db *gorm.DB, stateCode, hint string) (*Region, error) {
+	var regions []Region
+	if err := db.Joins("State").
+		Where("regions.is_active = ? AND UPPER(\"State\".code) = ?", true, strings.ToUpper(strings.TrimSpace(stateCode))).
+		Order("regions.display_order ASC, regions.id ASC").
+		Find(&regions).Error; err != nil {
+		return nil, err
+	}
+	if len(regions) == 0 {
+		return nil, nil
+	}
+
+	hint = strings.ToLower(strings.TrimSpace(hint))
+	if hint != "" {
+		for i, r := range regions {
+			name := strings.ToLower(r.Name)
+			if strings.Contains(hint, name) || strings.Contains(strings.ToLower(r.FamousFor), hint) {
+				return &regions[i], nil
+			}
+		}
+	}
+	for i, r := range regions {
+		if r.Type == "State" {
+			return &regions[i], nil
+		}
+	}
+	return &regions[0], nil
+}
+
 // TableName overrides
 func (Country) TableName() string {
 	return "countries"
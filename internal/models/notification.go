@@ -24,12 +24,22 @@ const (
 	NotificationChannelPush  NotificationChannel = "push"
 )
 
-// Notification represents user notifications
+type NotificationStatus string
+
+const (
+	NotificationStatusPending NotificationStatus = "pending" // Waiting in the outbox
+	NotificationStatusSent    NotificationStatus = "sent"
+	NotificationStatusFailed  NotificationStatus = "failed" // Gave up after the last retry
+)
+
+// Notification represents user notifications. Rows double as the outbox:
+// pending rows are sent by the dispatcher and retried until SentAt is set.
 type Notification struct {
 	ID        uint                `gorm:"primaryKey" json:"id"`
 	UserID    uint                `gorm:"not null;index" json:"user_id"`
 	Type      NotificationType    `gorm:"type:varchar(30);not null" json:"type"`
 	Channel   NotificationChannel `gorm:"type:varchar(20);not null" json:"channel"`
+	Event     string              `gorm:"type:varchar(50);index" json:"event,omitempty"` // Template, e.g. order_shipped
 	Title     string              `gorm:"not null" json:"title"`
 	Message   string              `gorm:"type:text;not null" json:"message"`
 	Data      JSONB               `gorm:"type:jsonb" json:"data,omitempty"` // Additional context
@@ -39,6 +49,12 @@ type Notification struct {
 	CreatedAt time.Time           `json:"created_at"`
 	DeletedAt gorm.DeletedAt      `gorm:"index" json:"-"`
 
+	// Outbox delivery state
+	Status        NotificationStatus `gorm:"type:varchar(20);default:'pending';index:idx_notification_outbox,priority:1" json:"-"`
+	Attempts      int                `gorm:"default:0" json:"-"`
+	NextAttemptAt *time.Time         `gorm:"index:idx_notification_outbox,priority:2" json:"-"`
+	LastError     string             `gorm:"type:text" json:"-"`
+
 	// Relationships
 	User User `gorm:"foreignKey:UserID" json:"-"`
 }
@@ -47,14 +63,14 @@ type Notification struct {
 type ActivityLog struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
 	UserID      uint      `gorm:"not null;index" json:"user_id"`
-	Action      string    `gorm:"not null;index" json:"action"` // create, update, delete, etc.
-	EntityType  string    `gorm:"not null" json:"entity_type"`  // product, order, user, etc.
-	EntityID    uint      `json:"entity_id,omitempty"`
+	Action      string    `gorm:"not null;index" json:"action"`                          // create, update, delete, etc.
+	EntityType  string    `gorm:"not null;index:idx_activity_entity" json:"entity_type"` // product, order, user, etc.
+	EntityID    uint      `gorm:"index:idx_activity_entity" json:"entity_id,omitempty"`
 	Description string    `gorm:"type:text" json:"description"`
 	IPAddress   string    `json:"ip_address,omitempty"`
 	UserAgent   string    `gorm:"type:text" json:"user_agent,omitempty"`
 	Metadata    JSONB     `gorm:"type:jsonb" json:"metadata,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
+	CreatedAt   time.Time `gorm:"index" json:"created_at"`
 
 	// Relationships
 	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
@@ -24,8 +24,42 @@ const (
 	PaymentStatusCompleted PaymentStatus = "completed"
 	PaymentStatusFailed    PaymentStatus = "failed"
 	PaymentStatusRefunded  PaymentStatus = "refunded"
+
+	PaymentStatusPartiallyRefunded PaymentStatus = "partially_refunded"
 )
 
+// orderTransitions lists the statuses each order status may move to
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusPending:    {OrderStatusConfirmed, OrderStatusCancelled},
+	OrderStatusConfirmed:  {OrderStatusProcessing, OrderStatusShipped, OrderStatusCancelled},
+	OrderStatusProcessing: {OrderStatusShipped, OrderStatusCancelled},
+	OrderStatusShipped:    {OrderStatusDelivered, OrderStatusReturned},
+	OrderStatusDelivered:  {OrderStatusReturned},
+	OrderStatusCancelled:  {},
+	OrderStatusReturned:   {},
+}
+
+// IsValid reports whether s is a known order status
+func (s OrderStatus) IsValid() bool {
+	_, ok := orderTransitions[s]
+	return ok
+}
+
+// CanTransitionTo reports whether an order may move from s to next
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range orderTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// NextStatuses returns the statuses an order may move to from s
+func (s OrderStatus) NextStatuses() []OrderStatus {
+	return orderTransitions[s]
+}
+
 // Order represents a customer order
 type Order struct {
 	ID              uint           `gorm:"primaryKey" json:"id"`
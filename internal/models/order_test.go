@@ -0,0 +1,48 @@
+package models
+
+import "testing"
+
+func TestOrderStatusCanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from, to OrderStatus
+		want     bool
+	}{
+		{OrderStatusPending, OrderStatusConfirmed, true},
+		{OrderStatusPending, OrderStatusCancelled, true},
+		{OrderStatusPending, OrderStatusShipped, false},
+		{OrderStatusConfirmed, OrderStatusProcessing, true},
+		{OrderStatusConfirmed, OrderStatusShipped, true},
+		{OrderStatusConfirmed, OrderStatusDelivered, false},
+		{OrderStatusProcessing, OrderStatusShipped, true},
+		{OrderStatusProcessing, OrderStatusConfirmed, false},
+		{OrderStatusShipped, OrderStatusDelivered, true},
+		{OrderStatusShipped, OrderStatusReturned, true},
+		{OrderStatusShipped, OrderStatusCancelled, false},
+		{OrderStatusDelivered, OrderStatusReturned, true},
+		{OrderStatusDelivered, OrderStatusShipped, false},
+		{OrderStatusCancelled, OrderStatusPending, false},
+		{OrderStatusReturned, OrderStatusDelivered, false},
+		{OrderStatusPending, OrderStatusPending, false},
+		{OrderStatus("unknown"), OrderStatusConfirmed, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+			t.Errorf("%s -> %s: got %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestOrderStatusIsValid(t *testing.T) {
+	for _, status := range []OrderStatus{
+		OrderStatusPending, OrderStatusConfirmed, OrderStatusProcessing,
+		OrderStatusShipped, OrderStatusDelivered, OrderStatusCancelled, OrderStatusReturned,
+	} {
+		if !status.IsValid() {
+			t.Errorf("%s: expected valid", status)
+		}
+	}
+	if OrderStatus("lost").IsValid() {
+		t.Error("lost: expected invalid")
+	}
+}
@@ -20,6 +20,7 @@ const (
 const (
 	PaymentProviderRazorpay PaymentProvider = "razorpay"
 	PaymentProviderStripe   PaymentProvider = "stripe"
+	PaymentProviderFake     PaymentProvider = "fake" // Offline provider for tests and local development
 )
 
 // Payment represents a payment transaction
@@ -46,6 +47,83 @@ type Payment struct {
 	Order Order `gorm:"foreignKey:OrderID" json:"-"`
 }
 
+type WebhookEventStatus string
+
+const (
+	WebhookEventReceived  WebhookEventStatus = "received"
+	WebhookEventProcessed WebhookEventStatus = "processed"
+	WebhookEventIgnored   WebhookEventStatus = "ignored"
+	WebhookEventFailed    WebhookEventStatus = "failed"
+)
+
+// PaymentWebhookEvent stores every raw gateway webhook for replay protection and reconciliation
+type PaymentWebhookEvent struct {
+	ID                uint               `gorm:"primaryKey" json:"id"`
+	Provider          PaymentProvider    `gorm:"type:varchar(50);not null;uniqueIndex:idx_payment_webhook_event" json:"provider"`
+	EventID           string             `gorm:"not null;uniqueIndex:idx_payment_webhook_event" json:"event_id"`
+	EventType         string             `gorm:"type:varchar(50);not null;index" json:"event_type"`
+	ProviderOrderID   string             `gorm:"index" json:"provider_order_id,omitempty"`
+	ProviderPaymentID string             `gorm:"index" json:"provider_payment_id,omitempty"`
+	Status            WebhookEventStatus `gorm:"type:varchar(20);default:'received';index" json:"status"`
+	Error             string             `gorm:"type:text" json:"error,omitempty"`
+	Attempts          int                `gorm:"default:0" json:"attempts"`
+	Payload           JSONB              `gorm:"type:jsonb" json:"payload"`
+	ProcessedAt       *time.Time         `json:"processed_at,omitempty"`
+	CreatedAt         time.Time          `json:"created_at"`
+	UpdatedAt         time.Time          `json:"updated_at"`
+}
+
+type RefundMethod string
+type RefundStatus string
+
+const (
+	RefundMethodGateway      RefundMethod = "gateway"       // Reversed through the payment provider
+	RefundMethodBankTransfer RefundMethod = "bank_transfer" // Paid out manually, e.g. for cash on delivery
+)
+
+const (
+	RefundStatusPending   RefundStatus = "pending"
+	RefundStatusProcessed RefundStatus = "processed"
+	RefundStatusFailed    RefundStatus = "failed"
+)
+
+// Refund represents money returned to a customer for an order
+type Refund struct {
+	ID               uint         `gorm:"primaryKey" json:"id"`
+	RefundNumber     string       `gorm:"uniqueIndex;not null" json:"refund_number"`
+	OrderID          uint         `gorm:"not null;index" json:"order_id"`
+	PaymentID        *uint        `gorm:"index" json:"payment_id,omitempty"` // Nil for bank transfers
+	ReturnID         *uint        `gorm:"index" json:"return_id,omitempty"`
+	Method           RefundMethod `gorm:"type:varchar(20);not null" json:"method"`
+	Status           RefundStatus `gorm:"type:varchar(20);default:'pending';index" json:"status"`
+	Amount           float64      `gorm:"not null" json:"amount"`
+	Currency         string       `gorm:"default:'INR'" json:"currency"`
+	ProviderRefundID string       `gorm:"index" json:"provider_refund_id,omitempty"` // Razorpay refund_id
+	Reason           string       `gorm:"type:text" json:"reason,omitempty"`
+	FailureReason    string       `gorm:"type:text" json:"failure_reason,omitempty"`
+
+	// Bank transfer payout details
+	BankAccountName   string `json:"bank_account_name,omitempty"`
+	BankAccountNumber string `json:"bank_account_number,omitempty"`
+	BankIFSC          string `gorm:"column:bank_ifsc" json:"bank_ifsc,omitempty"`
+	BankName          string `json:"bank_name,omitempty"`
+	BankReference     string `json:"bank_reference,omitempty"` // UTR of the payout
+
+	InitiatedBy uint       `json:"initiated_by"` // 0 when started by the gateway
+	ProcessedAt *time.Time `json:"processed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// Relationships
+	Order   Order    `gorm:"foreignKey:OrderID" json:"order,omitempty"`
+	Payment *Payment `gorm:"foreignKey:PaymentID" json:"-"`
+}
+
+const (
+	CouponTypePercentage = "percentage"
+	CouponTypeFixed      = "fixed"
+)
+
 // Coupon represents a discount coupon
 type Coupon struct {
 	ID             uint           `gorm:"primaryKey" json:"id"`
@@ -86,6 +164,14 @@ func (Payment) TableName() string {
 	return "payments"
 }
 
+func (PaymentWebhookEvent) TableName() string {
+	return "payment_webhook_events"
+}
+
+func (Refund) TableName() string {
+	return "refunds"
+}
+
 func (Coupon) TableName() string {
 	return "coupons"
 }
@@ -26,6 +26,32 @@ const (
 	ReturnReasonOther          ReturnReason = "other"
 )
 
+// returnTransitions lists the statuses each return status may move to
+var returnTransitions = map[ReturnStatus][]ReturnStatus{
+	ReturnStatusRequested: {ReturnStatusApproved, ReturnStatusRejected},
+	ReturnStatusApproved:  {ReturnStatusPickedUp, ReturnStatusReceived, ReturnStatusRejected, ReturnStatusRefunded},
+	ReturnStatusPickedUp:  {ReturnStatusReceived, ReturnStatusRefunded},
+	ReturnStatusReceived:  {ReturnStatusRefunded},
+	ReturnStatusRejected:  {},
+	ReturnStatusRefunded:  {},
+}
+
+// IsValid reports whether s is a known return status
+func (s ReturnStatus) IsValid() bool {
+	_, ok := returnTransitions[s]
+	return ok
+}
+
+// CanTransitionTo reports whether a return may move from s to next
+func (s ReturnStatus) CanTransitionTo(next ReturnStatus) bool {
+	for _, allowed := range returnTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
 // Return represents a return request
 type Return struct {
 	ID            uint           `gorm:"primaryKey" json:"id"`
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// PinCode maps an Indian postal code to its district
+type PinCode struct {
+	Code       string    `gorm:"primaryKey;size:6" json:"code"`
+	DistrictID uint      `gorm:"not null;index" json:"district_id"`
+	Area       string    `gorm:"size:100" json:"area,omitempty"` // Head post office, e.g. "Lucknow GPO"
+	Latitude   *float64  `json:"latitude,omitempty"`
+	Longitude  *float64  `json:"longitude,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	// Relationships
+	District District `gorm:"foreignKey:DistrictID" json:"district,omitempty"`
+}
+
+// PinCodeCoverage records that a courier delivers to a pin code
+type PinCodeCoverage struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	PinCode    string    `gorm:"size:6;not null;uniqueIndex:idx_pin_code_coverage" json:"pin_code"`
+	ProviderID uint      `gorm:"not null;uniqueIndex:idx_pin_code_coverage;index" json:"provider_id"`
+	Prepaid    bool      `gorm:"not null" json:"prepaid"`
+	COD        bool      `gorm:"column:cod;not null" json:"cod"`
+	ExtraDays  int       `gorm:"default:0" json:"extra_days"` // Added to estimates for remote (ODA) areas
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	// Relationships
+	Provider LogisticsProvider `gorm:"foreignKey:ProviderID" json:"provider,omitempty"`
+}
+
+func (PinCode) TableName() string {
+	return "pin_codes"
+}
+
+func (PinCodeCoverage) TableName() string {
+	return "pin_code_coverage"
+}
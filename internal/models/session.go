@@ -0,0 +1,96 @@
+package models
+
+import (
+	"time"
+)
+
+// RefreshToken is one link in a login session's chain of rotating refresh
+// tokens. Every token issued from one login shares a FamilyID; each refresh
+// marks the presented token used and issues the next one.
+type RefreshToken struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	UserID          uint       `gorm:"not null;index" json:"user_id"`
+	FamilyID        string     `gorm:"size:32;not null;index" json:"family_id"`
+	TokenHash       string     `gorm:"size:64;uniqueIndex;not null" json:"-"` // SHA-256 of the token
+	AccessJTI       string     `gorm:"size:32;not null" json:"-"`             // Access token issued alongside
+	AccessExpiresAt time.Time  `gorm:"not null" json:"-"`
+	ExpiresAt       time.Time  `gorm:"not null;index" json:"expires_at"`
+	UsedAt          *time.Time `json:"used_at,omitempty"` // Set once rotated
+	RevokedAt       *time.Time `json:"revoked_at,omitempty"`
+	IPAddress       string     `json:"ip_address,omitempty"`
+	UserAgent       string     `gorm:"type:text" json:"user_agent,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// RevokedToken denylists an access token by its jti until it expires
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey;size:32" json:"jti"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserTokenPurpose is what a single-use account token is for
+type UserTokenPurpose string
+
+const (
+	UserTokenEmailVerification UserTokenPurpose = "email_verification"
+	UserTokenPasswordReset     UserTokenPurpose = "password_reset"
+)
+
+// UserToken is a single-use, expiring token sent to a user by email. Only
+// its hash is stored.
+type UserToken struct {
+	ID        uint             `gorm:"primaryKey" json:"id"`
+	UserID    uint             `gorm:"not null;index" json:"user_id"`
+	Purpose   UserTokenPurpose `gorm:"type:varchar(30);not null" json:"purpose"`
+	TokenHash string           `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	Email     string           `gorm:"not null" json:"email"` // Address the token was sent to
+	ExpiresAt time.Time        `gorm:"not null;index" json:"expires_at"`
+	UsedAt    *time.Time       `json:"used_at,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// PhoneOTP is a one-time login code sent by SMS. Only its HMAC is stored.
+type PhoneOTP struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Phone      string     `gorm:"size:16;not null;index" json:"phone"` // E.164
+	CodeHash   string     `gorm:"size:64;not null" json:"-"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	Attempts   int        `gorm:"default:0" json:"attempts"` // Wrong codes entered
+	ConsumedAt *time.Time `json:"consumed_at,omitempty"`
+	IPAddress  string     `gorm:"size:45;index" json:"ip_address"`
+	CreatedAt  time.Time  `gorm:"index" json:"created_at"`
+}
+
+// OTPFailure records a wrong OTP entered, for per-phone and per-IP limits
+type OTPFailure struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Phone     string    `gorm:"size:16;not null;index" json:"phone"`
+	IPAddress string    `gorm:"size:45;index" json:"ip_address"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName overrides the table name
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+func (RevokedToken) TableName() string {
+	return "revoked_tokens"
+}
+
+func (UserToken) TableName() string {
+	return "user_tokens"
+}
+
+func (PhoneOTP) TableName() string {
+	return "phone_otps"
+}
+
+func (OTPFailure) TableName() string {
+	return "otp_failures"
+}
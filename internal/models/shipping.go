@@ -58,14 +58,15 @@ type Shipment struct {
 	TrackingEvents []TrackingEvent   `gorm:"foreignKey:ShipmentID" json:"tracking_events,omitempty"`
 }
 
-// TrackingEvent represents shipment tracking history
+// TrackingEvent represents shipment tracking history; a courier scan is
+// stored once per shipment, status and time
 type TrackingEvent struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
-	ShipmentID  uint      `gorm:"not null;index" json:"shipment_id"`
-	Status      string    `gorm:"not null" json:"status"`
+	ShipmentID  uint      `gorm:"not null;index;uniqueIndex:idx_tracking_event_scan" json:"shipment_id"`
+	Status      string    `gorm:"not null;uniqueIndex:idx_tracking_event_scan" json:"status"`
 	Location    string    `json:"location,omitempty"`
 	Description string    `gorm:"type:text" json:"description,omitempty"`
-	EventTime   time.Time `gorm:"not null" json:"event_time"`
+	EventTime   time.Time `gorm:"not null;uniqueIndex:idx_tracking_event_scan" json:"event_time"`
 	CreatedAt   time.Time `json:"created_at"`
 
 	// Relationships
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -20,7 +21,8 @@ type User struct {
 	Email         string         `gorm:"uniqueIndex;not null" json:"email" binding:"required,email"`
 	PasswordHash  string         `gorm:"not null" json:"-"`
 	Name          string         `gorm:"not null" json:"name" binding:"required"`
-	Phone         string         `gorm:"size:15" json:"phone"`
+	Phone         string         `gorm:"size:16;index" json:"phone"` // E.164 once verified
+	PhoneVerified bool           `gorm:"default:false" json:"phone_verified"`
 	Role          UserRole       `gorm:"type:varchar(20);default:'customer'" json:"role"`
 	EmailVerified bool           `gorm:"default:false" json:"email_verified"`
 	IsActive      bool           `gorm:"default:true" json:"is_active"`
@@ -66,6 +68,24 @@ type Address struct {
 	District District `gorm:"foreignKey:DistrictID" json:"district,omitempty"`
 }
 
+// phoneOnlyEmailDomain holds the placeholder emails of accounts registered
+// by phone OTP without an email address; .invalid never resolves
+const phoneOnlyEmailDomain = "phone.invalid"
+
+// PlaceholderEmail returns the placeholder email for a phone-only account
+func PlaceholderEmail(phone string) string {
+	return strings.TrimPrefix(phone, "+") + "@" + phoneOnlyEmailDomain
+}
+
+// ContactEmail returns the address to email the user at, or "" for
+// phone-only accounts
+func (u *User) ContactEmail() string {
+	if strings.HasSuffix(u.Email, "@"+phoneOnlyEmailDomain) {
+		return ""
+	}
+	return u.Email
+}
+
 // TableName overrides the table name
 func (User) TableName() string {
 	return "users"
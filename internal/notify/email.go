@@ -0,0 +1,124 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+)
+
+// SMTP sends email through an SMTP server, using STARTTLS when offered
+type SMTP struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     string
+}
+
+// NewSMTP creates an SMTP email sender; port defaults to 587
+func NewSMTP(host, port, username, password, from string) *SMTP {
+	if port == "" {
+		port = "587"
+	}
+	return &SMTP{host: host, port: port, username: username, password: password, from: from}
+}
+
+// Channel returns the email channel
+func (s *SMTP) Channel() models.NotificationChannel {
+	return models.NotificationChannelEmail
+}
+
+// Send delivers msg as a plain-text email
+func (s *SMTP) Send(ctx context.Context, to Recipient, msg Message) error {
+	if to.Email == "" {
+		return ErrNoAddress
+	}
+
+	var auth smtp.Auth
+	if s.username != "" {
+		auth = smtp.PlainAuth("", s.username, s.password, s.host)
+	}
+
+	addr := net.JoinHostPort(s.host, s.port)
+	if err := smtp.SendMail(addr, auth, s.from, []string{to.Email}, buildEmail(s.from, to, msg)); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	return nil
+}
+
+// MailSink is a local email stand-in that writes each message to a .eml
+// file in dir, or to the log when dir is empty
+type MailSink struct {
+	dir  string
+	from string
+}
+
+// NewMailSink creates a mail sink
+func NewMailSink(dir, from string) *MailSink {
+	if from == "" {
+		from = "noreply@localhost"
+	}
+	return &MailSink{dir: dir, from: from}
+}
+
+// Channel returns the email channel
+func (m *MailSink) Channel() models.NotificationChannel {
+	return models.NotificationChannelEmail
+}
+
+// Send writes msg to the sink
+func (m *MailSink) Send(ctx context.Context, to Recipient, msg Message) error {
+	if to.Email == "" {
+		return ErrNoAddress
+	}
+
+	if m.dir == "" {
+		log.Printf("[mail] to=%s subject=%q\n%s", to.Email, msg.Subject, msg.Body)
+		return nil
+	}
+
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405.000000000"), sanitizeFilename(to.Email))
+	return os.WriteFile(filepath.Join(m.dir, name), buildEmail(m.from, to, msg), 0o644)
+}
+
+// buildEmail formats an RFC 5322 plain-text message
+func buildEmail(from string, to Recipient, msg Message) []byte {
+	recipient := to.Email
+	if to.Name != "" {
+		recipient = fmt.Sprintf("%s <%s>", mime.QEncoding.Encode("utf-8", to.Name), to.Email)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", recipient)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+func sanitizeFilename(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r == ' ' {
+			return '_'
+		}
+		return r
+	}, s)
+}
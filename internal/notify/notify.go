@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+)
+
+// ErrNoSender is returned when no sender is registered for a channel
+var ErrNoSender = errors.New("no sender for notification channel")
+
+// ErrNoAddress is returned when the recipient has no address on a channel
+var ErrNoAddress = errors.New("recipient has no address for this channel")
+
+// Recipient is who a notification is delivered to
+type Recipient struct {
+	Name  string
+	Email string
+	Phone string
+}
+
+// Message is a rendered notification
+type Message struct {
+	Subject string // Email subject and in-app title
+	Body    string
+}
+
+// Sender delivers notifications over one channel
+type Sender interface {
+	// Channel returns the channel this sender serves
+	Channel() models.NotificationChannel
+
+	// Send delivers msg to the recipient
+	Send(ctx context.Context, to Recipient, msg Message) error
+}
+
+var (
+	mu       sync.RWMutex
+	senders  = map[models.NotificationChannel]Sender{}
+	initOnce sync.Once
+)
+
+// Register makes a sender available for its channel, replacing any previous one
+func Register(s Sender) {
+	mu.Lock()
+	defer mu.Unlock()
+	senders[s.Channel()] = s
+}
+
+// Get returns the sender registered for channel
+func Get(channel models.NotificationChannel) (Sender, error) {
+	initOnce.Do(registerFromEnv)
+
+	mu.RLock()
+	defer mu.RUnlock()
+	s, ok := senders[channel]
+	if !ok {
+		return nil, ErrNoSender
+	}
+	return s, nil
+}
+
+// Enabled reports whether notifications can be delivered on channel.
+// In-app notifications need no sender: the stored row is the delivery.
+func Enabled(channel models.NotificationChannel) bool {
+	if channel == models.NotificationChannelInApp {
+		return true
+	}
+	_, err := Get(channel)
+	return err == nil
+}
+
+// registerFromEnv registers the senders configured in the environment
+func registerFromEnv() {
+	if os.Getenv("EMAIL_ENABLED") == "true" {
+		Register(NewSMTP(
+			os.Getenv("SMTP_HOST"),
+			os.Getenv("SMTP_PORT"),
+			os.Getenv("SMTP_USER"),
+			os.Getenv("SMTP_PASSWORD"),
+			os.Getenv("SMTP_FROM_EMAIL"),
+		))
+	}
+	if os.Getenv("SMS_ENABLED") == "true" {
+		Register(NewTwilio(
+			os.Getenv("TWILIO_ACCOUNT_SID"),
+			os.Getenv("TWILIO_AUTH_TOKEN"),
+			os.Getenv("TWILIO_PHONE_NUMBER"),
+		))
+	}
+
+	// Local stand-ins for channels without a real sender; never in production
+	if os.Getenv("APP_ENV") == "production" {
+		return
+	}
+	mu.RLock()
+	_, hasEmail := senders[models.NotificationChannelEmail]
+	_, hasSMS := senders[models.NotificationChannelSMS]
+	mu.RUnlock()
+
+	if !hasEmail {
+		dir := os.Getenv("MAIL_SINK_DIR")
+		if os.Getenv("MAIL_SINK") == "log" {
+			dir = ""
+		} else if dir == "" {
+			dir = "./tmp/mail"
+		}
+		Register(NewMailSink(dir, os.Getenv("SMTP_FROM_EMAIL")))
+	}
+	if !hasSMS {
+		Register(NewSMSLogger())
+	}
+}
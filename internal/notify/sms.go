@@ -0,0 +1,107 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+)
+
+const twilioBaseURL = "https://api.twilio.com/2010-04-01"
+
+// Twilio sends SMS through the Twilio Messages API
+type Twilio struct {
+	accountSID string
+	authToken  string
+	from       string
+	client     *http.Client
+}
+
+// NewTwilio creates a Twilio SMS sender
+func NewTwilio(accountSID, authToken, from string) *Twilio {
+	return &Twilio{
+		accountSID: accountSID,
+		authToken:  authToken,
+		from:       from,
+		client:     &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Channel returns the SMS channel
+func (t *Twilio) Channel() models.NotificationChannel {
+	return models.NotificationChannelSMS
+}
+
+// Send delivers msg.Body as a text message
+func (t *Twilio) Send(ctx context.Context, to Recipient, msg Message) error {
+	if to.Phone == "" {
+		return ErrNoAddress
+	}
+
+	form := url.Values{
+		"From": {t.from},
+		"To":   {e164(to.Phone)},
+		"Body": {msg.Body},
+	}
+	endpoint := fmt.Sprintf("%s/Accounts/%s/Messages.json", twilioBaseURL, t.accountSID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(t.accountSID, t.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("twilio: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 300))
+		return fmt.Errorf("twilio: %d %s", resp.StatusCode, data)
+	}
+	return nil
+}
+
+// SMSLogger is a local SMS stand-in that writes messages to the log
+type SMSLogger struct{}
+
+// NewSMSLogger creates an SMS logger
+func NewSMSLogger() *SMSLogger {
+	return &SMSLogger{}
+}
+
+// Channel returns the SMS channel
+func (s *SMSLogger) Channel() models.NotificationChannel {
+	return models.NotificationChannelSMS
+}
+
+// Send logs the message
+func (s *SMSLogger) Send(ctx context.Context, to Recipient, msg Message) error {
+	if to.Phone == "" {
+		return ErrNoAddress
+	}
+	log.Printf("[sms] to=%s %s", e164(to.Phone), msg.Body)
+	return nil
+}
+
+// e164 adds the Indian country code to 10-digit mobile numbers
+func e164(phone string) string {
+	phone = strings.TrimSpace(phone)
+	if strings.HasPrefix(phone, "+") {
+		return phone
+	}
+	phone = strings.TrimPrefix(phone, "0")
+	if len(phone) == 10 {
+		return "+91" + phone
+	}
+	return "+" + phone
+}
@@ -0,0 +1,184 @@
+package notify
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"text/template"
+
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+)
+
+// ErrUnknownEvent is returned when no template exists for an event
+var ErrUnknownEvent = errors.New("unknown notification event")
+
+// Event identifies a notification template
+type Event string
+
+// Notification events
+const (
+	EventOrderPlaced    Event = "order_placed"
+	EventOrderShipped   Event = "order_shipped"
+	EventOrderDelivered Event = "order_delivered"
+	EventOrderRefunded  Event = "order_refunded"
+
+	// Account messages carry single-use links and codes and are sent
+	// directly, not through the notifications outbox
+	EventVerifyEmail   Event = "verify_email"
+	EventPasswordReset Event = "password_reset"
+	EventLoginOTP      Event = "login_otp"
+)
+
+// eventTemplate holds the texts of one event. Title is the email subject and
+// in-app title, Body the email and in-app text, and SMS the text message.
+type eventTemplate struct {
+	Type     models.NotificationType
+	Channels []models.NotificationChannel
+	Title    *template.Template
+	Body     *template.Template
+	SMS      *template.Template
+}
+
+var templates = map[Event]eventTemplate{
+	EventOrderPlaced: {
+		Type:     models.NotificationTypeOrder,
+		Channels: []models.NotificationChannel{models.NotificationChannelEmail, models.NotificationChannelSMS, models.NotificationChannelInApp},
+		Title:    parse("Order {{.OrderNumber}} placed"),
+		Body: parse(`Hi {{.Name}},
+
+Thank you for shopping with Tantuka. We have received your order {{.OrderNumber}} for {{.ItemCount}} item(s), totalling ₹{{printf "%.2f" .Total}}.
+{{if eq .PaymentMethod "cod"}}
+Please keep ₹{{printf "%.2f" .Total}} ready to pay on delivery.
+{{end}}
+We will let you know as soon as it ships.
+
+Team Tantuka`),
+		SMS: parse(`Tantuka: Order {{.OrderNumber}} for Rs.{{printf "%.2f" .Total}} is placed. We will notify you when it ships.`),
+	},
+	EventOrderShipped: {
+		Type:     models.NotificationTypeShipping,
+		Channels: []models.NotificationChannel{models.NotificationChannelEmail, models.NotificationChannelSMS, models.NotificationChannelInApp},
+		Title:    parse("Order {{.OrderNumber}} has shipped"),
+		Body: parse(`Hi {{.Name}},
+
+Your order {{.OrderNumber}} is on its way{{if .Courier}} with {{.Courier}}{{end}}.
+{{if .AWBNumber}}
+Tracking number: {{.AWBNumber}}{{end}}{{if .TrackingURL}}
+Track it here: {{.TrackingURL}}{{end}}
+
+Team Tantuka`),
+		SMS: parse(`Tantuka: Order {{.OrderNumber}} has shipped{{if .AWBNumber}}, AWB {{.AWBNumber}}{{end}}.{{if .TrackingURL}} Track: {{.TrackingURL}}{{end}}`),
+	},
+	EventOrderDelivered: {
+		Type:     models.NotificationTypeOrder,
+		Channels: []models.NotificationChannel{models.NotificationChannelEmail, models.NotificationChannelInApp},
+		Title:    parse("Order {{.OrderNumber}} delivered"),
+		Body: parse(`Hi {{.Name}},
+
+Your order {{.OrderNumber}} has been delivered. We hope you love it!
+
+If something isn't right, you can request a return from your orders page.
+
+Team Tantuka`),
+		SMS: parse(`Tantuka: Order {{.OrderNumber}} has been delivered.`),
+	},
+	EventOrderRefunded: {
+		Type:     models.NotificationTypePayment,
+		Channels: []models.NotificationChannel{models.NotificationChannelEmail, models.NotificationChannelSMS, models.NotificationChannelInApp},
+		Title:    parse("Refund of ₹{{printf \"%.2f\" .Amount}} for order {{.OrderNumber}}"),
+		Body: parse(`Hi {{.Name}},
+
+We have refunded ₹{{printf "%.2f" .Amount}} for your order {{.OrderNumber}}.
+{{if eq .Method "bank_transfer"}}
+The amount has been transferred to your bank account{{if .BankReference}} (reference {{.BankReference}}){{end}}.
+{{else}}
+It will reach your original payment method within 5-7 working days.
+{{end}}
+Team Tantuka`),
+		SMS: parse(`Tantuka: Refund of Rs.{{printf "%.2f" .Amount}} for order {{.OrderNumber}} has been processed.`),
+	},
+	EventVerifyEmail: {
+		Type:     models.NotificationTypeSystem,
+		Channels: []models.NotificationChannel{models.NotificationChannelEmail},
+		Title:    parse("Verify your email address"),
+		Body: parse(`Hi {{.Name}},
+
+Please confirm that this is your email address by opening the link below:
+
+{{.Link}}
+
+The link expires in {{.ExpiresIn}}. If you didn't create a Tantuka account, you can ignore this email.
+
+Team Tantuka`),
+	},
+	EventPasswordReset: {
+		Type:     models.NotificationTypeSystem,
+		Channels: []models.NotificationChannel{models.NotificationChannelEmail},
+		Title:    parse("Reset your Tantuka password"),
+		Body: parse(`Hi {{.Name}},
+
+We received a request to reset your password. Choose a new one here:
+
+{{.Link}}
+
+The link expires in {{.ExpiresIn}} and can be used once. If you didn't ask for this, ignore this email; your password stays the same.
+
+Team Tantuka`),
+	},
+	EventLoginOTP: {
+		Type:     models.NotificationTypeSystem,
+		Channels: []models.NotificationChannel{models.NotificationChannelSMS},
+		Title:    parse("Your Tantuka login code"),
+		Body:     parse(`{{.Code}} is your Tantuka login code. It expires in {{.ExpiresIn}}.`),
+		SMS:      parse(`{{.Code}} is your Tantuka login code. It expires in {{.ExpiresIn}}. Do not share it with anyone.`),
+	},
+}
+
+func parse(text string) *template.Template {
+	return template.Must(template.New("").Option("missingkey=zero").Parse(text))
+}
+
+// Channels returns the channels an event is sent on
+func Channels(event Event) []models.NotificationChannel {
+	return templates[event].Channels
+}
+
+// TypeOf returns the notification type of an event
+func TypeOf(event Event) models.NotificationType {
+	return templates[event].Type
+}
+
+// Render fills in an event's template for a channel
+func Render(event Event, channel models.NotificationChannel, data map[string]interface{}) (Message, error) {
+	t, ok := templates[event]
+	if !ok {
+		return Message{}, ErrUnknownEvent
+	}
+
+	title, err := execute(t.Title, data)
+	if err != nil {
+		return Message{}, err
+	}
+
+	body := t.Body
+	if channel == models.NotificationChannelSMS {
+		body = t.SMS
+	}
+	if body == nil {
+		return Message{}, ErrUnknownEvent
+	}
+	text, err := execute(body, data)
+	if err != nil {
+		return Message{}, err
+	}
+
+	return Message{Subject: title, Body: text}, nil
+}
+
+func execute(t *template.Template, data map[string]interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
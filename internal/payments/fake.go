@@ -0,0 +1,85 @@
+package payments
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+)
+
+// Fake is an in-process payment provider for tests and local development.
+// It speaks the Razorpay signature and webhook format, signed with a local secret.
+type Fake struct {
+	secret string
+}
+
+// NewFake creates a fake provider signing with secret
+func NewFake(secret string) *Fake {
+	return &Fake{secret: secret}
+}
+
+// Name returns the provider identifier
+func (f *Fake) Name() models.PaymentProvider {
+	return models.PaymentProviderFake
+}
+
+// KeyID returns a placeholder public key
+func (f *Fake) KeyID() string {
+	return "fake_key"
+}
+
+// CreateOrder returns a locally generated order ID without any network call
+func (f *Fake) CreateOrder(ctx context.Context, req OrderRequest) (*ProviderOrder, error) {
+	return &ProviderOrder{
+		ID:       "order_fake_" + randomID(),
+		Amount:   req.Amount,
+		Currency: req.Currency,
+		Status:   "created",
+	}, nil
+}
+
+// VerifyPayment checks a signature produced by SignPayment
+func (f *Fake) VerifyPayment(providerOrderID, providerPaymentID, signature string) error {
+	return verifyHMAC(f.secret, []byte(providerOrderID+"|"+providerPaymentID), signature)
+}
+
+// ParseWebhook verifies a body signed by SignWebhook and decodes it
+func (f *Fake) ParseWebhook(header http.Header, body []byte) (*WebhookEvent, error) {
+	if err := verifyHMAC(f.secret, body, header.Get("X-Razorpay-Signature")); err != nil {
+		return nil, err
+	}
+	return parseRazorpayEvent(header.Get("X-Razorpay-Event-Id"), body)
+}
+
+// Refund processes the refund immediately without any network call
+func (f *Fake) Refund(ctx context.Context, req RefundRequest) (*ProviderRefund, error) {
+	return &ProviderRefund{
+		ID:     "rfnd_fake_" + randomID(),
+		Amount: req.Amount,
+		Status: RefundStatusProcessed,
+	}, nil
+}
+
+// NewPaymentID returns a fake provider payment ID
+func (f *Fake) NewPaymentID() string {
+	return "pay_fake_" + randomID()
+}
+
+// SignPayment returns the signature a client would receive after paying
+func (f *Fake) SignPayment(providerOrderID, providerPaymentID string) string {
+	return sign(f.secret, []byte(providerOrderID+"|"+providerPaymentID))
+}
+
+// SignWebhook returns the X-Razorpay-Signature for a webhook body
+func (f *Fake) SignWebhook(body []byte) string {
+	return sign(f.secret, body)
+}
+
+// randomID returns a short random hex identifier
+func randomID() string {
+	b := make([]byte, 7)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
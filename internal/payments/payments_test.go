@@ -0,0 +1,213 @@
+package payments
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+)
+
+func TestRazorpayVerifyPayment(t *testing.T) {
+	r := NewRazorpay("key", "key_secret", "hook_secret")
+	valid := sign("key_secret", []byte("order_1|pay_1"))
+
+	tests := []struct {
+		name      string
+		orderID   string
+		paymentID string
+		signature string
+		wantErr   bool
+	}{
+		{"valid", "order_1", "pay_1", valid, false},
+		{"other payment", "order_1", "pay_2", valid, true},
+		{"other order", "order_2", "pay_1", valid, true},
+		{"webhook secret", "order_1", "pay_1", sign("hook_secret", []byte("order_1|pay_1")), true},
+		{"empty signature", "order_1", "pay_1", "", true},
+		{"garbage", "order_1", "pay_1", "not-hex", true},
+	}
+
+	for _, tt := range tests {
+		err := r.VerifyPayment(tt.orderID, tt.paymentID, tt.signature)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: got %v, want ErrInvalidSignature", tt.name, err)
+		}
+	}
+}
+
+func TestVerifyHMACRejectsEmptySecret(t *testing.T) {
+	if err := verifyHMAC("", []byte("body"), sign("", []byte("body"))); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("got %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestRazorpayParseWebhook(t *testing.T) {
+	r := NewRazorpay("key", "key_secret", "hook_secret")
+	body := []byte(`{"event":"payment.captured","payload":{"payment":{"entity":{"id":"pay_1","order_id":"order_1","method":"upi","amount":49900}}}}`)
+
+	tests := []struct {
+		name      string
+		body      []byte
+		signature string
+		wantErr   bool
+	}{
+		{"valid", body, sign("hook_secret", body), false},
+		{"tampered amount", bytes.Replace(body, []byte("49900"), []byte("100"), 1), sign("hook_secret", body), true},
+		{"key secret", body, sign("key_secret", body), true},
+		{"unsigned", body, "", true},
+	}
+
+	for _, tt := range tests {
+		header := http.Header{}
+		header.Set("X-Razorpay-Signature", tt.signature)
+		header.Set("X-Razorpay-Event-Id", "evt_1")
+
+		event, err := r.ParseWebhook(header, tt.body)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if event.ID != "evt_1" || event.Type != EventPaymentCaptured || event.ProviderOrderID != "order_1" ||
+			event.ProviderPaymentID != "pay_1" || event.Method != "upi" || event.Amount != 49900 {
+			t.Errorf("%s: unexpected event %+v", tt.name, event)
+		}
+	}
+}
+
+func TestParseRazorpayEvent(t *testing.T) {
+	refund := []byte(`{"event":"refund.processed","payload":{"refund":{"entity":{"id":"rfnd_1","payment_id":"pay_1","amount":10000,"receipt":"RF-1"}}}}`)
+
+	event, err := parseRazorpayEvent("", refund)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.RefundID != "rfnd_1" || event.RefundAmount != 10000 || event.RefundReceipt != "RF-1" {
+		t.Errorf("unexpected refund fields %+v", event)
+	}
+	if event.ProviderPaymentID != "pay_1" {
+		t.Errorf("ProviderPaymentID = %q, want the refund's payment", event.ProviderPaymentID)
+	}
+	if len(event.ID) != 64 {
+		t.Errorf("ID = %q, want a content hash when the header is missing", event.ID)
+	}
+
+	again, _ := parseRazorpayEvent("", refund)
+	if again.ID != event.ID {
+		t.Error("content hash ID is not stable")
+	}
+
+	if _, err := parseRazorpayEvent("evt", []byte("{")); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
+
+func TestFakeSignatures(t *testing.T) {
+	fake := NewFake("local_secret")
+	other := NewFake("other_secret")
+
+	paymentID := fake.NewPaymentID()
+	signature := fake.SignPayment("order_fake_1", paymentID)
+	if err := fake.VerifyPayment("order_fake_1", paymentID, signature); err != nil {
+		t.Errorf("own payment signature rejected: %v", err)
+	}
+	if err := other.VerifyPayment("order_fake_1", paymentID, signature); err == nil {
+		t.Error("payment signature accepted with another secret")
+	}
+
+	body := []byte(`{"event":"payment.failed","payload":{"payment":{"entity":{"id":"pay_1","order_id":"order_1","error_code":"BAD_REQUEST_ERROR"}}}}`)
+	header := http.Header{}
+	header.Set("X-Razorpay-Signature", fake.SignWebhook(body))
+	if _, err := fake.ParseWebhook(header, body); err != nil {
+		t.Errorf("own webhook rejected: %v", err)
+	}
+	if _, err := other.ParseWebhook(header, body); err == nil {
+		t.Error("webhook accepted with another secret")
+	}
+
+	if err := NewFake("").VerifyPayment("order_1", "pay_1", sign("", []byte("order_1|pay_1"))); err == nil {
+		t.Error("fake without a secret accepted a signature")
+	}
+}
+
+func TestDefaultProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		appEnv   string
+		razorpay string
+		secret   string
+		want     models.PaymentProvider // Empty when checkout has no provider
+	}{
+		{"razorpay", "production", "true", "", models.PaymentProviderRazorpay},
+		{"fake with secret", "development", "false", "local_secret", models.PaymentProviderFake},
+		{"fake without secret", "development", "false", "", ""},
+		{"fake in production", "production", "false", "local_secret", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reloadProviders(t)
+			t.Setenv("APP_ENV", tt.appEnv)
+			t.Setenv("PAYMENT_PROVIDER", "")
+			t.Setenv("RAZORPAY_ENABLED", tt.razorpay)
+			t.Setenv("FAKE_PAYMENT_SECRET", tt.secret)
+
+			provider, err := Default()
+			if tt.want == "" {
+				if !errors.Is(err, ErrUnknownProvider) {
+					t.Errorf("got %v, %v; want ErrUnknownProvider", provider, err)
+				}
+				return
+			}
+			if err != nil || provider.Name() != tt.want {
+				t.Errorf("got %v, %v; want %s", provider, err, tt.want)
+			}
+		})
+	}
+}
+
+// reloadProviders empties the registry so the next Get registers providers
+// from the environment again, and puts the current providers back after t
+func reloadProviders(t *testing.T) {
+	initOnce.Do(registerFromEnv)
+	mu.Lock()
+	saved := providers
+	providers = map[models.PaymentProvider]Provider{}
+	initOnce = sync.Once{}
+	mu.Unlock()
+
+	t.Cleanup(func() {
+		mu.Lock()
+		providers = saved
+		initOnce = sync.Once{}
+		initOnce.Do(func() {})
+		mu.Unlock()
+	})
+}
+
+func TestPaiseConversion(t *testing.T) {
+	tests := []struct {
+		rupees float64
+		paise  int64
+	}{
+		{499, 49900},
+		{0.1 + 0.2, 30},
+		{1299.99, 129999},
+	}
+
+	for _, tt := range tests {
+		if got := ToPaise(tt.rupees); got != tt.paise {
+			t.Errorf("ToPaise(%v) = %d, want %d", tt.rupees, got, tt.paise)
+		}
+	}
+	if got := FromPaise(129999); got != 1299.99 {
+		t.Errorf("FromPaise(129999) = %v, want 1299.99", got)
+	}
+}
@@ -0,0 +1,167 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+)
+
+// ErrInvalidSignature is returned when a payment or webhook signature does not match
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// ErrUnknownProvider is returned when no provider is registered under a name
+var ErrUnknownProvider = errors.New("unknown payment provider")
+
+// Webhook event types (Razorpay naming)
+const (
+	EventPaymentCaptured = "payment.captured"
+	EventPaymentFailed   = "payment.failed"
+	EventRefundProcessed = "refund.processed"
+	EventRefundFailed    = "refund.failed"
+)
+
+// Refund statuses reported by providers (Razorpay naming)
+const (
+	RefundStatusPending   = "pending"
+	RefundStatusProcessed = "processed"
+	RefundStatusFailed    = "failed"
+)
+
+// OrderRequest represents a request to open a payment order with the provider
+type OrderRequest struct {
+	Amount   int64             // In paise
+	Currency string            // INR
+	Receipt  string            // Our order number
+	Notes    map[string]string // Free-form metadata stored with the provider
+}
+
+// ProviderOrder represents a payment order created at the provider
+type ProviderOrder struct {
+	ID       string `json:"id"`
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+	Status   string `json:"status"`
+}
+
+// RefundRequest represents a request to refund all or part of a captured payment
+type RefundRequest struct {
+	ProviderPaymentID string
+	Amount            int64             // In paise
+	Receipt           string            // Our refund number
+	Notes             map[string]string // Free-form metadata stored with the provider
+}
+
+// ProviderRefund represents a refund created at the provider
+type ProviderRefund struct {
+	ID     string `json:"id"`
+	Amount int64  `json:"amount"`
+	Status string `json:"status"`
+}
+
+// WebhookEvent represents a parsed, signature-verified provider webhook
+type WebhookEvent struct {
+	ID                string
+	Type              string
+	ProviderOrderID   string
+	ProviderPaymentID string
+	Method            string
+	Amount            int64 // In paise
+	ErrorCode         string
+	ErrorDescription  string
+	RefundID          string
+	RefundAmount      int64  // In paise
+	RefundReceipt     string // Our refund number, when we created the refund
+	Payload           map[string]interface{}
+}
+
+// Provider is a payment gateway integration
+type Provider interface {
+	// Name returns the provider identifier stored on models.Payment
+	Name() models.PaymentProvider
+
+	// KeyID returns the public key the client checkout needs
+	KeyID() string
+
+	// CreateOrder opens a payment order for the client to pay against
+	CreateOrder(ctx context.Context, req OrderRequest) (*ProviderOrder, error)
+
+	// VerifyPayment checks the signature returned to the client after payment
+	VerifyPayment(providerOrderID, providerPaymentID, signature string) error
+
+	// ParseWebhook verifies and decodes a webhook request
+	ParseWebhook(header http.Header, body []byte) (*WebhookEvent, error)
+
+	// Refund returns money for a captured payment; partial amounts are allowed
+	Refund(ctx context.Context, req RefundRequest) (*ProviderRefund, error)
+}
+
+var (
+	mu        sync.RWMutex
+	providers = map[models.PaymentProvider]Provider{}
+	initOnce  sync.Once
+)
+
+// Register makes a provider available by name, replacing any previous one
+func Register(p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	providers[p.Name()] = p
+}
+
+// Get returns the provider registered under name
+func Get(name models.PaymentProvider) (Provider, error) {
+	initOnce.Do(registerFromEnv)
+
+	mu.RLock()
+	defer mu.RUnlock()
+	p, ok := providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return p, nil
+}
+
+// Default returns the provider selected by PAYMENT_PROVIDER
+// (razorpay when RAZORPAY_ENABLED=true, otherwise the offline fake)
+func Default() (Provider, error) {
+	name := os.Getenv("PAYMENT_PROVIDER")
+	if name == "" {
+		name = string(models.PaymentProviderFake)
+		if os.Getenv("RAZORPAY_ENABLED") == "true" {
+			name = string(models.PaymentProviderRazorpay)
+		}
+	}
+	return Get(models.PaymentProvider(name))
+}
+
+// registerFromEnv registers the providers configured in the environment
+func registerFromEnv() {
+	if os.Getenv("RAZORPAY_ENABLED") == "true" {
+		Register(NewRazorpay(
+			os.Getenv("RAZORPAY_KEY_ID"),
+			os.Getenv("RAZORPAY_KEY_SECRET"),
+			os.Getenv("RAZORPAY_WEBHOOK_SECRET"),
+		))
+	}
+
+	// Never accept fake payments in production, and only with a secret of
+	// our own: anyone knowing it can mark orders paid
+	if secret := os.Getenv("FAKE_PAYMENT_SECRET"); secret != "" && os.Getenv("APP_ENV") != "production" {
+		Register(NewFake(secret))
+	}
+}
+
+// ToPaise converts a rupee amount to paise
+func ToPaise(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// FromPaise converts paise to a rupee amount
+func FromPaise(amount int64) float64 {
+	return float64(amount) / 100
+}
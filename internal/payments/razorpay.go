@@ -0,0 +1,219 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+)
+
+const razorpayBaseURL = "https://api.razorpay.com/v1"
+
+// Razorpay is the Razorpay payment gateway
+type Razorpay struct {
+	keyID         string
+	keySecret     string
+	webhookSecret string
+	baseURL       string
+	client        *http.Client
+}
+
+// NewRazorpay creates a Razorpay provider
+func NewRazorpay(keyID, keySecret, webhookSecret string) *Razorpay {
+	return &Razorpay{
+		keyID:         keyID,
+		keySecret:     keySecret,
+		webhookSecret: webhookSecret,
+		baseURL:       razorpayBaseURL,
+		client:        &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Name returns the provider identifier
+func (r *Razorpay) Name() models.PaymentProvider {
+	return models.PaymentProviderRazorpay
+}
+
+// KeyID returns the public key for Razorpay Checkout
+func (r *Razorpay) KeyID() string {
+	return r.keyID
+}
+
+// CreateOrder creates a Razorpay order
+func (r *Razorpay) CreateOrder(ctx context.Context, req OrderRequest) (*ProviderOrder, error) {
+	body := map[string]interface{}{
+		"amount":   req.Amount,
+		"currency": req.Currency,
+		"receipt":  req.Receipt,
+	}
+	if len(req.Notes) > 0 {
+		body["notes"] = req.Notes
+	}
+
+	var order ProviderOrder
+	if err := r.do(ctx, http.MethodPost, "/orders", body, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// VerifyPayment checks the razorpay_signature returned by Checkout
+func (r *Razorpay) VerifyPayment(providerOrderID, providerPaymentID, signature string) error {
+	return verifyHMAC(r.keySecret, []byte(providerOrderID+"|"+providerPaymentID), signature)
+}
+
+// ParseWebhook verifies X-Razorpay-Signature and decodes the event
+func (r *Razorpay) ParseWebhook(header http.Header, body []byte) (*WebhookEvent, error) {
+	if err := verifyHMAC(r.webhookSecret, body, header.Get("X-Razorpay-Signature")); err != nil {
+		return nil, err
+	}
+	return parseRazorpayEvent(header.Get("X-Razorpay-Event-Id"), body)
+}
+
+// Refund creates a Razorpay refund against a captured payment
+func (r *Razorpay) Refund(ctx context.Context, req RefundRequest) (*ProviderRefund, error) {
+	body := map[string]interface{}{
+		"amount":  req.Amount,
+		"receipt": req.Receipt,
+	}
+	if len(req.Notes) > 0 {
+		body["notes"] = req.Notes
+	}
+
+	var refund ProviderRefund
+	if err := r.do(ctx, http.MethodPost, "/payments/"+req.ProviderPaymentID+"/refund", body, &refund); err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+// do sends an authenticated JSON request to the Razorpay API
+func (r *Razorpay) do(ctx context.Context, method, path string, in, out interface{}) error {
+	payload, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, r.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(r.keyID, r.keySecret)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("razorpay: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error struct {
+				Code        string `json:"code"`
+				Description string `json:"description"`
+			} `json:"error"`
+		}
+		json.Unmarshal(data, &apiErr)
+		return fmt.Errorf("razorpay: %s %s: %d %s %s", method, path, resp.StatusCode, apiErr.Error.Code, apiErr.Error.Description)
+	}
+
+	return json.Unmarshal(data, out)
+}
+
+// razorpayEvent is the webhook envelope sent by Razorpay
+type razorpayEvent struct {
+	Event   string `json:"event"`
+	Payload struct {
+		Payment struct {
+			Entity struct {
+				ID               string `json:"id"`
+				OrderID          string `json:"order_id"`
+				Method           string `json:"method"`
+				Amount           int64  `json:"amount"`
+				ErrorCode        string `json:"error_code"`
+				ErrorDescription string `json:"error_description"`
+			} `json:"entity"`
+		} `json:"payment"`
+		Refund struct {
+			Entity struct {
+				ID        string `json:"id"`
+				PaymentID string `json:"payment_id"`
+				Amount    int64  `json:"amount"`
+				Receipt   string `json:"receipt"`
+			} `json:"entity"`
+		} `json:"refund"`
+	} `json:"payload"`
+}
+
+// parseRazorpayEvent decodes a Razorpay-format webhook body
+func parseRazorpayEvent(eventID string, body []byte) (*WebhookEvent, error) {
+	var raw razorpayEvent
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+
+	var payload map[string]interface{}
+	json.Unmarshal(body, &payload)
+
+	// Fall back to a content hash when the event ID header is missing
+	if eventID == "" {
+		sum := sha256.Sum256(body)
+		eventID = hex.EncodeToString(sum[:])
+	}
+
+	payment := raw.Payload.Payment.Entity
+	refund := raw.Payload.Refund.Entity
+	event := &WebhookEvent{
+		ID:                eventID,
+		Type:              raw.Event,
+		ProviderOrderID:   payment.OrderID,
+		ProviderPaymentID: payment.ID,
+		Method:            payment.Method,
+		Amount:            payment.Amount,
+		ErrorCode:         payment.ErrorCode,
+		ErrorDescription:  payment.ErrorDescription,
+		RefundID:          refund.ID,
+		RefundAmount:      refund.Amount,
+		RefundReceipt:     refund.Receipt,
+		Payload:           payload,
+	}
+
+	// Refund events may carry only the refund entity
+	if event.ProviderPaymentID == "" {
+		event.ProviderPaymentID = refund.PaymentID
+	}
+
+	return event, nil
+}
+
+// sign returns the hex HMAC-SHA256 of data
+func sign(secret string, data []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyHMAC compares signature with the HMAC of data in constant time
+func verifyHMAC(secret string, data []byte, signature string) error {
+	if secret == "" || signature == "" {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(sign(secret, data)), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package serviceability
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeDB is a GORM handle that builds Postgres SQL without a server. It
+// records every statement, answers queries with canned rows by table and
+// keeps the values passed to Create.
+type fakeDB struct {
+	*gorm.DB
+	statements []string
+	rows       map[string]interface{} // Table name to a slice for the query's destination
+	created    []interface{}
+	nextID     uint
+}
+
+func newFakeDB(t *testing.T) *fakeDB {
+	t.Helper()
+	f := &fakeDB{rows: map[string]interface{}{}, nextID: 1000}
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: fakePool{}}), &gorm.Config{
+		DryRun: true,
+		Logger: fakeLogger{f},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Callback().Query().After("gorm:query").Register("test:rows", f.fill); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Callback().Create().After("gorm:create").Register("test:created", f.create); err != nil {
+		t.Fatal(err)
+	}
+	f.DB = db
+	return f
+}
+
+// fill copies the canned rows for the queried table into the destination
+func (f *fakeDB) fill(db *gorm.DB) {
+	rows, ok := f.rows[db.Statement.Table]
+	if !ok {
+		return
+	}
+	dest := reflect.ValueOf(db.Statement.Dest)
+	if dest.Kind() == reflect.Ptr && dest.Elem().Type() == reflect.TypeOf(rows) {
+		dest.Elem().Set(reflect.ValueOf(rows))
+	}
+}
+
+// create gives new records an ID, as the database would, and keeps a copy
+func (f *fakeDB) create(db *gorm.DB) {
+	value := reflect.Indirect(reflect.ValueOf(db.Statement.Dest))
+	if value.Kind() == reflect.Struct {
+		if id := value.FieldByName("ID"); id.IsValid() && id.CanSet() {
+			f.nextID++
+			id.SetUint(uint64(f.nextID))
+		}
+	}
+	copied := reflect.New(value.Type()).Elem()
+	if value.Kind() == reflect.Slice {
+		copied = reflect.AppendSlice(reflect.MakeSlice(value.Type(), 0, value.Len()), value)
+	} else {
+		copied.Set(value)
+	}
+	f.created = append(f.created, copied.Interface())
+}
+
+// find returns the recorded statements containing all of parts
+func (f *fakeDB) find(parts ...string) []string {
+	var found []string
+	for _, stmt := range f.statements {
+		matched := true
+		for _, part := range parts {
+			if !strings.Contains(stmt, part) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			found = append(found, stmt)
+		}
+	}
+	return found
+}
+
+// fakeLogger records the SQL of each statement
+type fakeLogger struct{ f *fakeDB }
+
+func (l fakeLogger) LogMode(logger.LogLevel) logger.Interface    { return l }
+func (fakeLogger) Info(context.Context, string, ...interface{})  {}
+func (fakeLogger) Warn(context.Context, string, ...interface{})  {}
+func (fakeLogger) Error(context.Context, string, ...interface{}) {}
+func (l fakeLogger) Trace(_ context.Context, _ time.Time, fc func() (string, int64), _ error) {
+	sql, _ := fc()
+	l.f.statements = append(l.f.statements, sql)
+}
+
+var errNoServer = errors.New("fake database has no server")
+
+// fakePool stands in for the connection pool; dry runs only use it to
+// begin and end transactions
+type fakePool struct{}
+
+func (fakePool) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, errNoServer
+}
+
+func (fakePool) ExecContext(context.Context, string, ...interface{}) (sql.Result, error) {
+	return nil, errNoServer
+}
+
+func (fakePool) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	return nil, errNoServer
+}
+
+func (fakePool) QueryRowContext(context.Context, string, ...interface{}) *sql.Row {
+	return nil
+}
+
+func (fakePool) BeginTx(context.Context, *sql.TxOptions) (gorm.ConnPool, error) {
+	return &fakeTx{}, nil
+}
+
+// fakeTx is a transaction on fakePool
+type fakeTx struct{ fakePool }
+
+func (*fakeTx) Commit() error   { return nil }
+func (*fakeTx) Rollback() error { return nil }
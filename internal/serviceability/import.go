@@ -0,0 +1,442 @@
+package serviceability
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+)
+
+// ErrInvalidCSV is returned for files that can't be imported at all, such
+// as ones missing a required column
+var ErrInvalidCSV = errors.New("invalid CSV file")
+
+// maxReportedErrors caps the row errors kept in an ImportResult
+const maxReportedErrors = 50
+
+// importBatchSize is how many rows are upserted per statement
+const importBatchSize = 500
+
+// ImportResult summarises a CSV import
+type ImportResult struct {
+	Rows             int      `json:"rows"`
+	Imported         int      `json:"imported"`
+	Skipped          int      `json:"skipped"`
+	DistrictsCreated int      `json:"districts_created,omitempty"`
+	Errors           []string `json:"errors,omitempty"` // The first few rejected rows
+}
+
+func (r *ImportResult) skip(line int, format string, args ...interface{}) {
+	r.Skipped++
+	if len(r.Errors) < maxReportedErrors {
+		r.Errors = append(r.Errors, fmt.Sprintf("line %d: %s", line, fmt.Sprintf(format, args...)))
+	}
+}
+
+// ImportPinCodes loads pin code to district mappings from CSV, such as the
+// India Post directory. Columns are found by header name: pincode,
+// district and statename are required; officename, latitude and longitude
+// are optional. States must already exist; missing districts are created.
+// Existing pin codes are updated, and the first row wins for pin codes
+// listed more than once.
+func ImportPinCodes(db *gorm.DB, r io.Reader) (*ImportResult, error) {
+	reader, columns, err := openCSV(r)
+	if err != nil {
+		return nil, err
+	}
+	pinCol, err := columns.require("pincode", "pin_code", "pin")
+	if err != nil {
+		return nil, err
+	}
+	districtCol, err := columns.require("district", "districtname", "district_name")
+	if err != nil {
+		return nil, err
+	}
+	stateCol, err := columns.require("statename", "state", "state_name")
+	if err != nil {
+		return nil, err
+	}
+	areaCol := columns.find("officename", "office_name", "area")
+	latCol := columns.find("latitude", "lat")
+	lonCol := columns.find("longitude", "long", "lng", "lon")
+
+	result := &ImportResult{}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		states, err := loadStates(tx)
+		if err != nil {
+			return err
+		}
+		districts, err := loadDistricts(tx)
+		if err != nil {
+			return err
+		}
+
+		seen := map[string]bool{}
+		batch := make([]models.PinCode, 0, importBatchSize)
+		for line := 2; ; line++ {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				var parseErr *csv.ParseError
+				if errors.As(err, &parseErr) {
+					result.Rows++
+					result.skip(line, "%v", parseErr.Err)
+					continue
+				}
+				return err
+			}
+			result.Rows++
+
+			pin, err := NormalizePinCode(field(record, pinCol))
+			if err != nil {
+				result.skip(line, "invalid pin code %q", field(record, pinCol))
+				continue
+			}
+			if seen[pin] {
+				result.Skipped++
+				continue
+			}
+
+			state, ok := states[nameKey(field(record, stateCol))]
+			if !ok {
+				result.skip(line, "unknown state %q", field(record, stateCol))
+				continue
+			}
+			districtName := field(record, districtCol)
+			if districtName == "" {
+				result.skip(line, "missing district")
+				continue
+			}
+			key := districtKey(state.ID, districtName)
+			districtID, ok := districts[key]
+			if !ok {
+				district := models.District{StateID: state.ID, Name: titleCase(districtName), IsActive: true}
+				if err := tx.Create(&district).Error; err != nil {
+					return err
+				}
+				districts[key] = district.ID
+				districtID = district.ID
+				result.DistrictsCreated++
+			}
+
+			seen[pin] = true
+			batch = append(batch, models.PinCode{
+				Code:       pin,
+				DistrictID: districtID,
+				Area:       titleCase(field(record, areaCol)),
+				Latitude:   parseCoordinate(field(record, latCol), 90),
+				Longitude:  parseCoordinate(field(record, lonCol), 180),
+			})
+			if len(batch) == importBatchSize {
+				if err := upsertPinCodes(tx, batch); err != nil {
+					return err
+				}
+				result.Imported += len(batch)
+				batch = batch[:0]
+			}
+		}
+
+		if err := upsertPinCodes(tx, batch); err != nil {
+			return err
+		}
+		result.Imported += len(batch)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ImportCoverage loads courier coverage from CSV with the columns pincode,
+// courier (a logistics provider code) and optionally prepaid, cod and
+// extra_days. Existing rows for a pin code and courier are updated. With
+// replace, the listed couriers' pin codes that aren't in the file are
+// removed, so a courier's full pin code list can be loaded as is.
+func ImportCoverage(db *gorm.DB, r io.Reader, replace bool) (*ImportResult, error) {
+	reader, columns, err := openCSV(r)
+	if err != nil {
+		return nil, err
+	}
+	pinCol, err := columns.require("pincode", "pin_code", "pin")
+	if err != nil {
+		return nil, err
+	}
+	courierCol, err := columns.require("courier", "courier_code", "provider")
+	if err != nil {
+		return nil, err
+	}
+	prepaidCol := columns.find("prepaid")
+	codCol := columns.find("cod")
+	extraCol := columns.find("extra_days", "oda_days")
+
+	result := &ImportResult{}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var providers []models.LogisticsProvider
+		if err := tx.Find(&providers).Error; err != nil {
+			return err
+		}
+		providerIDs := make(map[string]uint, len(providers))
+		for _, p := range providers {
+			providerIDs[strings.ToLower(p.Code)] = p.ID
+		}
+
+		rows := make([]models.PinCodeCoverage, 0, importBatchSize)
+		listed := map[uint]bool{}
+		for line := 2; ; line++ {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				var parseErr *csv.ParseError
+				if errors.As(err, &parseErr) {
+					result.Rows++
+					result.skip(line, "%v", parseErr.Err)
+					continue
+				}
+				return err
+			}
+			result.Rows++
+
+			pin, err := NormalizePinCode(field(record, pinCol))
+			if err != nil {
+				result.skip(line, "invalid pin code %q", field(record, pinCol))
+				continue
+			}
+			providerID, ok := providerIDs[strings.ToLower(field(record, courierCol))]
+			if !ok {
+				result.skip(line, "unknown courier %q", field(record, courierCol))
+				continue
+			}
+			prepaid, err := parseFlag(field(record, prepaidCol), true)
+			if err != nil {
+				result.skip(line, "invalid prepaid value %q", field(record, prepaidCol))
+				continue
+			}
+			cod, err := parseFlag(field(record, codCol), false)
+			if err != nil {
+				result.skip(line, "invalid cod value %q", field(record, codCol))
+				continue
+			}
+			extraDays := 0
+			if value := field(record, extraCol); value != "" {
+				extraDays, err = strconv.Atoi(value)
+				if err != nil || extraDays < 0 {
+					result.skip(line, "invalid extra_days %q", value)
+					continue
+				}
+			}
+
+			if replace && !listed[providerID] {
+				if err := tx.Where("provider_id = ?", providerID).Delete(&models.PinCodeCoverage{}).Error; err != nil {
+					return err
+				}
+			}
+			listed[providerID] = true
+
+			rows = append(rows, models.PinCodeCoverage{
+				PinCode:    pin,
+				ProviderID: providerID,
+				Prepaid:    prepaid,
+				COD:        cod,
+				ExtraDays:  extraDays,
+			})
+			if len(rows) == importBatchSize {
+				if err := upsertCoverage(tx, rows); err != nil {
+					return err
+				}
+				result.Imported += len(rows)
+				rows = rows[:0]
+			}
+		}
+
+		if err := upsertCoverage(tx, rows); err != nil {
+			return err
+		}
+		result.Imported += len(rows)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func upsertPinCodes(tx *gorm.DB, rows []models.PinCode) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "code"}},
+		DoUpdates: clause.AssignmentColumns([]string{"district_id", "area", "latitude", "longitude", "updated_at"}),
+	}).Create(&rows).Error
+}
+
+func upsertCoverage(tx *gorm.DB, rows []models.PinCodeCoverage) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	// A pin code listed twice for a courier in one batch can't be upserted
+	// by a single statement, so keep the last
+	unique := make([]models.PinCodeCoverage, 0, len(rows))
+	index := map[string]int{}
+	for _, row := range rows {
+		key := fmt.Sprintf("%s/%d", row.PinCode, row.ProviderID)
+		if i, ok := index[key]; ok {
+			unique[i] = row
+			continue
+		}
+		index[key] = len(unique)
+		unique = append(unique, row)
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "pin_code"}, {Name: "provider_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"prepaid", "cod", "extra_days", "updated_at"}),
+	}).Create(&unique).Error
+}
+
+// loadStates indexes Indian states by name and code
+func loadStates(tx *gorm.DB) (map[string]models.State, error) {
+	var states []models.State
+	if err := tx.Joins("JOIN countries ON countries.id = states.country_id").
+		Where("countries.code = ?", "IN").
+		Find(&states).Error; err != nil {
+		return nil, err
+	}
+	byName := make(map[string]models.State, 2*len(states))
+	for _, s := range states {
+		byName[nameKey(s.Name)] = s
+		byName[nameKey(s.Code)] = s
+	}
+	return byName, nil
+}
+
+// loadDistricts indexes district IDs by districtKey
+func loadDistricts(tx *gorm.DB) (map[string]uint, error) {
+	var districts []models.District
+	if err := tx.Find(&districts).Error; err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]uint, len(districts))
+	for _, d := range districts {
+		byKey[districtKey(d.StateID, d.Name)] = d.ID
+	}
+	return byKey, nil
+}
+
+// csvColumns maps lower-case header names to column indexes
+type csvColumns map[string]int
+
+// openCSV reads the header row of a CSV file
+func openCSV(r io.Reader) (*csv.Reader, csvColumns, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, fmt.Errorf("%w: file is empty", ErrInvalidCSV)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+	}
+
+	columns := csvColumns{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, ok := columns[name]; !ok {
+			columns[name] = i
+		}
+	}
+	return reader, columns, nil
+}
+
+// find returns the index of the first of names in the header, or -1
+func (c csvColumns) find(names ...string) int {
+	for _, name := range names {
+		if i, ok := c[name]; ok {
+			return i
+		}
+	}
+	return -1
+}
+
+// require is find for columns the file must have
+func (c csvColumns) require(names ...string) (int, error) {
+	if i := c.find(names...); i >= 0 {
+		return i, nil
+	}
+	return -1, fmt.Errorf("%w: no %s column", ErrInvalidCSV, names[0])
+}
+
+// field returns the trimmed value in column i, or "" if the row is short
+func field(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// nameKey normalises a place name for matching, e.g. "ANDAMAN & NICOBAR
+// ISLANDS" and "Andaman and Nicobar Islands"
+func nameKey(name string) string {
+	name = strings.ToLower(strings.ReplaceAll(name, "&", " and "))
+	return strings.Join(strings.Fields(name), " ")
+}
+
+func districtKey(stateID uint, name string) string {
+	return fmt.Sprintf("%d/%s", stateID, nameKey(name))
+}
+
+// titleCase turns upper-case names from India Post data, e.g. "LUCKNOW
+// G.P.O.", into "Lucknow G.P.O."; names already in mixed case are kept
+func titleCase(s string) string {
+	if strings.ToUpper(s) != s {
+		return s
+	}
+	words := strings.Fields(s)
+	for i, word := range words {
+		if strings.Contains(word, ".") {
+			continue // Abbreviations such as G.P.O. and S.O
+		}
+		runes := []rune(strings.ToLower(word))
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	return strings.Join(words, " ")
+}
+
+// parseCoordinate parses a latitude or longitude, treating blanks, "NA" and
+// out-of-range values as unknown
+func parseCoordinate(value string, limit float64) *float64 {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil || v < -limit || v > limit || v == 0 {
+		return nil
+	}
+	return &v
+}
+
+// parseFlag parses yes/no style CSV values; blank means fallback
+func parseFlag(value string, fallback bool) (bool, error) {
+	switch strings.ToLower(value) {
+	case "":
+		return fallback, nil
+	case "y", "yes", "true", "1":
+		return true, nil
+	case "n", "no", "false", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid flag %q", value)
+}
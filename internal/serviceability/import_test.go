@@ -0,0 +1,312 @@
+package serviceability
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+)
+
+func TestImportHeaders(t *testing.T) {
+	// Header problems are reported before the database is touched
+	tests := []struct {
+		name string
+		csv  string
+		run  func(string) error
+	}{
+		{"empty pin code file", "", importPinCodes},
+		{"no pin code column", "district,statename\nLucknow,Uttar Pradesh\n", importPinCodes},
+		{"no district column", "pincode,statename\n226001,Uttar Pradesh\n", importPinCodes},
+		{"no state column", "pincode,district\n226001,Lucknow\n", importPinCodes},
+		{"empty coverage file", "", importCoverage},
+		{"no courier column", "pincode,cod\n226001,y\n", importCoverage},
+		{"no coverage pin code column", "courier,cod\nfake,y\n", importCoverage},
+	}
+
+	for _, tt := range tests {
+		if err := tt.run(tt.csv); !errors.Is(err, ErrInvalidCSV) {
+			t.Errorf("%s: got %v, want ErrInvalidCSV", tt.name, err)
+		}
+	}
+}
+
+func importPinCodes(csv string) error {
+	_, err := ImportPinCodes(nil, strings.NewReader(csv))
+	return err
+}
+
+func importCoverage(csv string) error {
+	_, err := ImportCoverage(nil, strings.NewReader(csv), false)
+	return err
+}
+
+func TestImportPinCodes(t *testing.T) {
+	db := newFakeDB(t)
+	db.rows["states"] = []models.State{
+		{ID: 1, Name: "Uttar Pradesh", Code: "UP"},
+		{ID: 2, Name: "Kerala", Code: "KL"},
+		{ID: 3, Name: "Telangana", Code: "TG"},
+	}
+	db.rows["districts"] = []models.District{{ID: 10, StateID: 1, Name: "Lucknow"}}
+
+	csv := `pincode,officename,district,statename,latitude,longitude
+226001,LUCKNOW G.P.O.,LUCKNOW,UTTAR PRADESH,26.8467,80.9462
+226 002,Aminabad S.O,Lucknow,Uttar Pradesh,NA,NA
+226001,LUCKNOW DUPLICATE,LUCKNOW,UTTAR PRADESH,,
+682001,ERNAKULAM H.O,ERNAKULAM,KERALA,,
+682002,ERNAKULAM SOUTH S.O,Ernakulam,KL,,
+110001,NEW DELHI G.P.O.,NEW DELHI,ATLANTIS,,
+12345,NOWHERE,Lucknow,Uttar Pradesh,,
+500001,HYDERABAD G.P.O.,,TELANGANA,,
+`
+	result, err := ImportPinCodes(db.DB, strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := ImportResult{Rows: 8, Imported: 4, Skipped: 4, DistrictsCreated: 1}
+	if result.Rows != want.Rows || result.Imported != want.Imported || result.Skipped != want.Skipped || result.DistrictsCreated != want.DistrictsCreated {
+		t.Errorf("got %+v, want %+v", *result, want)
+	}
+	// The repeated pin code is skipped without an error
+	wantErrors := []string{
+		`line 7: unknown state "ATLANTIS"`,
+		`line 8: invalid pin code "12345"`,
+		`line 9: missing district`,
+	}
+	if !reflect.DeepEqual(result.Errors, wantErrors) {
+		t.Errorf("errors = %q, want %q", result.Errors, wantErrors)
+	}
+
+	if len(db.created) != 2 {
+		t.Fatalf("created %d batches, want a district and the pin codes", len(db.created))
+	}
+	district, ok := db.created[0].(models.District)
+	if !ok || district.StateID != 2 || district.Name != "Ernakulam" || !district.IsActive {
+		t.Errorf("created district %+v, want active Ernakulam in Kerala", db.created[0])
+	}
+
+	pins, ok := db.created[1].([]models.PinCode)
+	if !ok || len(pins) != 4 {
+		t.Fatalf("created %+v, want 4 pin codes", db.created[1])
+	}
+	wantPins := []struct {
+		code       string
+		districtID uint
+		area       string
+		located    bool
+	}{
+		{"226001", 10, "Lucknow G.P.O.", true},
+		{"226002", 10, "Aminabad S.O", false},
+		{"682001", district.ID, "Ernakulam H.O", false},
+		{"682002", district.ID, "Ernakulam South S.O", false},
+	}
+	for i, w := range wantPins {
+		pin := pins[i]
+		if pin.Code != w.code || pin.DistrictID != w.districtID || pin.Area != w.area || (pin.Latitude != nil) != w.located || (pin.Longitude != nil) != w.located {
+			t.Errorf("pin %d = %+v, want %+v", i, pin, w)
+		}
+	}
+
+	if len(db.find(`INSERT INTO "pin_codes"`, `ON CONFLICT ("code") DO UPDATE`)) != 1 {
+		t.Errorf("pin codes not upserted in one statement: %q", db.statements)
+	}
+}
+
+func TestImportCoverage(t *testing.T) {
+	csv := `pincode,courier,prepaid,cod,extra_days
+226001,Delhivery,y,y,0
+226002,delhivery,,n,2
+226001,delhivery,yes,no,1
+682001,bluedart,y,maybe,
+682001,ekart,y,y,
+682002,bluedart,,,
+110001,bluedart,y,y,-1
+`
+	for _, replace := range []bool{false, true} {
+		db := newFakeDB(t)
+		db.rows["logistics_providers"] = []models.LogisticsProvider{
+			{ID: 1, Code: "delhivery"},
+			{ID: 2, Code: "BlueDart"},
+		}
+
+		result, err := ImportCoverage(db.DB, strings.NewReader(csv), replace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Rows != 7 || result.Imported != 4 || result.Skipped != 3 {
+			t.Errorf("replace=%v: got %+v, want 7 rows, 4 imported and 3 skipped", replace, *result)
+		}
+		wantErrors := []string{
+			`line 5: invalid cod value "maybe"`,
+			`line 6: unknown courier "ekart"`,
+			`line 8: invalid extra_days "-1"`,
+		}
+		if !reflect.DeepEqual(result.Errors, wantErrors) {
+			t.Errorf("replace=%v: errors = %q, want %q", replace, result.Errors, wantErrors)
+		}
+
+		// The pin code listed twice for a courier keeps its last row
+		type coverage struct {
+			pin        string
+			providerID uint
+			prepaid    bool
+			cod        bool
+			extraDays  int
+		}
+		want := []coverage{
+			{"226001", 1, true, false, 1},
+			{"226002", 1, true, false, 2},
+			{"682002", 2, true, false, 0},
+		}
+		var got []coverage
+		if len(db.created) == 1 {
+			rows, _ := db.created[0].([]models.PinCodeCoverage)
+			for _, row := range rows {
+				got = append(got, coverage{row.PinCode, row.ProviderID, row.Prepaid, row.COD, row.ExtraDays})
+			}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("replace=%v: created %+v, want %+v", replace, got, want)
+		}
+		if len(db.find(`INSERT INTO "pin_code_coverage"`, `ON CONFLICT ("pin_code","provider_id") DO UPDATE`)) != 1 {
+			t.Errorf("replace=%v: coverage not upserted in one statement: %q", replace, db.statements)
+		}
+
+		for _, provider := range []string{"provider_id = 1", "provider_id = 2"} {
+			deletes := db.find(`DELETE FROM "pin_code_coverage"`, provider)
+			if replace && len(deletes) != 1 {
+				t.Errorf("replace=true: want one delete with %s, got %q", provider, deletes)
+			}
+			if !replace && len(deletes) != 0 {
+				t.Errorf("replace=false: unexpected deletes %q", deletes)
+			}
+		}
+	}
+}
+
+func TestOpenCSV(t *testing.T) {
+	reader, columns, err := openCSV(strings.NewReader("\ufeffPinCode, OfficeName ,District,pincode\n226001,LUCKNOW G.P.O.,LUCKNOW\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if i := columns.find("pin_code", "pincode"); i != 0 {
+		t.Errorf("pincode column = %d, want 0 (BOM stripped, first of duplicates)", i)
+	}
+	if i := columns.find("officename"); i != 1 {
+		t.Errorf("officename column = %d, want 1", i)
+	}
+	if i := columns.find("latitude", "lat"); i != -1 {
+		t.Errorf("latitude column = %d, want -1", i)
+	}
+	if _, err := columns.require("statename", "state"); !errors.Is(err, ErrInvalidCSV) {
+		t.Errorf("require missing column: got %v, want ErrInvalidCSV", err)
+	}
+
+	record, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := field(record, 1); got != "LUCKNOW G.P.O." {
+		t.Errorf("field 1 = %q", got)
+	}
+	if got := field(record, 3); got != "" {
+		t.Errorf("field past the end of a short row = %q, want empty", got)
+	}
+	if got := field(record, -1); got != "" {
+		t.Errorf("field of a missing column = %q, want empty", got)
+	}
+}
+
+func TestNameKey(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"ANDAMAN & NICOBAR ISLANDS", "Andaman and Nicobar Islands"},
+		{"  Uttar   Pradesh ", "uttar pradesh"},
+		{"Jammu&Kashmir", "Jammu and Kashmir"},
+	}
+
+	for _, tt := range tests {
+		if nameKey(tt.a) != nameKey(tt.b) {
+			t.Errorf("nameKey(%q) = %q, nameKey(%q) = %q; want equal", tt.a, nameKey(tt.a), tt.b, nameKey(tt.b))
+		}
+	}
+	if districtKey(1, "Lucknow") == districtKey(2, "Lucknow") {
+		t.Error("districtKey ignores the state")
+	}
+}
+
+func TestTitleCase(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"LUCKNOW G.P.O.", "Lucknow G.P.O."},
+		{"NORTH  24 PARGANAS", "North 24 Parganas"},
+		{"Chhatrapati Sambhajinagar", "Chhatrapati Sambhajinagar"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := titleCase(tt.in); got != tt.want {
+			t.Errorf("titleCase(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseCoordinate(t *testing.T) {
+	tests := []struct {
+		value string
+		limit float64
+		want  float64 // 0 means unknown
+	}{
+		{"26.8467", 90, 26.8467},
+		{"-80.9462", 180, -80.9462},
+		{"NA", 90, 0},
+		{"", 90, 0},
+		{"0", 90, 0},
+		{"95.5", 90, 0},
+		{"95.5", 180, 95.5},
+	}
+
+	for _, tt := range tests {
+		got := parseCoordinate(tt.value, tt.limit)
+		switch {
+		case tt.want == 0 && got != nil:
+			t.Errorf("parseCoordinate(%q, %v) = %v, want unknown", tt.value, tt.limit, *got)
+		case tt.want != 0 && (got == nil || *got != tt.want):
+			t.Errorf("parseCoordinate(%q, %v) = %v, want %v", tt.value, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestParseFlag(t *testing.T) {
+	tests := []struct {
+		value    string
+		fallback bool
+		want     bool
+		wantErr  bool
+	}{
+		{"", true, true, false},
+		{"", false, false, false},
+		{"Y", false, true, false},
+		{"yes", false, true, false},
+		{"TRUE", false, true, false},
+		{"1", false, true, false},
+		{"n", true, false, false},
+		{"No", true, false, false},
+		{"false", true, false, false},
+		{"0", true, false, false},
+		{"maybe", true, false, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseFlag(tt.value, tt.fallback)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseFlag(%q, %v) = %v, %v; want %v, error %v", tt.value, tt.fallback, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
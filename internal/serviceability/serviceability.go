@@ -0,0 +1,261 @@
+// Package serviceability answers whether and how fast we can deliver to an
+// Indian pin code, from imported pin code and courier coverage data
+package serviceability
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/nilabhsubramaniam/kapas/internal/config"
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+)
+
+// ErrInvalidPinCode is returned for strings that aren't 6-digit pin codes
+var ErrInvalidPinCode = errors.New("invalid pin code")
+
+var pinCodePattern = regexp.MustCompile(`^[1-9][0-9]{5}$`)
+
+// Zone is how far a parcel travels from the warehouse
+type Zone string
+
+const (
+	ZoneLocal    Zone = "local"    // Same district
+	ZoneRegional Zone = "regional" // Same state
+	ZoneNational Zone = "national"
+)
+
+// rank orders zones from nearest to farthest
+func (z Zone) rank() int {
+	switch z {
+	case ZoneLocal:
+		return 0
+	case ZoneRegional:
+		return 1
+	}
+	return 2
+}
+
+// Destination is what we know about delivering to a pin code
+type Destination struct {
+	Code     string
+	PinCode  *models.PinCode          // Nil when the pin code hasn't been imported
+	Couriers []models.PinCodeCoverage // Active couriers delivering there
+}
+
+// Origin is a warehouse that can ship to a destination
+type Origin struct {
+	Warehouse  models.Warehouse
+	Available  int // Stock of the requested product; 0 when no product was given
+	Zone       Zone
+	DistanceKm *float64 // Nil unless both pin codes have coordinates
+}
+
+// NormalizePinCode strips spaces from pin and checks it is a 6-digit pin code
+func NormalizePinCode(pin string) (string, error) {
+	pin = strings.ReplaceAll(strings.TrimSpace(pin), " ", "")
+	if !pinCodePattern.MatchString(pin) {
+		return "", ErrInvalidPinCode
+	}
+	return pin, nil
+}
+
+// Lookup loads a pin code's district and the active couriers serving it
+func Lookup(db *gorm.DB, pin string) (*Destination, error) {
+	code, err := NormalizePinCode(pin)
+	if err != nil {
+		return nil, err
+	}
+	dest := &Destination{Code: code}
+
+	var row models.PinCode
+	err = db.Preload("District.State").First(&row, "code = ?", code).Error
+	switch {
+	case err == nil:
+		dest.PinCode = &row
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+
+	if err := db.Joins("Provider").
+		Where("pin_code_coverage.pin_code = ? AND \"Provider\".is_active = ?", code, true).
+		Where("pin_code_coverage.prepaid OR pin_code_coverage.cod").
+		Find(&dest.Couriers).Error; err != nil {
+		return nil, err
+	}
+	return dest, nil
+}
+
+// Serviceable reports whether any active courier delivers to the destination
+func (d *Destination) Serviceable() bool {
+	return len(d.Couriers) > 0
+}
+
+// CODAvailable reports whether any courier collects cash on delivery there
+func (d *Destination) CODAvailable() bool {
+	for _, c := range d.Couriers {
+		if c.COD {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtraDays is the smallest remote-area delay among the couriers serving
+// the destination
+func (d *Destination) ExtraDays() int {
+	extra := -1
+	for _, c := range d.Couriers {
+		if extra < 0 || c.ExtraDays < extra {
+			extra = c.ExtraDays
+		}
+	}
+	if extra < 0 {
+		return 0
+	}
+	return extra
+}
+
+// DistrictName returns the destination's district, or "" if unknown
+func (d *Destination) DistrictName() string {
+	if d.PinCode == nil {
+		return ""
+	}
+	return d.PinCode.District.Name
+}
+
+// StateName returns the destination's state, or "" if unknown
+func (d *Destination) StateName() string {
+	if d.PinCode == nil {
+		return ""
+	}
+	return d.PinCode.District.State.Name
+}
+
+// NearestWarehouse returns the active warehouse closest to dest with at
+// least quantity of productID available, or nil if none has it. With
+// productID 0 every active warehouse qualifies.
+func NearestWarehouse(db *gorm.DB, dest *Destination, productID uint, quantity int) (*Origin, error) {
+	var rows []struct {
+		models.Warehouse
+		Available int
+	}
+	// warehouses has no available column, so it is always selected explicitly
+	query := db.Model(&models.Warehouse{}).
+		Select("warehouses.*, 0 AS available").
+		Where("warehouses.is_active = ?", true)
+	if productID != 0 {
+		query = query.Select("warehouses.*, inventory.quantity - inventory.reserved_quantity AS available").
+			Joins("JOIN inventory ON inventory.warehouse_id = warehouses.id AND inventory.deleted_at IS NULL").
+			Where("inventory.product_id = ? AND inventory.quantity - inventory.reserved_quantity >= ?", productID, quantity)
+	}
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	pins := make([]string, 0, len(rows))
+	for _, row := range rows {
+		pins = append(pins, row.PinCode)
+	}
+	var known []models.PinCode
+	if err := db.Preload("District").Where("code IN ?", pins).Find(&known).Error; err != nil {
+		return nil, err
+	}
+	byCode := make(map[string]*models.PinCode, len(known))
+	for i := range known {
+		byCode[known[i].Code] = &known[i]
+	}
+
+	origins := make([]Origin, 0, len(rows))
+	for _, row := range rows {
+		origin := Origin{Warehouse: row.Warehouse, Available: row.Available}
+		origin.Zone, origin.DistanceKm = locate(&row.Warehouse, byCode[row.PinCode], dest)
+		origins = append(origins, origin)
+	}
+
+	sort.SliceStable(origins, func(i, j int) bool {
+		a, b := origins[i], origins[j]
+		if a.Zone.rank() != b.Zone.rank() {
+			return a.Zone.rank() < b.Zone.rank()
+		}
+		if (a.DistanceKm == nil) != (b.DistanceKm == nil) {
+			return a.DistanceKm != nil
+		}
+		if a.DistanceKm != nil && *a.DistanceKm != *b.DistanceKm {
+			return *a.DistanceKm < *b.DistanceKm
+		}
+		if a.Available != b.Available {
+			return a.Available > b.Available
+		}
+		return a.Warehouse.ID < b.Warehouse.ID
+	})
+	return &origins[0], nil
+}
+
+// locate works out the zone and distance from a warehouse to dest. pin is
+// the warehouse's imported pin code, if any; otherwise the warehouse's
+// city and state names are compared with the destination's.
+func locate(warehouse *models.Warehouse, pin *models.PinCode, dest *Destination) (Zone, *float64) {
+	if dest.PinCode == nil {
+		return ZoneNational, nil
+	}
+	to := dest.PinCode
+
+	if pin == nil {
+		switch {
+		case !strings.EqualFold(warehouse.State, dest.StateName()):
+			return ZoneNational, nil
+		case strings.EqualFold(warehouse.City, dest.DistrictName()):
+			return ZoneLocal, nil
+		}
+		return ZoneRegional, nil
+	}
+
+	var distance *float64
+	if pin.Latitude != nil && pin.Longitude != nil && to.Latitude != nil && to.Longitude != nil {
+		km := haversineKm(*pin.Latitude, *pin.Longitude, *to.Latitude, *to.Longitude)
+		distance = &km
+	}
+	switch {
+	case pin.DistrictID == to.DistrictID:
+		return ZoneLocal, distance
+	case pin.District.StateID == to.District.StateID:
+		return ZoneRegional, distance
+	}
+	return ZoneNational, distance
+}
+
+// TransitDays is how many working days couriers take within a zone
+func TransitDays(zone Zone) int {
+	switch zone {
+	case ZoneLocal:
+		return config.GetEnvInt("DELIVERY_DAYS_LOCAL", 1)
+	case ZoneRegional:
+		return config.GetEnvInt("DELIVERY_DAYS_REGIONAL", 3)
+	}
+	return config.GetEnvInt("DELIVERY_DAYS_NATIONAL", config.GetEnvInt("COURIER_TRANSIT_DAYS", 5))
+}
+
+// EstimatedDays is the working days from order to delivery when shipping
+// from origin to dest: packing time, transit time and any remote-area delay
+func EstimatedDays(origin *Origin, dest *Destination) int {
+	return config.GetEnvInt("DELIVERY_HANDLING_DAYS", 1) + TransitDays(origin.Zone) + dest.ExtraDays()
+}
+
+// haversineKm returns the great-circle distance between two points in km
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusKm = 6371
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat, dLon := rad(lat2-lat1), rad(lon2-lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(rad(lat1))*math.Cos(rad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
@@ -0,0 +1,174 @@
+package serviceability
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+)
+
+func TestNormalizePinCode(t *testing.T) {
+	tests := []struct {
+		pin     string
+		want    string
+		wantErr bool
+	}{
+		{"226001", "226001", false},
+		{" 226 001 ", "226001", false},
+		{"026001", "", true},
+		{"22600", "", true},
+		{"2260011", "", true},
+		{"22600a", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizePinCode(tt.pin)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidPinCode) {
+				t.Errorf("NormalizePinCode(%q) = %q, %v; want ErrInvalidPinCode", tt.pin, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("NormalizePinCode(%q) = %q, %v; want %q", tt.pin, got, err, tt.want)
+		}
+	}
+}
+
+func TestLocate(t *testing.T) {
+	lat, lon := 26.85, 80.95
+	lucknow := &models.PinCode{Code: "226001", DistrictID: 1, District: models.District{ID: 1, StateID: 10, Name: "Lucknow", State: models.State{ID: 10, Name: "Uttar Pradesh"}}}
+	kanpur := &models.PinCode{Code: "208001", DistrictID: 2, District: models.District{ID: 2, StateID: 10, Name: "Kanpur", State: models.State{ID: 10, Name: "Uttar Pradesh"}}}
+	kochi := &models.PinCode{Code: "682001", DistrictID: 3, Latitude: &lat, Longitude: &lon, District: models.District{ID: 3, StateID: 20, Name: "Ernakulam", State: models.State{ID: 20, Name: "Kerala"}}}
+	warehouse := &models.Warehouse{City: "Lucknow", State: "Uttar Pradesh"}
+
+	tests := []struct {
+		name string
+		pin  *models.PinCode
+		dest *models.PinCode
+		want Zone
+	}{
+		{"same district", lucknow, lucknow, ZoneLocal},
+		{"same state", lucknow, kanpur, ZoneRegional},
+		{"other state", lucknow, kochi, ZoneNational},
+		{"unknown destination", lucknow, nil, ZoneNational},
+		{"city match without warehouse pin", nil, lucknow, ZoneLocal},
+		{"state match without warehouse pin", nil, kanpur, ZoneRegional},
+		{"no match without warehouse pin", nil, kochi, ZoneNational},
+	}
+
+	for _, tt := range tests {
+		zone, _ := locate(warehouse, tt.pin, &Destination{PinCode: tt.dest})
+		if zone != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, zone, tt.want)
+		}
+	}
+
+	if _, distance := locate(warehouse, kochi, &Destination{PinCode: kochi}); distance == nil || *distance != 0 {
+		t.Errorf("distance between the same coordinates = %v, want 0", distance)
+	}
+	if _, distance := locate(warehouse, lucknow, &Destination{PinCode: kochi}); distance != nil {
+		t.Errorf("distance without warehouse coordinates = %v, want nil", *distance)
+	}
+}
+
+// warehouseRows matches the rows NearestWarehouse scans into
+type warehouseRows = []struct {
+	models.Warehouse
+	Available int
+}
+
+func TestNearestWarehouse(t *testing.T) {
+	dest := &Destination{Code: "226010", PinCode: &models.PinCode{
+		Code:       "226010",
+		DistrictID: 1,
+		District:   models.District{ID: 1, StateID: 10, Name: "Lucknow", State: models.State{ID: 10, Name: "Uttar Pradesh"}},
+	}}
+
+	t.Run("pin code only", func(t *testing.T) {
+		db := newFakeDB(t)
+		db.rows["warehouses"] = warehouseRows{
+			{Warehouse: models.Warehouse{ID: 1, City: "Kochi", State: "Kerala", PinCode: "682001"}},
+			{Warehouse: models.Warehouse{ID: 2, City: "Kanpur", State: "Uttar Pradesh", PinCode: "208001"}},
+			{Warehouse: models.Warehouse{ID: 3, City: "Lucknow", State: "Uttar Pradesh", PinCode: "226001"}},
+		}
+
+		origin, err := NearestWarehouse(db.DB, dest, 0, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if origin == nil || origin.Warehouse.ID != 3 || origin.Zone != ZoneLocal {
+			t.Errorf("got %+v, want the Lucknow warehouse in the local zone", origin)
+		}
+
+		queries := db.find(`FROM "warehouses"`)
+		if len(queries) != 1 {
+			t.Fatalf("got warehouse queries %q", queries)
+		}
+		// warehouses has no available column
+		if !strings.Contains(queries[0], "0 AS available") || strings.Contains(queries[0], `"warehouses"."available"`) || strings.Contains(queries[0], "inventory") {
+			t.Errorf("unexpected warehouse query %q", queries[0])
+		}
+	})
+
+	t.Run("product in stock", func(t *testing.T) {
+		db := newFakeDB(t)
+		db.rows["warehouses"] = warehouseRows{
+			{Warehouse: models.Warehouse{ID: 1, City: "Kanpur", State: "Uttar Pradesh", PinCode: "208001"}, Available: 2},
+			{Warehouse: models.Warehouse{ID: 2, City: "Agra", State: "Uttar Pradesh", PinCode: "282001"}, Available: 5},
+			{Warehouse: models.Warehouse{ID: 3, City: "Kochi", State: "Kerala", PinCode: "682001"}, Available: 50},
+		}
+
+		origin, err := NearestWarehouse(db.DB, dest, 7, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if origin == nil || origin.Warehouse.ID != 2 || origin.Zone != ZoneRegional || origin.Available != 5 {
+			t.Errorf("got %+v, want the regional warehouse with the most stock", origin)
+		}
+
+		queries := db.find(`FROM "warehouses"`, "JOIN inventory", "inventory.product_id = 7", ">= 2")
+		if len(queries) != 1 || strings.Contains(queries[0], "0 AS available") {
+			t.Errorf("unexpected warehouse queries %q", db.statements)
+		}
+	})
+
+	t.Run("none in stock", func(t *testing.T) {
+		db := newFakeDB(t)
+		origin, err := NearestWarehouse(db.DB, dest, 7, 1)
+		if err != nil || origin != nil {
+			t.Errorf("got %+v, %v; want no warehouse", origin, err)
+		}
+	})
+}
+
+func TestDestinationCouriers(t *testing.T) {
+	dest := &Destination{}
+	if dest.Serviceable() || dest.CODAvailable() || dest.ExtraDays() != 0 {
+		t.Error("destination without couriers should not be serviceable")
+	}
+
+	dest.Couriers = []models.PinCodeCoverage{
+		{Prepaid: true, ExtraDays: 3},
+		{Prepaid: true, COD: true, ExtraDays: 1},
+	}
+	if !dest.Serviceable() || !dest.CODAvailable() {
+		t.Error("expected a serviceable destination with cash on delivery")
+	}
+	if got := dest.ExtraDays(); got != 1 {
+		t.Errorf("ExtraDays = %d, want the fastest courier's 1", got)
+	}
+}
+
+func TestHaversineKm(t *testing.T) {
+	// Lucknow to New Delhi is about 418 km as the crow flies
+	if got := haversineKm(26.8467, 80.9462, 28.6139, 77.2090); math.Abs(got-418) > 5 {
+		t.Errorf("got %.0f km, want about 418", got)
+	}
+	if got := haversineKm(10, 20, 10, 20); got != 0 {
+		t.Errorf("got %v km for the same point, want 0", got)
+	}
+}
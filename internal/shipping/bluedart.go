@@ -0,0 +1,363 @@
+package shipping
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+)
+
+const bluedartBaseURL = "https://apigateway.bluedart.com/in/transportation"
+
+// Bluedart is the Blue Dart API gateway. Blue Dart has no rate API, so
+// quotes use the contracted rate card from BLUEDART_RATE_BASE and
+// BLUEDART_RATE_PER_KG.
+type Bluedart struct {
+	clientID     string
+	clientSecret string
+	loginID      string
+	licenseKey   string
+	customerCode string
+	baseURL      string
+	client       *http.Client
+	session      *bluedartSession
+}
+
+// bluedartSession caches the gateway JWT between calls
+type bluedartSession struct {
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// NewBluedart creates a Blue Dart courier
+func NewBluedart(clientID, clientSecret, loginID, licenseKey, customerCode string) *Bluedart {
+	return &Bluedart{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		loginID:      loginID,
+		licenseKey:   licenseKey,
+		customerCode: customerCode,
+		baseURL:      bluedartBaseURL,
+		client:       newHTTPClient(),
+		session:      &bluedartSession{},
+	}
+}
+
+// Code returns the courier code
+func (b *Bluedart) Code() string {
+	return CodeBluedart
+}
+
+func (b *Bluedart) withBaseURL(baseURL string) Courier {
+	clone := *b
+	clone.baseURL = strings.TrimRight(baseURL, "/")
+	clone.session = &bluedartSession{}
+	return &clone
+}
+
+// Quote prices the parcel from the rate card and asks Blue Dart for the
+// expected delivery date
+func (b *Bluedart) Quote(ctx context.Context, req QuoteRequest) (*Quote, error) {
+	cost := envFloat("BLUEDART_RATE_BASE", 90) +
+		envFloat("BLUEDART_RATE_PER_KG", 45)*math.Ceil(math.Max(req.Parcel.WeightKg, 0.5))
+
+	body := map[string]interface{}{
+		"pPinCodeFrom":    req.PickupPinCode,
+		"pPinCodeTo":      req.DeliveryPinCode,
+		"pProductCode":    "A",
+		"pSubProductCode": b.subProductCode(req.CODAmount),
+		"pPudate":         fmt.Sprintf("/Date(%d)/", time.Now().UnixMilli()),
+		"pPickupTime":     "16:00",
+		"profile":         b.profile(),
+	}
+
+	var resp struct {
+		Result struct {
+			ExpectedDateDelivery string `json:"ExpectedDateDelivery"`
+			IsError              bool   `json:"IsError"`
+			ErrorMessage         string `json:"ErrorMessage"`
+		} `json:"GetDomesticTransitTimeForPinCodeandProductResult"`
+	}
+	if err := b.do(ctx, "/transit/v1/GetDomesticTransitTimeForPinCodeandProduct", body, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Result.IsError {
+		return nil, fmt.Errorf("bluedart: transit time: %s", resp.Result.ErrorMessage)
+	}
+
+	eta, ok := parseBluedartDate(resp.Result.ExpectedDateDelivery)
+	if !ok {
+		eta = EstimateDelivery(time.Now(), transitDays())
+	}
+
+	return &Quote{ShippingCost: cost, EstimatedDelivery: eta}, nil
+}
+
+// Book generates a waybill and registers the pickup in one call
+func (b *Bluedart) Book(ctx context.Context, req BookingRequest) (*Booking, error) {
+	quote, err := b.Quote(ctx, QuoteRequest{
+		PickupPinCode:   req.Pickup.PinCode,
+		DeliveryPinCode: req.Delivery.PinCode,
+		Parcel:          req.Parcel,
+		CODAmount:       req.CODAmount,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pickup := EstimateDelivery(time.Now(), 1)
+	body := map[string]interface{}{
+		"Request": map[string]interface{}{
+			"Consignee": map[string]interface{}{
+				"ConsigneeName":     req.Delivery.Name,
+				"ConsigneeAddress1": req.Delivery.AddressLine1,
+				"ConsigneeAddress2": req.Delivery.AddressLine2,
+				"ConsigneeAddress3": req.Delivery.City + ", " + req.Delivery.State,
+				"ConsigneePincode":  req.Delivery.PinCode,
+				"ConsigneeMobile":   req.Delivery.Phone,
+			},
+			"Shipper": map[string]interface{}{
+				"CustomerCode":     b.customerCode,
+				"CustomerName":     req.Pickup.Name,
+				"CustomerAddress1": req.Pickup.AddressLine1,
+				"CustomerAddress2": req.Pickup.AddressLine2,
+				"CustomerAddress3": req.Pickup.City + ", " + req.Pickup.State,
+				"CustomerPincode":  req.Pickup.PinCode,
+				"CustomerMobile":   req.Pickup.Phone,
+			},
+			"Services": map[string]interface{}{
+				"ProductCode":          "A",
+				"SubProductCode":       b.subProductCode(req.CODAmount),
+				"CreditReferenceNo":    req.OrderNumber,
+				"DeclaredValue":        req.DeclaredValue,
+				"CollectableAmount":    req.CODAmount,
+				"ActualWeight":         req.Parcel.WeightKg,
+				"PieceCount":           1,
+				"PickupDate":           fmt.Sprintf("/Date(%d)/", pickup.UnixMilli()),
+				"PickupTime":           "1600",
+				"RegisterPickup":       true,
+				"Dimensions":           []map[string]interface{}{{"Length": req.Parcel.LengthCm, "Breadth": req.Parcel.WidthCm, "Height": req.Parcel.HeightCm, "Count": 1}},
+				"PDFOutputNotRequired": true,
+			},
+		},
+		"Profile": b.profile(),
+	}
+
+	var resp struct {
+		Result struct {
+			AWBNo   string `json:"AWBNo"`
+			IsError bool   `json:"IsError"`
+			Status  []struct {
+				StatusInformation string `json:"StatusInformation"`
+			} `json:"Status"`
+		} `json:"GenerateWayBillResult"`
+	}
+	if err := b.do(ctx, "/waybill/v1/GenerateWayBill", body, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Result.IsError || resp.Result.AWBNo == "" {
+		reasons := make([]string, 0, len(resp.Result.Status))
+		for _, s := range resp.Result.Status {
+			reasons = append(reasons, s.StatusInformation)
+		}
+		return nil, fmt.Errorf("bluedart: waybill rejected: %s", strings.Join(reasons, "; "))
+	}
+
+	return &Booking{
+		AWBNumber:         resp.Result.AWBNo,
+		ShippingCost:      quote.ShippingCost,
+		EstimatedDelivery: quote.EstimatedDelivery,
+		PickupDate:        &pickup,
+		TrackingURL:       "https://www.bluedart.com/tracking?trackFor=0&trackNo=" + resp.Result.AWBNo,
+	}, nil
+}
+
+// Cancel cancels a waybill that has not been picked up
+func (b *Bluedart) Cancel(ctx context.Context, awbNumber string) error {
+	body := map[string]interface{}{
+		"Request": map[string]string{"AWBNo": awbNumber},
+		"Profile": b.profile(),
+	}
+
+	var resp struct {
+		Result struct {
+			IsError bool `json:"IsError"`
+			Status  []struct {
+				StatusInformation string `json:"StatusInformation"`
+			} `json:"Status"`
+		} `json:"CancelWaybillResult"`
+	}
+	if err := b.do(ctx, "/waybill/v1/CancelWaybill", body, &resp); err != nil {
+		return err
+	}
+	if resp.Result.IsError {
+		reason := ""
+		if len(resp.Result.Status) > 0 {
+			reason = resp.Result.Status[0].StatusInformation
+		}
+		return fmt.Errorf("bluedart: cancel %s rejected: %s", awbNumber, reason)
+	}
+	return nil
+}
+
+// subProductCode is P for prepaid and C for cash on delivery
+func (b *Bluedart) subProductCode(codAmount float64) string {
+	if codAmount > 0 {
+		return "C"
+	}
+	return "P"
+}
+
+func (b *Bluedart) profile() map[string]string {
+	return map[string]string{
+		"LoginID":    b.loginID,
+		"LicenceKey": b.licenseKey,
+		"Api_type":   "S",
+	}
+}
+
+// do sends an authenticated POST to the gateway
+func (b *Bluedart) do(ctx context.Context, path string, in, out interface{}) error {
+	token, err := b.authToken(ctx)
+	if err != nil {
+		return err
+	}
+	return doJSON(ctx, b.client, CodeBluedart, http.MethodPost, b.baseURL+path,
+		http.Header{"JWTToken": {token}}, in, out)
+}
+
+// authToken returns a cached gateway JWT, logging in when it has expired
+func (b *Bluedart) authToken(ctx context.Context) (string, error) {
+	b.session.mu.Lock()
+	defer b.session.mu.Unlock()
+
+	if b.session.token != "" && time.Now().Before(b.session.expiry) {
+		return b.session.token, nil
+	}
+
+	var resp struct {
+		JWTToken string `json:"JWTToken"`
+	}
+	header := http.Header{"ClientID": {b.clientID}, "clientSecret": {b.clientSecret}}
+	if err := doJSON(ctx, b.client, CodeBluedart, http.MethodGet, b.baseURL+"/token/v1/login", header, nil, &resp); err != nil {
+		return "", err
+	}
+
+	b.session.token = resp.JWTToken
+	b.session.expiry = time.Now().Add(23 * time.Hour)
+	return resp.JWTToken, nil
+}
+
+// parseBluedartDate reads the gateway's "/Date(ms)/" or ISO dates
+func parseBluedartDate(value string) (time.Time, bool) {
+	if strings.HasPrefix(value, "/Date(") {
+		digits := strings.TrimSuffix(strings.TrimPrefix(value, "/Date("), ")/")
+		if i := strings.IndexAny(digits, "+-"); i > 0 {
+			digits = digits[:i]
+		}
+		if ms, err := strconv.ParseInt(digits, 10, 64); err == nil {
+			return time.UnixMilli(ms), true
+		}
+		return time.Time{}, false
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// envFloat reads a float setting, falling back when unset or invalid
+func envFloat(key string, fallback float64) float64 {
+	if v, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return v
+	}
+	return fallback
+}
+
+// bluedartScanTypes maps Blue Dart scan types onto shipment statuses
+var bluedartScanTypes = map[string]models.ShipmentStatus{
+	"PU": models.ShipmentStatusPickedUp,
+	"UD": models.ShipmentStatusInTransit,
+	"DL": models.ShipmentStatusDelivered,
+	"RT": models.ShipmentStatusReturned,
+}
+
+// Track pulls the scan history for a batch of waybills
+func (b *Bluedart) Track(ctx context.Context, awbNumbers []string) ([]TrackingUpdate, error) {
+	token, err := b.authToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{
+		"handler": {"tnt"},
+		"action":  {"custawbquery"},
+		"loginid": {b.loginID},
+		"awb":     {"awb"},
+		"numbers": {strings.Join(awbNumbers, ",")},
+		"format":  {"json"},
+		"lickey":  {b.licenseKey},
+		"verno":   {"1"},
+		"scan":    {"1"},
+	}
+
+	var resp struct {
+		ShipmentData struct {
+			Shipment []struct {
+				WaybillNo string `json:"WaybillNo"`
+				Scans     []struct {
+					ScanDetail struct {
+						Scan            string `json:"Scan"`
+						ScanType        string `json:"ScanType"`
+						ScanDate        string `json:"ScanDate"` // 18-Oct-2024
+						ScanTime        string `json:"ScanTime"` // 14:30
+						ScannedLocation string `json:"ScannedLocation"`
+					} `json:"ScanDetail"`
+				} `json:"Scans"`
+			} `json:"Shipment"`
+		} `json:"ShipmentData"`
+	}
+	if err := doJSON(ctx, b.client, CodeBluedart, http.MethodGet,
+		b.baseURL+"/tracking/v1/shipment?"+params.Encode(), http.Header{"JWTToken": {token}}, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	var updates []TrackingUpdate
+	for _, shipment := range resp.ShipmentData.Shipment {
+		for _, scan := range shipment.Scans {
+			detail := scan.ScanDetail
+			eventTime, ok := parseEventTime(detail.ScanDate+" "+detail.ScanTime, "02-Jan-2006 15:04")
+			if !ok {
+				continue
+			}
+
+			status := bluedartScanTypes[detail.ScanType]
+			scanText := strings.ToUpper(detail.Scan)
+			switch {
+			case strings.Contains(scanText, "OUT FOR DELIVERY"):
+				status = models.ShipmentStatusOutForDelivery
+			case strings.Contains(scanText, "UNDELIVERED"), strings.Contains(scanText, "DELIVERY ATTEMPTED"):
+				status = models.ShipmentStatusFailed
+			}
+
+			updates = append(updates, TrackingUpdate{
+				AWBNumber:   shipment.WaybillNo,
+				Status:      status,
+				RawStatus:   detail.Scan,
+				Location:    detail.ScannedLocation,
+				Description: detail.Scan,
+				EventTime:   eventTime,
+			})
+		}
+	}
+	return updates, nil
+}
@@ -0,0 +1,190 @@
+package shipping
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+)
+
+// ErrUnknownCourier is returned when no courier is registered under a code
+var ErrUnknownCourier = errors.New("unknown courier")
+
+// Courier codes, matching models.LogisticsProvider.Code
+const (
+	CodeDelhivery  = "delhivery"
+	CodeBluedart   = "bluedart"
+	CodeShiprocket = "shiprocket"
+	CodeFake       = "fake"
+)
+
+// Address is a pickup or delivery location
+type Address struct {
+	Name         string
+	Phone        string
+	AddressLine1 string
+	AddressLine2 string
+	City         string
+	State        string
+	PinCode      string
+	Country      string
+}
+
+// Parcel describes the package handed to the courier
+type Parcel struct {
+	WeightKg float64
+	LengthCm float64
+	WidthCm  float64
+	HeightCm float64
+}
+
+// QuoteRequest asks a courier for the cost and transit time of a delivery
+type QuoteRequest struct {
+	PickupPinCode   string
+	DeliveryPinCode string
+	Parcel          Parcel
+	CODAmount       float64 // Zero for prepaid orders
+}
+
+// Quote is a courier's price and delivery estimate
+type Quote struct {
+	ShippingCost      float64
+	EstimatedDelivery time.Time
+}
+
+// BookingRequest asks a courier to pick up a parcel
+type BookingRequest struct {
+	OrderNumber   string
+	Pickup        Address
+	Delivery      Address
+	Parcel        Parcel
+	DeclaredValue float64
+	CODAmount     float64 // Zero for prepaid orders
+}
+
+// Booking is a confirmed pickup with its airway bill
+type Booking struct {
+	AWBNumber         string
+	Reference         string // Courier's own shipment ID, when different from the AWB
+	ShippingCost      float64
+	EstimatedDelivery time.Time
+	PickupDate        *time.Time
+	TrackingURL       string
+}
+
+// Courier is a logistics provider integration
+type Courier interface {
+	// Code returns the models.LogisticsProvider.Code this courier serves
+	Code() string
+
+	// Quote returns the cost and estimated delivery for a parcel
+	Quote(ctx context.Context, req QuoteRequest) (*Quote, error)
+
+	// Book schedules a pickup and allocates an AWB
+	Book(ctx context.Context, req BookingRequest) (*Booking, error)
+
+	// Cancel cancels a booked shipment that has not been picked up
+	Cancel(ctx context.Context, awbNumber string) error
+}
+
+// endpointOverrider is implemented by couriers whose API base URL can be
+// taken from models.LogisticsProvider.APIEndpoint
+type endpointOverrider interface {
+	withBaseURL(baseURL string) Courier
+}
+
+var (
+	mu       sync.RWMutex
+	couriers = map[string]Courier{}
+	initOnce sync.Once
+)
+
+// Register makes a courier available by code, replacing any previous one
+func Register(c Courier) {
+	mu.Lock()
+	defer mu.Unlock()
+	couriers[c.Code()] = c
+}
+
+// Get returns the courier registered under code
+func Get(code string) (Courier, error) {
+	initOnce.Do(registerFromEnv)
+
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := couriers[code]
+	if !ok {
+		return nil, ErrUnknownCourier
+	}
+	return c, nil
+}
+
+// For returns the courier for a logistics provider, pointed at its
+// APIEndpoint when one is set
+func For(provider models.LogisticsProvider) (Courier, error) {
+	c, err := Get(provider.Code)
+	if err != nil {
+		return nil, err
+	}
+	if provider.APIEndpoint != "" {
+		if o, ok := c.(endpointOverrider); ok {
+			return o.withBaseURL(provider.APIEndpoint), nil
+		}
+	}
+	return c, nil
+}
+
+// registerFromEnv registers the couriers configured in the environment
+func registerFromEnv() {
+	if os.Getenv("DELHIVERY_ENABLED") == "true" {
+		Register(NewDelhivery(os.Getenv("DELHIVERY_API_KEY")))
+	}
+	if os.Getenv("BLUEDART_ENABLED") == "true" {
+		Register(NewBluedart(
+			os.Getenv("BLUEDART_CLIENT_ID"),
+			os.Getenv("BLUEDART_CLIENT_SECRET"),
+			os.Getenv("BLUEDART_LOGIN_ID"),
+			os.Getenv("BLUEDART_LICENSE_KEY"),
+			os.Getenv("BLUEDART_CUSTOMER_CODE"),
+		))
+	}
+	if os.Getenv("SHIPROCKET_ENABLED") == "true" {
+		Register(NewShiprocket(
+			os.Getenv("SHIPROCKET_EMAIL"),
+			os.Getenv("SHIPROCKET_PASSWORD"),
+			os.Getenv("SHIPROCKET_PICKUP_LOCATION"),
+			os.Getenv("SHIPROCKET_WEBHOOK_TOKEN"),
+		))
+	}
+
+	// The fake courier's webhook sets any shipment's status, which moves
+	// its order along and can trigger return refunds. Keep it out of
+	// production, and off until FAKE_COURIER_SECRET gives it a signing key.
+	if secret := os.Getenv("FAKE_COURIER_SECRET"); secret != "" && os.Getenv("APP_ENV") != "production" {
+		Register(NewFake(secret))
+	}
+}
+
+// transitDays is the fallback delivery estimate for couriers without an ETA API
+func transitDays() int {
+	if days, err := strconv.Atoi(os.Getenv("COURIER_TRANSIT_DAYS")); err == nil && days > 0 {
+		return days
+	}
+	return 5
+}
+
+// EstimateDelivery adds working days to from, skipping Sundays
+func EstimateDelivery(from time.Time, days int) time.Time {
+	date := from
+	for days > 0 {
+		date = date.AddDate(0, 0, 1)
+		if date.Weekday() != time.Sunday {
+			days--
+		}
+	}
+	return date
+}
@@ -0,0 +1,243 @@
+package shipping
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+)
+
+const delhiveryBaseURL = "https://track.delhivery.com"
+
+// Delhivery is the Delhivery B2C courier API
+type Delhivery struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+// NewDelhivery creates a Delhivery courier
+func NewDelhivery(apiKey string) *Delhivery {
+	return &Delhivery{
+		apiKey:  apiKey,
+		baseURL: delhiveryBaseURL,
+		client:  newHTTPClient(),
+	}
+}
+
+// Code returns the courier code
+func (d *Delhivery) Code() string {
+	return CodeDelhivery
+}
+
+func (d *Delhivery) withBaseURL(baseURL string) Courier {
+	clone := *d
+	clone.baseURL = strings.TrimRight(baseURL, "/")
+	return &clone
+}
+
+// Quote fetches the surface freight charge; Delhivery has no ETA API, so
+// delivery is estimated from COURIER_TRANSIT_DAYS
+func (d *Delhivery) Quote(ctx context.Context, req QuoteRequest) (*Quote, error) {
+	params := url.Values{
+		"md":    {"S"},
+		"ss":    {"Delivered"},
+		"o_pin": {req.PickupPinCode},
+		"d_pin": {req.DeliveryPinCode},
+		"cgm":   {fmt.Sprintf("%d", int(math.Ceil(req.Parcel.WeightKg*1000)))},
+		"pt":    {"Pre-paid"},
+	}
+	if req.CODAmount > 0 {
+		params.Set("pt", "COD")
+		params.Set("cod", fmt.Sprintf("%.2f", req.CODAmount))
+	}
+
+	var charges []struct {
+		TotalAmount float64 `json:"total_amount"`
+	}
+	if err := doJSON(ctx, d.client, CodeDelhivery, http.MethodGet,
+		d.baseURL+"/api/kinko/v1/invoice/charges/.json?"+params.Encode(), d.header(), nil, &charges); err != nil {
+		return nil, err
+	}
+	if len(charges) == 0 {
+		return nil, fmt.Errorf("delhivery: no rate for %s to %s", req.PickupPinCode, req.DeliveryPinCode)
+	}
+
+	return &Quote{
+		ShippingCost:      charges[0].TotalAmount,
+		EstimatedDelivery: EstimateDelivery(time.Now(), transitDays()),
+	}, nil
+}
+
+// Book creates a manifest entry; Delhivery allocates the waybill in the response
+func (d *Delhivery) Book(ctx context.Context, req BookingRequest) (*Booking, error) {
+	quote, err := d.Quote(ctx, QuoteRequest{
+		PickupPinCode:   req.Pickup.PinCode,
+		DeliveryPinCode: req.Delivery.PinCode,
+		Parcel:          req.Parcel,
+		CODAmount:       req.CODAmount,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	paymentMode := "Prepaid"
+	if req.CODAmount > 0 {
+		paymentMode = "COD"
+	}
+	shipment := map[string]interface{}{
+		"name":            req.Delivery.Name,
+		"phone":           req.Delivery.Phone,
+		"add":             strings.TrimSpace(req.Delivery.AddressLine1 + " " + req.Delivery.AddressLine2),
+		"city":            req.Delivery.City,
+		"state":           req.Delivery.State,
+		"country":         req.Delivery.Country,
+		"pin":             req.Delivery.PinCode,
+		"order":           req.OrderNumber,
+		"payment_mode":    paymentMode,
+		"cod_amount":      req.CODAmount,
+		"total_amount":    req.DeclaredValue,
+		"weight":          req.Parcel.WeightKg * 1000,
+		"shipment_length": req.Parcel.LengthCm,
+		"shipment_width":  req.Parcel.WidthCm,
+		"shipment_height": req.Parcel.HeightCm,
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"shipments":       []interface{}{shipment},
+		"pickup_location": map[string]string{"name": req.Pickup.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The create API takes form-encoded JSON rather than a JSON body
+	form := url.Values{"format": {"json"}, "data": {string(data)}}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		d.baseURL+"/api/cmu/create.json", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header = d.header()
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var resp struct {
+		Success  bool   `json:"success"`
+		RMK      string `json:"rmk"`
+		Packages []struct {
+			Waybill string   `json:"waybill"`
+			Status  string   `json:"status"`
+			Remarks []string `json:"remarks"`
+		} `json:"packages"`
+	}
+	if err := send(d.client, CodeDelhivery, httpReq, &resp); err != nil {
+		return nil, err
+	}
+	if !resp.Success || len(resp.Packages) == 0 || resp.Packages[0].Waybill == "" {
+		reason := resp.RMK
+		if len(resp.Packages) > 0 && len(resp.Packages[0].Remarks) > 0 {
+			reason = strings.Join(resp.Packages[0].Remarks, "; ")
+		}
+		return nil, fmt.Errorf("delhivery: booking rejected: %s", reason)
+	}
+
+	awb := resp.Packages[0].Waybill
+	return &Booking{
+		AWBNumber:         awb,
+		ShippingCost:      quote.ShippingCost,
+		EstimatedDelivery: quote.EstimatedDelivery,
+		TrackingURL:       "https://www.delhivery.com/track/package/" + awb,
+	}, nil
+}
+
+// Cancel cancels a manifested waybill
+func (d *Delhivery) Cancel(ctx context.Context, awbNumber string) error {
+	var resp struct {
+		Status bool   `json:"status"`
+		Remark string `json:"remark"`
+	}
+	if err := doJSON(ctx, d.client, CodeDelhivery, http.MethodPost, d.baseURL+"/api/p/edit", d.header(),
+		map[string]string{"waybill": awbNumber, "cancellation": "true"}, &resp); err != nil {
+		return err
+	}
+	if !resp.Status {
+		return fmt.Errorf("delhivery: cancel %s rejected: %s", awbNumber, resp.Remark)
+	}
+	return nil
+}
+
+func (d *Delhivery) header() http.Header {
+	return http.Header{"Authorization": {"Token " + d.apiKey}}
+}
+
+// delhiveryStatuses maps Delhivery scan statuses onto shipment statuses
+var delhiveryStatuses = map[string]models.ShipmentStatus{
+	"MANIFESTED":       models.ShipmentStatusPending,
+	"NOT PICKED":       models.ShipmentStatusPending,
+	"PICKED UP":        models.ShipmentStatusPickedUp,
+	"IN TRANSIT":       models.ShipmentStatusInTransit,
+	"PENDING":          models.ShipmentStatusInTransit,
+	"DISPATCHED":       models.ShipmentStatusOutForDelivery,
+	"OUT FOR DELIVERY": models.ShipmentStatusOutForDelivery,
+	"DELIVERED":        models.ShipmentStatusDelivered,
+	"UNDELIVERED":      models.ShipmentStatusFailed,
+	"RTO":              models.ShipmentStatusReturned,
+	"RETURNED":         models.ShipmentStatusReturned,
+}
+
+// Track pulls scans for up to 50 waybills per call
+func (d *Delhivery) Track(ctx context.Context, awbNumbers []string) ([]TrackingUpdate, error) {
+	var resp struct {
+		ShipmentData []struct {
+			Shipment struct {
+				AWB   string `json:"AWB"`
+				Scans []struct {
+					ScanDetail struct {
+						Scan            string `json:"Scan"`
+						ScanType        string `json:"ScanType"` // UD forward, DL delivered, RT return
+						ScanDateTime    string `json:"ScanDateTime"`
+						ScannedLocation string `json:"ScannedLocation"`
+						Instructions    string `json:"Instructions"`
+					} `json:"ScanDetail"`
+				} `json:"Scans"`
+			} `json:"Shipment"`
+		} `json:"ShipmentData"`
+	}
+	params := url.Values{"waybill": {strings.Join(awbNumbers, ",")}}
+	if err := doJSON(ctx, d.client, CodeDelhivery, http.MethodGet,
+		d.baseURL+"/api/v1/packages/json/?"+params.Encode(), d.header(), nil, &resp); err != nil {
+		return nil, err
+	}
+
+	var updates []TrackingUpdate
+	for _, data := range resp.ShipmentData {
+		for _, scan := range data.Shipment.Scans {
+			detail := scan.ScanDetail
+			eventTime, ok := parseEventTime(detail.ScanDateTime, "2006-01-02T15:04:05.000")
+			if !ok {
+				continue
+			}
+
+			status := mapStatus(delhiveryStatuses, detail.Scan)
+			if detail.ScanType == "RT" && status == models.ShipmentStatusDelivered {
+				// Delivered back to the seller on an RTO leg
+				status = models.ShipmentStatusReturned
+			}
+
+			updates = append(updates, TrackingUpdate{
+				AWBNumber:   data.Shipment.AWB,
+				Status:      status,
+				RawStatus:   detail.Scan,
+				Location:    detail.ScannedLocation,
+				Description: detail.Instructions,
+				EventTime:   eventTime,
+			})
+		}
+	}
+	return updates, nil
+}
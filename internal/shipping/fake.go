@@ -0,0 +1,131 @@
+package shipping
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+)
+
+// Fake is an offline courier for development and tests. It prices parcels
+// from a simple rate card, allocates random AWBs and accepts tracking
+// webhooks signed with SignWebhook.
+type Fake struct {
+	secret string
+}
+
+// fakeWebhook is the fake courier's tracking webhook body
+type fakeWebhook struct {
+	AWBNumber   string                `json:"awb_number"`
+	Status      models.ShipmentStatus `json:"status"`
+	Location    string                `json:"location"`
+	Description string                `json:"description"`
+	EventTime   time.Time             `json:"event_time"`
+}
+
+// NewFake creates the fake courier, accepting webhooks signed with secret
+func NewFake(secret string) *Fake {
+	return &Fake{secret: secret}
+}
+
+// Code returns the courier code
+func (f *Fake) Code() string {
+	return CodeFake
+}
+
+// Quote prices the parcel at ₹60 plus ₹30 per started kg; same-region pin
+// codes (matching first digit) arrive sooner
+func (f *Fake) Quote(ctx context.Context, req QuoteRequest) (*Quote, error) {
+	days := 5
+	if req.PickupPinCode != "" && req.DeliveryPinCode != "" && req.PickupPinCode[0] == req.DeliveryPinCode[0] {
+		days = 3
+	}
+
+	cost := 60 + 30*math.Ceil(math.Max(req.Parcel.WeightKg, 0.5))
+	if req.CODAmount > 0 {
+		cost += 40
+	}
+
+	return &Quote{
+		ShippingCost:      cost,
+		EstimatedDelivery: EstimateDelivery(time.Now(), days),
+	}, nil
+}
+
+// Book allocates a fake AWB with pickup scheduled for the next day
+func (f *Fake) Book(ctx context.Context, req BookingRequest) (*Booking, error) {
+	quote, err := f.Quote(ctx, QuoteRequest{
+		PickupPinCode:   req.Pickup.PinCode,
+		DeliveryPinCode: req.Delivery.PinCode,
+		Parcel:          req.Parcel,
+		CODAmount:       req.CODAmount,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	awb, err := fakeAWB()
+	if err != nil {
+		return nil, err
+	}
+	pickup := time.Now().AddDate(0, 0, 1)
+
+	return &Booking{
+		AWBNumber:         awb,
+		ShippingCost:      quote.ShippingCost,
+		EstimatedDelivery: quote.EstimatedDelivery,
+		PickupDate:        &pickup,
+	}, nil
+}
+
+// Cancel always succeeds
+func (f *Fake) Cancel(ctx context.Context, awbNumber string) error {
+	return nil
+}
+
+// ParseWebhook checks X-Fake-Courier-Signature and decodes one event
+func (f *Fake) ParseWebhook(header http.Header, body []byte) ([]TrackingUpdate, error) {
+	if f.secret == "" || !hmac.Equal([]byte(f.SignWebhook(body)), []byte(header.Get("X-Fake-Courier-Signature"))) {
+		return nil, ErrInvalidSignature
+	}
+
+	var event fakeWebhook
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, err
+	}
+	if event.EventTime.IsZero() {
+		event.EventTime = time.Now()
+	}
+
+	return []TrackingUpdate{{
+		AWBNumber:   event.AWBNumber,
+		Status:      event.Status,
+		RawStatus:   string(event.Status),
+		Location:    event.Location,
+		Description: event.Description,
+		EventTime:   event.EventTime,
+	}}, nil
+}
+
+// SignWebhook returns the signature the fake courier expects for body
+func (f *Fake) SignWebhook(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(f.secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func fakeAWB() (string, error) {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "FAKE" + strings.ToUpper(hex.EncodeToString(buf)), nil
+}
@@ -0,0 +1,77 @@
+package shipping
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// newHTTPClient returns the client used for courier API calls
+func newHTTPClient() *http.Client {
+	return &http.Client{Timeout: 20 * time.Second}
+}
+
+// doJSON sends a request with an optional JSON body and decodes a JSON response
+func doJSON(ctx context.Context, client *http.Client, courier, method, url string, header http.Header, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	return send(client, courier, req, out)
+}
+
+// send executes req and decodes the JSON response into out
+func send(client *http.Client, courier string, req *http.Request, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", courier, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: %s %s: %d %s", courier, req.Method, req.URL.Path, resp.StatusCode, truncate(string(data), 300))
+	}
+
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("%s: decoding %s response: %w", courier, req.URL.Path, err)
+	}
+	return nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,170 @@
+package shipping
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+)
+
+func TestFakeParseWebhook(t *testing.T) {
+	fake := NewFake("courier_secret")
+	body := []byte(`{"awb_number":"FAKE123","status":"delivered","location":"Lucknow","event_time":"2026-03-02T10:00:00Z"}`)
+
+	tests := []struct {
+		name      string
+		courier   *Fake
+		signature string
+		wantErr   bool
+	}{
+		{"valid", fake, fake.SignWebhook(body), false},
+		{"other secret", fake, NewFake("other_secret").SignWebhook(body), true},
+		{"unsigned", fake, "", true},
+		{"no secret configured", NewFake(""), NewFake("").SignWebhook(body), true},
+	}
+
+	for _, tt := range tests {
+		header := http.Header{}
+		header.Set("X-Fake-Courier-Signature", tt.signature)
+
+		updates, err := tt.courier.ParseWebhook(header, body)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if len(updates) != 1 || updates[0].AWBNumber != "FAKE123" || updates[0].Status != models.ShipmentStatusDelivered ||
+			updates[0].Location != "Lucknow" {
+			t.Errorf("%s: unexpected updates %+v", tt.name, updates)
+		}
+	}
+}
+
+func TestShiprocketParseWebhook(t *testing.T) {
+	courier := NewShiprocket("", "", "", "hook_token")
+	body := []byte(`{"awb":19041211125783,"current_status":"Delivered","current_timestamp":"02 03 2026 15:04:05","scans":[
+		{"date":"2026-03-01 09:30:00","activity":"Picked up","location":"Lucknow","sr-status-label":"PICKED UP"},
+		{"date":"not a date","activity":"Bad scan","sr-status-label":"IN TRANSIT"},
+		{"date":"2026-03-02 15:04:05","activity":"Delivered","location":"Delhi","sr-status-label":"delivered"}]}`)
+
+	for _, token := range []string{"", "wrong"} {
+		header := http.Header{}
+		header.Set("X-Api-Key", token)
+		if _, err := courier.ParseWebhook(header, body); err != ErrInvalidSignature {
+			t.Errorf("token %q: got %v, want ErrInvalidSignature", token, err)
+		}
+	}
+	if _, err := NewShiprocket("", "", "", "").ParseWebhook(http.Header{}, body); err != ErrInvalidSignature {
+		t.Errorf("no token configured: got %v, want ErrInvalidSignature", err)
+	}
+
+	header := http.Header{}
+	header.Set("X-Api-Key", "hook_token")
+	updates, err := courier.ParseWebhook(header, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updates) != 2 {
+		t.Fatalf("got %d updates, want 2 (unparseable scans skipped)", len(updates))
+	}
+	if updates[0].AWBNumber != "19041211125783" || updates[0].Status != models.ShipmentStatusPickedUp {
+		t.Errorf("unexpected first update %+v", updates[0])
+	}
+	if updates[1].Status != models.ShipmentStatusDelivered || updates[1].EventTime.Location() != istLocation {
+		t.Errorf("unexpected second update %+v", updates[1])
+	}
+}
+
+func TestForFakeCourier(t *testing.T) {
+	body := []byte(`{"awb_number":"FAKE123","status":"delivered","event_time":"2026-03-02T10:00:00Z"}`)
+	provider := models.LogisticsProvider{Code: CodeFake}
+
+	tests := []struct {
+		name     string
+		appEnv   string
+		secret   string
+		wantFake bool
+	}{
+		{"no secret", "development", "", false},
+		{"production", "production", "courier_secret", false},
+		{"secret", "staging", "courier_secret", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reloadCouriers(t)
+			t.Setenv("APP_ENV", tt.appEnv)
+			t.Setenv("FAKE_COURIER_SECRET", tt.secret)
+
+			courier, err := For(provider)
+			if !tt.wantFake {
+				if !errors.Is(err, ErrUnknownCourier) {
+					t.Errorf("got %v, %v; want ErrUnknownCourier", courier, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Webhooks are only accepted with the configured secret
+			receiver, ok := courier.(WebhookReceiver)
+			if !ok {
+				t.Fatal("fake courier does not receive webhooks")
+			}
+			header := http.Header{}
+			header.Set("X-Fake-Courier-Signature", NewFake(tt.secret).SignWebhook(body))
+			if _, err := receiver.ParseWebhook(header, body); err != nil {
+				t.Errorf("webhook signed with the secret rejected: %v", err)
+			}
+			header.Set("X-Fake-Courier-Signature", NewFake("guessed").SignWebhook(body))
+			if _, err := receiver.ParseWebhook(header, body); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("webhook signed with another secret: got %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
+
+// reloadCouriers empties the registry so the next Get registers couriers
+// from the environment again, and puts the current couriers back after t
+func reloadCouriers(t *testing.T) {
+	initOnce.Do(registerFromEnv)
+	mu.Lock()
+	saved := couriers
+	couriers = map[string]Courier{}
+	initOnce = sync.Once{}
+	mu.Unlock()
+
+	t.Cleanup(func() {
+		mu.Lock()
+		couriers = saved
+		initOnce = sync.Once{}
+		initOnce.Do(func() {})
+		mu.Unlock()
+	})
+}
+
+func TestEstimateDelivery(t *testing.T) {
+	friday := time.Date(2026, 3, 6, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		days int
+		want time.Time
+	}{
+		{0, friday},
+		{1, time.Date(2026, 3, 7, 12, 0, 0, 0, time.UTC)},
+		{2, time.Date(2026, 3, 9, 12, 0, 0, 0, time.UTC)}, // Skips Sunday
+		{3, time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		if got := EstimateDelivery(friday, tt.days); !got.Equal(tt.want) {
+			t.Errorf("%d days: got %s, want %s", tt.days, got.Format("Mon 2006-01-02"), tt.want.Format("Mon 2006-01-02"))
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package shipping
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+)
+
+const shiprocketBaseURL = "https://apiv2.shiprocket.in/v1/external"
+
+// Shiprocket is the Shiprocket aggregator API; it picks the courier
+// partner itself, so a booking is order create + AWB assign + pickup
+type Shiprocket struct {
+	email          string
+	password       string
+	pickupLocation string // Pickup location nickname configured in Shiprocket
+	webhookToken   string // Token Shiprocket sends in x-api-key on tracking webhooks
+	baseURL        string
+	client         *http.Client
+	session        *shiprocketSession
+}
+
+// shiprocketSession caches the login token between calls
+type shiprocketSession struct {
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// NewShiprocket creates a Shiprocket courier
+func NewShiprocket(email, password, pickupLocation, webhookToken string) *Shiprocket {
+	return &Shiprocket{
+		email:          email,
+		password:       password,
+		pickupLocation: pickupLocation,
+		webhookToken:   webhookToken,
+		baseURL:        shiprocketBaseURL,
+		client:         newHTTPClient(),
+		session:        &shiprocketSession{},
+	}
+}
+
+// Code returns the courier code
+func (s *Shiprocket) Code() string {
+	return CodeShiprocket
+}
+
+func (s *Shiprocket) withBaseURL(baseURL string) Courier {
+	clone := *s
+	clone.baseURL = strings.TrimRight(baseURL, "/")
+	clone.session = &shiprocketSession{}
+	return &clone
+}
+
+// shiprocketServiceability is the serviceability API response
+type shiprocketServiceability struct {
+	Data struct {
+		RecommendedCourierCompanyID int `json:"recommended_courier_company_id"`
+		AvailableCourierCompanies   []struct {
+			CourierCompanyID int     `json:"courier_company_id"`
+			Rate             float64 `json:"rate"`
+			EstimatedDays    string  `json:"estimated_delivery_days"`
+			ETD              string  `json:"etd"` // e.g. "Oct 21, 2024"
+		} `json:"available_courier_companies"`
+	} `json:"data"`
+}
+
+// Quote returns the recommended courier partner's rate and ETD
+func (s *Shiprocket) Quote(ctx context.Context, req QuoteRequest) (*Quote, error) {
+	params := url.Values{
+		"pickup_postcode":   {req.PickupPinCode},
+		"delivery_postcode": {req.DeliveryPinCode},
+		"weight":            {fmt.Sprintf("%.2f", req.Parcel.WeightKg)},
+		"cod":               {"0"},
+	}
+	if req.CODAmount > 0 {
+		params.Set("cod", "1")
+	}
+
+	var resp shiprocketServiceability
+	if err := s.do(ctx, http.MethodGet, "/courier/serviceability/?"+params.Encode(), nil, &resp); err != nil {
+		return nil, err
+	}
+
+	companies := resp.Data.AvailableCourierCompanies
+	if len(companies) == 0 {
+		return nil, fmt.Errorf("shiprocket: %s is not serviceable from %s", req.DeliveryPinCode, req.PickupPinCode)
+	}
+
+	best := companies[0]
+	for _, c := range companies {
+		if c.CourierCompanyID == resp.Data.RecommendedCourierCompanyID {
+			best = c
+			break
+		}
+	}
+
+	eta, err := time.Parse("Jan 2, 2006", best.ETD)
+	if err != nil {
+		eta = EstimateDelivery(time.Now(), transitDays())
+	}
+
+	return &Quote{ShippingCost: best.Rate, EstimatedDelivery: eta}, nil
+}
+
+// Book creates a Shiprocket order, assigns an AWB and requests pickup
+func (s *Shiprocket) Book(ctx context.Context, req BookingRequest) (*Booking, error) {
+	quote, err := s.Quote(ctx, QuoteRequest{
+		PickupPinCode:   req.Pickup.PinCode,
+		DeliveryPinCode: req.Delivery.PinCode,
+		Parcel:          req.Parcel,
+		CODAmount:       req.CODAmount,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	paymentMethod := "Prepaid"
+	if req.CODAmount > 0 {
+		paymentMethod = "COD"
+	}
+	order := map[string]interface{}{
+		"order_id":              req.OrderNumber,
+		"order_date":            time.Now().Format("2006-01-02 15:04"),
+		"pickup_location":       s.pickupLocation,
+		"billing_customer_name": req.Delivery.Name,
+		"billing_last_name":     "",
+		"billing_address":       req.Delivery.AddressLine1,
+		"billing_address_2":     req.Delivery.AddressLine2,
+		"billing_city":          req.Delivery.City,
+		"billing_state":         req.Delivery.State,
+		"billing_country":       req.Delivery.Country,
+		"billing_pincode":       req.Delivery.PinCode,
+		"billing_phone":         req.Delivery.Phone,
+		"shipping_is_billing":   true,
+		"order_items":           []map[string]interface{}{{"name": "Order " + req.OrderNumber, "sku": req.OrderNumber, "units": 1, "selling_price": req.DeclaredValue}},
+		"payment_method":        paymentMethod,
+		"sub_total":             req.DeclaredValue,
+		"length":                req.Parcel.LengthCm,
+		"breadth":               req.Parcel.WidthCm,
+		"height":                req.Parcel.HeightCm,
+		"weight":                req.Parcel.WeightKg,
+	}
+
+	var created struct {
+		OrderID    int `json:"order_id"`
+		ShipmentID int `json:"shipment_id"`
+	}
+	if err := s.do(ctx, http.MethodPost, "/orders/create/adhoc", order, &created); err != nil {
+		return nil, err
+	}
+
+	var assigned struct {
+		AWBAssignStatus int `json:"awb_assign_status"`
+		Response        struct {
+			Data struct {
+				AWBCode string `json:"awb_code"`
+			} `json:"data"`
+		} `json:"response"`
+	}
+	if err := s.do(ctx, http.MethodPost, "/courier/assign/awb",
+		map[string]interface{}{"shipment_id": created.ShipmentID}, &assigned); err != nil {
+		return nil, err
+	}
+	awb := assigned.Response.Data.AWBCode
+	if awb == "" {
+		return nil, fmt.Errorf("shiprocket: no AWB assigned for shipment %d", created.ShipmentID)
+	}
+
+	var pickup struct {
+		Response struct {
+			PickupScheduledDate string `json:"pickup_scheduled_date"`
+		} `json:"response"`
+	}
+	if err := s.do(ctx, http.MethodPost, "/courier/generate/pickup",
+		map[string]interface{}{"shipment_id": []int{created.ShipmentID}}, &pickup); err != nil {
+		return nil, err
+	}
+
+	booking := &Booking{
+		AWBNumber:         awb,
+		Reference:         fmt.Sprintf("%d", created.ShipmentID),
+		ShippingCost:      quote.ShippingCost,
+		EstimatedDelivery: quote.EstimatedDelivery,
+		TrackingURL:       "https://shiprocket.co/tracking/" + awb,
+	}
+	if date, err := time.Parse("2006-01-02 15:04:05", pickup.Response.PickupScheduledDate); err == nil {
+		booking.PickupDate = &date
+	}
+	return booking, nil
+}
+
+// Cancel cancels the shipment for an AWB
+func (s *Shiprocket) Cancel(ctx context.Context, awbNumber string) error {
+	return s.do(ctx, http.MethodPost, "/orders/cancel/shipment/awbs",
+		map[string]interface{}{"awbs": []string{awbNumber}}, nil)
+}
+
+// do sends an authenticated request, logging in first if the token has expired
+func (s *Shiprocket) do(ctx context.Context, method, path string, in, out interface{}) error {
+	token, err := s.authToken(ctx)
+	if err != nil {
+		return err
+	}
+	header := http.Header{"Authorization": {"Bearer " + token}}
+	return doJSON(ctx, s.client, CodeShiprocket, method, s.baseURL+path, header, in, out)
+}
+
+// authToken returns a cached API token; Shiprocket tokens last 10 days
+func (s *Shiprocket) authToken(ctx context.Context) (string, error) {
+	s.session.mu.Lock()
+	defer s.session.mu.Unlock()
+
+	if s.session.token != "" && time.Now().Before(s.session.expiry) {
+		return s.session.token, nil
+	}
+
+	var resp struct {
+		Token string `json:"token"`
+	}
+	if err := doJSON(ctx, s.client, CodeShiprocket, http.MethodPost, s.baseURL+"/auth/login", nil,
+		map[string]string{"email": s.email, "password": s.password}, &resp); err != nil {
+		return "", err
+	}
+
+	s.session.token = resp.Token
+	s.session.expiry = time.Now().Add(9 * 24 * time.Hour)
+	return resp.Token, nil
+}
+
+// shiprocketStatuses maps Shiprocket status labels onto shipment statuses
+var shiprocketStatuses = map[string]models.ShipmentStatus{
+	"AWB ASSIGNED":               models.ShipmentStatusPending,
+	"PICKUP SCHEDULED":           models.ShipmentStatusPending,
+	"PICKUP GENERATED":           models.ShipmentStatusPending,
+	"PICKED UP":                  models.ShipmentStatusPickedUp,
+	"SHIPPED":                    models.ShipmentStatusInTransit,
+	"IN TRANSIT":                 models.ShipmentStatusInTransit,
+	"REACHED AT DESTINATION HUB": models.ShipmentStatusInTransit,
+	"OUT FOR DELIVERY":           models.ShipmentStatusOutForDelivery,
+	"DELIVERED":                  models.ShipmentStatusDelivered,
+	"UNDELIVERED":                models.ShipmentStatusFailed,
+	"RTO INITIATED":              models.ShipmentStatusInTransit,
+	"RTO DELIVERED":              models.ShipmentStatusReturned,
+}
+
+// shiprocketWebhook is the tracking webhook body
+type shiprocketWebhook struct {
+	AWB              json.RawMessage `json:"awb"` // Sent as a number or a string
+	CurrentStatus    string          `json:"current_status"`
+	CurrentTimestamp string          `json:"current_timestamp"`
+	Scans            []struct {
+		Date     string `json:"date"`
+		Activity string `json:"activity"`
+		Location string `json:"location"`
+		Status   string `json:"sr-status-label"`
+	} `json:"scans"`
+}
+
+// ParseWebhook checks the x-api-key token configured on the Shiprocket
+// webhook and decodes its scans
+func (s *Shiprocket) ParseWebhook(header http.Header, body []byte) ([]TrackingUpdate, error) {
+	token := header.Get("X-Api-Key")
+	if s.webhookToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.webhookToken)) != 1 {
+		return nil, ErrInvalidSignature
+	}
+
+	var event shiprocketWebhook
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, err
+	}
+	awb := strings.Trim(string(event.AWB), `"`)
+
+	var updates []TrackingUpdate
+	for _, scan := range event.Scans {
+		eventTime, ok := parseEventTime(scan.Date)
+		if !ok {
+			continue
+		}
+		updates = append(updates, TrackingUpdate{
+			AWBNumber:   awb,
+			Status:      mapStatus(shiprocketStatuses, scan.Status),
+			RawStatus:   scan.Status,
+			Location:    scan.Location,
+			Description: scan.Activity,
+			EventTime:   eventTime,
+		})
+	}
+
+	// Status-only updates carry no scans
+	if len(updates) == 0 {
+		eventTime, ok := parseEventTime(event.CurrentTimestamp, "02 01 2006 15:04:05")
+		if !ok {
+			eventTime = time.Now()
+		}
+		updates = append(updates, TrackingUpdate{
+			AWBNumber: awb,
+			Status:    mapStatus(shiprocketStatuses, event.CurrentStatus),
+			RawStatus: event.CurrentStatus,
+			EventTime: eventTime,
+		})
+	}
+	return updates, nil
+}
@@ -0,0 +1,56 @@
+package shipping
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nilabhsubramaniam/kapas/internal/models"
+)
+
+// ErrInvalidSignature is returned when a courier webhook fails authentication
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// TrackingUpdate is one courier scan for a shipment
+type TrackingUpdate struct {
+	AWBNumber   string
+	Status      models.ShipmentStatus // Empty when the courier status has no equivalent
+	RawStatus   string                // Courier's own status text
+	Location    string
+	Description string
+	EventTime   time.Time
+}
+
+// WebhookReceiver is implemented by couriers that push tracking updates
+type WebhookReceiver interface {
+	// ParseWebhook authenticates and decodes a tracking webhook
+	ParseWebhook(header http.Header, body []byte) ([]TrackingUpdate, error)
+}
+
+// Tracker is implemented by couriers whose tracking can be polled
+type Tracker interface {
+	// Track returns the scans for the given AWBs
+	Track(ctx context.Context, awbNumbers []string) ([]TrackingUpdate, error)
+}
+
+// istLocation is the zone courier timestamps without an offset are in
+var istLocation = time.FixedZone("IST", 5*60*60+30*60)
+
+// parseEventTime reads a courier timestamp, trying the layouts couriers use;
+// timestamps without an offset are taken as IST
+func parseEventTime(value string, layouts ...string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range append(layouts, time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05") {
+		if t, err := time.ParseInLocation(layout, value, istLocation); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// mapStatus looks up a courier status, case-insensitively
+func mapStatus(table map[string]models.ShipmentStatus, raw string) models.ShipmentStatus {
+	return table[strings.ToUpper(strings.TrimSpace(raw))]
+}
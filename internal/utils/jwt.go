@@ -1,6 +1,10 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"os"
 	"strconv"
@@ -11,50 +15,127 @@ import (
 
 // Claims represents JWT claims
 type Claims struct {
-	UserID uint   `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID    uint   `json:"user_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid"` // Refresh token family the token belongs to
 	jwt.RegisteredClaims
 }
 
-// GenerateToken creates a new JWT token
-func GenerateToken(userID uint, email, role string) (string, error) {
-	// Get expiration hours from env
-	expirationHours, err := strconv.Atoi(os.Getenv("JWT_EXPIRATION_HOURS"))
-	if err != nil || expirationHours == 0 {
-		expirationHours = 24 // Default 24 hours
+// accessAudience marks access tokens so tokens signed for other purposes,
+// such as password reset links, can't be used to authenticate
+const accessAudience = "access"
+
+// ActionClaims are the claims of a single-purpose token emailed to a user
+type ActionClaims struct {
+	UserID uint `json:"user_id"`
+	jwt.RegisteredClaims
+}
+
+// AccessTokenTTL is how long access tokens stay valid
+func AccessTokenTTL() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("JWT_EXPIRATION_MINUTES"))
+	if err != nil || minutes <= 0 {
+		minutes = 15 // Default 15 minutes
 	}
+	return time.Duration(minutes) * time.Minute
+}
 
-	expirationTime := time.Now().Add(time.Duration(expirationHours) * time.Hour)
+// RefreshTokenTTL is how long a session lasts without being refreshed
+func RefreshTokenTTL() time.Duration {
+	hours, err := strconv.Atoi(os.Getenv("JWT_REFRESH_EXPIRATION_HOURS"))
+	if err != nil || hours <= 0 {
+		hours = 168 // Default 7 days
+	}
+	return time.Duration(hours) * time.Hour
+}
 
+// GenerateToken creates a short-lived access token for a session. The
+// token's ID (jti) is returned so the token can be revoked.
+func GenerateToken(userID uint, email, role, sessionID string) (string, string, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return "", "", errors.New("JWT_SECRET not configured")
+	}
+
+	jti, err := RandomID()
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
+		UserID:    userID,
+		Email:     email,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ID:        jti,
+			Audience:  jwt.ClaimStrings{accessAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL())),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString([]byte(secret))
+	if err != nil {
+		return "", "", err
+	}
+	return signed, jti, nil
+}
+
+// ValidateToken validates and parses an access token
+func ValidateToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	if err := parseToken(tokenString, claims, accessAudience); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
 
+// GenerateActionToken creates a signed token for one purpose, such as
+// email verification, that expires after ttl
+func GenerateActionToken(userID uint, purpose string, ttl time.Duration) (string, error) {
 	secret := os.Getenv("JWT_SECRET")
 	if secret == "" {
 		return "", errors.New("JWT_SECRET not configured")
 	}
 
-	return token.SignedString([]byte(secret))
+	jti, err := RandomID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := &ActionClaims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Audience:  jwt.ClaimStrings{purpose},
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
 }
 
-// ValidateToken validates and parses a JWT token
-func ValidateToken(tokenString string) (*Claims, error) {
-	claims := &Claims{}
+// ValidateActionToken validates a token made by GenerateActionToken for purpose
+func ValidateActionToken(tokenString, purpose string) (*ActionClaims, error) {
+	claims := &ActionClaims{}
+	if err := parseToken(tokenString, claims, purpose); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
 
+// parseToken verifies a token's signature, expiry and audience into claims
+func parseToken(tokenString string, claims jwt.Claims, audience string) error {
 	secret := os.Getenv("JWT_SECRET")
 	if secret == "" {
-		return nil, errors.New("JWT_SECRET not configured")
+		return errors.New("JWT_SECRET not configured")
 	}
 
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
@@ -63,15 +144,40 @@ func ValidateToken(tokenString string) (*Claims, error) {
 			return nil, errors.New("invalid signing method")
 		}
 		return []byte(secret), nil
-	})
+	}, jwt.WithAudience(audience))
 
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	if !token.Valid {
-		return nil, errors.New("invalid token")
+		return errors.New("invalid token")
 	}
 
-	return claims, nil
+	return nil
+}
+
+// GenerateRefreshToken creates an opaque random refresh token
+func GenerateRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the SHA-256 hex digest of a token, for storing tokens
+// that must not be readable from the database
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RandomID returns 16 random bytes as hex
+func RandomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
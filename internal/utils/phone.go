@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+)
+
+// ErrInvalidPhone is returned for numbers that can't be put in E.164 form
+var ErrInvalidPhone = errors.New("invalid phone number")
+
+// NormalizePhone returns phone in E.164 form, e.g. +919876543210. Numbers
+// without a leading + or 00 are national numbers in the country whose
+// dialling code is dialCode, e.g. "+91".
+func NormalizePhone(phone, dialCode string) (string, error) {
+	phone = strings.TrimSpace(phone)
+	international := strings.HasPrefix(phone, "+")
+
+	var digits strings.Builder
+	for i, r := range phone {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && i == 0:
+		case r == ' ' || r == '-' || r == '(' || r == ')' || r == '.':
+		default:
+			return "", ErrInvalidPhone
+		}
+	}
+	number := digits.String()
+
+	if !international && strings.HasPrefix(number, "00") {
+		number, international = number[2:], true
+	}
+	code := strings.TrimPrefix(strings.TrimSpace(dialCode), "+")
+	if !international {
+		if code == "" {
+			return "", ErrInvalidPhone
+		}
+		number = strings.TrimLeft(number, "0") // Trunk prefix
+		// Numbers typed with the country code but no +, e.g. 919876543210
+		if len(number) > 10 && strings.HasPrefix(number, code) {
+			number = number[len(code):]
+		}
+		number = code + number
+	}
+
+	if len(number) < 8 || len(number) > 15 || number[0] == '0' {
+		return "", ErrInvalidPhone
+	}
+	// Indian numbers are always 10 digits after the country code
+	if strings.HasPrefix(number, "91") && len(number) != 12 {
+		return "", ErrInvalidPhone
+	}
+	return "+" + number, nil
+}
+
+// GenerateOTP returns a random numeric code of the given length
+func GenerateOTP(length int) (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(length)), nil)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", length, n), nil
+}
+
+// HashOTP returns a keyed hash of a code sent to phone. Codes are short, so
+// a plain hash could be reversed by trying every code; the key prevents that.
+func HashOTP(phone, code string) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("JWT_SECRET")))
+	mac.Write([]byte(phone + ":" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		phone    string
+		dialCode string
+		want     string
+		wantErr  bool
+	}{
+		{"9876543210", "+91", "+919876543210", false},
+		{"98765 43210", "+91", "+919876543210", false},
+		{"098765-43210", "+91", "+919876543210", false},
+		{"919876543210", "+91", "+919876543210", false},
+		{"+91 98765 43210", "", "+919876543210", false},
+		{"0091 9876543210", "+91", "+919876543210", false},
+		{"(415) 555-0132", "+1", "+14155550132", false},
+		{"+44 20 7946 0958", "+91", "+442079460958", false},
+		{"9876543210", "91", "+919876543210", false},
+		{"987654321", "+91", "", true},        // Too short for India
+		{"+9198765432100", "+91", "", true},   // Too long for India
+		{"9876543210", "", "", true},          // National number without a country
+		{"98765x43210", "+91", "", true},      // Letters
+		{"98+7654321", "+91", "", true},       // + after the start
+		{"+0123456789", "+91", "", true},      // Country codes don't start with 0
+		{"12345", "+1", "", true},             // Too short
+		{"+1234567890123456", "+1", "", true}, // Over 15 digits
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizePhone(tt.phone, tt.dialCode)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidPhone) {
+				t.Errorf("NormalizePhone(%q, %q) = %q, %v; want ErrInvalidPhone", tt.phone, tt.dialCode, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("NormalizePhone(%q, %q) = %q, %v; want %q", tt.phone, tt.dialCode, got, err, tt.want)
+		}
+	}
+}
+
+func TestGenerateOTP(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		code, err := GenerateOTP(6)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) != 6 {
+			t.Fatalf("got %q, want 6 digits", code)
+		}
+		for _, r := range code {
+			if r < '0' || r > '9' {
+				t.Fatalf("got %q, want only digits", code)
+			}
+		}
+	}
+}
+
+func TestHashOTP(t *testing.T) {
+	t.Setenv("JWT_SECRET", "secret")
+	hash := HashOTP("+919876543210", "482913")
+
+	if hash != HashOTP("+919876543210", "482913") {
+		t.Error("hash is not stable")
+	}
+	if hash == HashOTP("+919876543211", "482913") {
+		t.Error("hash ignores the phone")
+	}
+	if hash == HashOTP("+919876543210", "482914") {
+		t.Error("hash ignores the code")
+	}
+
+	t.Setenv("JWT_SECRET", "other")
+	if hash == HashOTP("+919876543210", "482913") {
+		t.Error("hash ignores the key")
+	}
+}